@@ -0,0 +1,109 @@
+package main
+
+import (
+	"bytes"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/MohammadLashkari/snippetbox/internal/gist"
+)
+
+type client struct {
+	baseURL string
+	token   string
+	http    *http.Client
+}
+
+func newClient(cfg *config) *client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if cfg.InsecureSkipVerify {
+		transport.TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
+	}
+	return &client{
+		baseURL: strings.TrimRight(cfg.URL, "/"),
+		token:   cfg.Token,
+		http:    &http.Client{Timeout: 30 * time.Second, Transport: transport},
+	}
+}
+
+func (c *client) create(filename, title, content string) (*gist.Gist, error) {
+	req := gist.Request{
+		Files: map[string]*gist.FileRequest{filename: {Content: &content}},
+	}
+	if title != "" {
+		req.Description = &title
+	}
+	var g gist.Gist
+	if err := c.do(http.MethodPost, "/api/gists", &req, &g); err != nil {
+		return nil, err
+	}
+	return &g, nil
+}
+
+func (c *client) list() ([]*gist.Gist, error) {
+	var gists []*gist.Gist
+	if err := c.do(http.MethodGet, "/api/gists", nil, &gists); err != nil {
+		return nil, err
+	}
+	return gists, nil
+}
+
+func (c *client) raw(id string) ([]byte, error) {
+	rs, err := c.http.Get(c.baseURL + "/snippet/raw/" + url.PathEscape(id))
+	if err != nil {
+		return nil, err
+	}
+	defer rs.Body.Close()
+	if rs.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("snippet %s: %s", id, rs.Status)
+	}
+	return io.ReadAll(rs.Body)
+}
+
+// do sends body as JSON and decodes a successful response into dst. Error
+// responses are returned as a *gist.Error.
+func (c *client) do(method, path string, body, dst any) error {
+	var r io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		r = bytes.NewReader(b)
+	}
+	req, err := http.NewRequest(method, c.baseURL+path, r)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.token != "" {
+		req.Header.Set("Authorization", "token "+c.token)
+	}
+
+	rs, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer rs.Body.Close()
+
+	if rs.StatusCode < 200 || rs.StatusCode > 299 {
+		apiErr := &gist.Error{}
+		if err := json.NewDecoder(rs.Body).Decode(apiErr); err != nil || apiErr.Message == "" {
+			apiErr.Message = rs.Status
+		}
+		return apiErr
+	}
+	if dst == nil {
+		return nil
+	}
+	return json.NewDecoder(rs.Body).Decode(dst)
+}
@@ -0,0 +1,127 @@
+// Command snippet creates, fetches and lists snippets through the
+// gist-compatible API of a snippetbox server.
+//
+// Usage:
+//
+//	snippet [-title title] [file ...]   create a snippet from each file, or from stdin
+//	snippet get id                      print the raw content of a snippet
+//	snippet list                        list your snippets
+//	snippet config -url url -token tok  save the server URL and API token
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"text/tabwriter"
+)
+
+func main() {
+	if err := run(os.Args[1:], os.Stdin, os.Stdout); err != nil {
+		fmt.Fprintf(os.Stderr, "snippet: %s\n", err)
+		os.Exit(1)
+	}
+}
+
+func run(args []string, stdin io.Reader, stdout io.Writer) error {
+	path, err := configPath()
+	if err != nil {
+		return err
+	}
+	cfg, err := loadConfig(path)
+	if err != nil {
+		return err
+	}
+
+	if len(args) > 0 {
+		switch args[0] {
+		case "config":
+			return configure(path, cfg, args[1:], stdout)
+		case "get":
+			if len(args) != 2 {
+				return errors.New("usage: snippet get id")
+			}
+			content, err := newClient(cfg).raw(args[1])
+			if err != nil {
+				return err
+			}
+			_, err = stdout.Write(content)
+			return err
+		case "list":
+			return list(newClient(cfg), stdout)
+		}
+	}
+	return create(newClient(cfg), args, stdin, stdout)
+}
+
+func configure(path string, cfg *config, args []string, stdout io.Writer) error {
+	fs := flag.NewFlagSet("config", flag.ContinueOnError)
+	fs.StringVar(&cfg.URL, "url", cfg.URL, "snippetbox server URL")
+	fs.StringVar(&cfg.Token, "token", cfg.Token, "API token from the account page")
+	fs.BoolVar(&cfg.InsecureSkipVerify, "insecure", cfg.InsecureSkipVerify, "skip TLS certificate verification")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if err := saveConfig(path, cfg); err != nil {
+		return err
+	}
+	fmt.Fprintf(stdout, "saved %s\n", path)
+	return nil
+}
+
+func create(c *client, args []string, stdin io.Reader, stdout io.Writer) error {
+	fs := flag.NewFlagSet("snippet", flag.ContinueOnError)
+	title := fs.String("title", "", "snippet title, defaults to the file name")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if c.token == "" {
+		return errors.New("no API token configured, run: snippet config -token <token>")
+	}
+
+	if fs.NArg() == 0 {
+		content, err := io.ReadAll(stdin)
+		if err != nil {
+			return err
+		}
+		return createOne(c, "stdin", *title, string(content), stdout)
+	}
+	for _, name := range fs.Args() {
+		content, err := os.ReadFile(name)
+		if err != nil {
+			return err
+		}
+		if err := createOne(c, filepath.Base(name), *title, string(content), stdout); err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+func createOne(c *client, filename, title, content string, stdout io.Writer) error {
+	g, err := c.create(filename, title, content)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(stdout, g.HTMLURL)
+	return err
+}
+
+func list(c *client, stdout io.Writer) error {
+	if c.token == "" {
+		return errors.New("no API token configured, run: snippet config -token <token>")
+	}
+	gists, err := c.list()
+	if err != nil {
+		return err
+	}
+	tw := tabwriter.NewWriter(stdout, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tCREATED\tTITLE")
+	for _, g := range gists {
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", g.ID, g.CreatedAt.Format("2006-01-02 15:04"), g.Description)
+	}
+	return tw.Flush()
+}
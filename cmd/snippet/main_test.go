@@ -0,0 +1,128 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/MohammadLashkari/snippetbox/internal/assert"
+	"github.com/MohammadLashkari/snippetbox/internal/gist"
+)
+
+func newTestServer(t *testing.T) *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /api/gists", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "token valid-token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			json.NewEncoder(w).Encode(gist.Error{Message: "Bad credentials"})
+			return
+		}
+		var req gist.Request
+		json.NewDecoder(r.Body).Decode(&req)
+		if req.Files["main.go"] == nil || *req.Files["main.go"].Content != "package main\n" {
+			w.WriteHeader(http.StatusUnprocessableEntity)
+			json.NewEncoder(w).Encode(gist.Error{
+				Message: "Validation Failed",
+				Errors:  []gist.FieldError{{Resource: "Gist", Field: "files", Code: "missing_field"}},
+			})
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(gist.Gist{ID: "7", HTMLURL: "https://example.com/snippet/view/7"})
+	})
+	mux.HandleFunc("GET /api/gists", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode([]gist.Gist{{
+			ID:          "7",
+			Description: "main.go",
+			CreatedAt:   time.Date(2024, 06, 29, 21, 05, 0, 0, time.UTC),
+		}})
+	})
+	mux.HandleFunc("GET /snippet/raw/{id}", func(w http.ResponseWriter, r *http.Request) {
+		if r.PathValue("id") != "7" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte("package main\n"))
+	})
+	ts := httptest.NewTLSServer(mux)
+	t.Cleanup(ts.Close)
+	return ts
+}
+
+func TestRun(t *testing.T) {
+	ts := newTestServer(t)
+	t.Setenv("SNIPPETBOX_CONFIG", filepath.Join(t.TempDir(), "config.json"))
+
+	var out bytes.Buffer
+	err := run([]string{"config", "-url", ts.URL, "-token", "valid-token", "-insecure"}, nil, &out)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		args    []string
+		stdin   string
+		wantOut string
+		wantErr string
+	}{
+		{
+			name:    "Create from stdin",
+			stdin:   "package main\n",
+			wantErr: "Validation Failed; files: missing_field",
+		},
+		{
+			name:    "Get",
+			args:    []string{"get", "7"},
+			wantOut: "package main\n",
+		},
+		{
+			name:    "Get missing",
+			args:    []string{"get", "8"},
+			wantErr: "snippet 8: 404 Not Found",
+		},
+		{
+			name:    "List",
+			args:    []string{"list"},
+			wantOut: "7   2024-06-29 21:05  main.go",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			err := run(tt.args, strings.NewReader(tt.stdin), &out)
+			if tt.wantErr != "" {
+				if err == nil {
+					t.Fatalf("got no error; want %q", tt.wantErr)
+				}
+				assert.Equal(t, err.Error(), tt.wantErr)
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			assert.StringContains(t, out.String(), tt.wantOut)
+		})
+	}
+}
+
+func TestCreate(t *testing.T) {
+	ts := newTestServer(t)
+	cfg := &config{URL: ts.URL, Token: "valid-token", InsecureSkipVerify: true}
+
+	g, err := newClient(cfg).create("main.go", "", "package main\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, g.HTMLURL, "https://example.com/snippet/view/7")
+
+	cfg.Token = "wrong-token"
+	_, err = newClient(cfg).create("main.go", "", "package main\n")
+	assert.Equal(t, err.Error(), "Bad credentials")
+}
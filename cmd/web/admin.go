@@ -0,0 +1,274 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/MohammadLashkari/snippetbox/internal/models"
+	"github.com/MohammadLashkari/snippetbox/internal/validator"
+)
+
+// adminUserLimit caps the number of users listed on /admin.
+const adminUserLimit = 50
+
+type adminSearchForm struct {
+	Query string
+}
+
+type adminRoleForm struct {
+	Role string `form:"role"`
+}
+
+type adminLockoutForm struct {
+	Key string `form:"key"`
+}
+
+func (app *application) adminUsers(w http.ResponseWriter, r *http.Request) {
+	form := adminSearchForm{Query: r.URL.Query().Get("q")}
+	users, err := app.users.Search(form.Query, adminUserLimit)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	data := app.newTemplateData(r)
+	data.Users = users
+	data.Form = form
+	app.render(w, r, http.StatusOK, "admin.tmpl", data)
+}
+
+func (app *application) adminUser(w http.ResponseWriter, r *http.Request) {
+	user, ok := app.adminUserFromPath(w, r)
+	if !ok {
+		return
+	}
+	snippets, err := app.snippets.ByUser(user.ID)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	data := app.newTemplateData(r)
+	data.User = user
+	data.Snippets = snippets
+	app.render(w, r, http.StatusOK, "adminuser.tmpl", data)
+}
+
+func (app *application) adminUserSuspendPost(w http.ResponseWriter, r *http.Request) {
+	app.adminSetSuspended(w, r, true)
+}
+
+func (app *application) adminUserUnsuspendPost(w http.ResponseWriter, r *http.Request) {
+	app.adminSetSuspended(w, r, false)
+}
+
+func (app *application) adminSetSuspended(w http.ResponseWriter, r *http.Request, suspended bool) {
+	user, ok := app.adminManagedUser(w, r)
+	if !ok {
+		return
+	}
+	if err := app.users.SetSuspended(user.ID, suspended); err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	flash := fmt.Sprintf("%s can log in again", user.Name)
+	event := models.AuditAdminUnsuspend
+	if suspended {
+		event = models.AuditAdminSuspend
+		if err := app.destroyUserSessions(r.Context(), user.ID); err != nil {
+			app.serverError(w, r, err)
+			return
+		}
+		flash = fmt.Sprintf("%s has been suspended", user.Name)
+	}
+	app.auditAdmin(r, user.ID, event, "")
+	app.sessionManager.Put(r.Context(), "flash", flash)
+	http.Redirect(w, r, fmt.Sprintf("/admin/users/%d", user.ID), http.StatusSeeOther)
+}
+
+func (app *application) adminUserRolePost(w http.ResponseWriter, r *http.Request) {
+	user, ok := app.adminManagedUser(w, r)
+	if !ok {
+		return
+	}
+	var form adminRoleForm
+	if err := app.decodePostForm(r, &form); err != nil || !models.ValidRole(form.Role) {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+	if err := app.users.SetRole(user.ID, form.Role); err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	app.auditAdmin(r, user.ID, models.AuditAdminRole, user.Role+" to "+form.Role)
+	app.sessionManager.Put(r.Context(), "flash", fmt.Sprintf("%s is now a %s", user.Name, form.Role))
+	http.Redirect(w, r, fmt.Sprintf("/admin/users/%d", user.ID), http.StatusSeeOther)
+}
+
+func (app *application) adminUserDeletePost(w http.ResponseWriter, r *http.Request) {
+	user, ok := app.adminManagedUser(w, r)
+	if !ok {
+		return
+	}
+	if err := app.users.Delete(user.ID, false); err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			app.notFound(w)
+		} else {
+			app.serverError(w, r, err)
+		}
+		return
+	}
+	if err := app.destroyUserSessions(r.Context(), user.ID); err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	app.auditAdmin(r, user.ID, models.AuditAdminDelete, "")
+	app.sessionManager.Put(r.Context(), "flash", fmt.Sprintf("%s has been deleted", user.Name))
+	http.Redirect(w, r, "/admin", http.StatusSeeOther)
+}
+
+func (app *application) adminSnippetDeletePost(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil || id < 1 {
+		app.notFound(w)
+		return
+	}
+	snippet, err := app.snippets.Get(id)
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			app.notFound(w)
+		} else {
+			app.serverError(w, r, err)
+		}
+		return
+	}
+	if err := app.snippets.Delete(snippet.ID); err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	app.notifySnippet(r, models.EventSnippetDeleted, snippet)
+	app.auditAdmin(r, snippet.UserID, models.AuditAdminSnippet, fmt.Sprintf("#%d %s", snippet.ID, snippet.Title))
+	app.sessionManager.Put(r.Context(), "flash", fmt.Sprintf("snippet #%d removed", snippet.ID))
+	// Snippets kept from deleted accounts have no owner to go back to.
+	if snippet.UserID == 0 {
+		http.Redirect(w, r, "/admin", http.StatusSeeOther)
+		return
+	}
+	http.Redirect(w, r, fmt.Sprintf("/admin/users/%d", snippet.UserID), http.StatusSeeOther)
+}
+
+func (app *application) adminLockouts(w http.ResponseWriter, r *http.Request) {
+	lockouts, err := app.loginAttempts.Locked()
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	data := app.newTemplateData(r)
+	data.Lockouts = lockouts
+	app.render(w, r, http.StatusOK, "lockouts.tmpl", data)
+}
+
+func (app *application) adminLockoutClearPost(w http.ResponseWriter, r *http.Request) {
+	var form adminLockoutForm
+	if err := app.decodePostForm(r, &form); err != nil || !validator.NotBlank(form.Key) {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+	if err := app.loginAttempts.Clear(form.Key); err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	app.auditAdmin(r, 0, models.AuditAdminLockout, form.Key)
+	app.sessionManager.Put(r.Context(), "flash", fmt.Sprintf("lockout of %s lifted", form.Key))
+	http.Redirect(w, r, "/admin/lockouts", http.StatusSeeOther)
+}
+
+type adminAuditForm struct {
+	UserID  int
+	ActorID int
+	Event   string
+	IP      string
+	Events  []string
+}
+
+func (app *application) adminAudit(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	form := adminAuditForm{
+		Event:  query.Get("event"),
+		IP:     query.Get("ip"),
+		Events: models.AuditEvents,
+	}
+	for _, f := range []struct {
+		param string
+		id    *int
+	}{
+		{"user", &form.UserID},
+		{"actor", &form.ActorID},
+	} {
+		s := query.Get(f.param)
+		if s == "" {
+			continue
+		}
+		id, err := strconv.Atoi(s)
+		if err != nil || id < 1 {
+			app.clientError(w, http.StatusBadRequest)
+			return
+		}
+		*f.id = id
+	}
+	events, err := app.auditLog.Search(models.AuditFilter{
+		UserID:  form.UserID,
+		ActorID: form.ActorID,
+		Event:   form.Event,
+		IP:      form.IP,
+		Limit:   auditEventLimit,
+	})
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	data := app.newTemplateData(r)
+	data.AuditEvents = events
+	data.Form = form
+	app.render(w, r, http.StatusOK, "adminaudit.tmpl", data)
+}
+
+// adminUserFromPath loads the user named by the {id} path value, answering
+// with 404 if there is none.
+func (app *application) adminUserFromPath(w http.ResponseWriter, r *http.Request) (*models.User, bool) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil || id < 1 {
+		app.notFound(w)
+		return nil, false
+	}
+	user, err := app.users.Get(id)
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			app.notFound(w)
+		} else {
+			app.serverError(w, r, err)
+		}
+		return nil, false
+	}
+	return user, true
+}
+
+// adminManagedUser is adminUserFromPath for actions that change the user.
+// Staff cannot act on their own account, and only admins can act on other
+// moderators and admins.
+func (app *application) adminManagedUser(w http.ResponseWriter, r *http.Request) (*models.User, bool) {
+	user, ok := app.adminUserFromPath(w, r)
+	if !ok {
+		return nil, false
+	}
+	if user.ID == app.sessionManager.GetInt(r.Context(), "authenticatedUserID") {
+		app.sessionManager.Put(r.Context(), "flash", "you cannot change your own account from the admin area.")
+		http.Redirect(w, r, fmt.Sprintf("/admin/users/%d", user.ID), http.StatusSeeOther)
+		return nil, false
+	}
+	if user.HasRole(models.RoleModerator) && !app.hasRole(r, models.RoleAdmin) {
+		app.clientError(w, http.StatusForbidden)
+		return nil, false
+	}
+	return user, true
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/MohammadLashkari/snippetbox/internal/assert"
+)
+
+func TestRequireRole(t *testing.T) {
+	app := newTestApplication(t)
+
+	tests := []struct {
+		name     string
+		email    string
+		urlPath  string
+		wantCode int
+	}{
+		{"Anonymous", "", "/admin", http.StatusSeeOther},
+		{"User", "foo@example.com", "/admin", http.StatusForbidden},
+		{"Moderator", "mod@example.com", "/admin", http.StatusOK},
+		{"Admin", "admin@example.com", "/admin", http.StatusOK},
+		{"Moderator lockouts", "mod@example.com", "/admin/lockouts", http.StatusForbidden},
+		{"Admin lockouts", "admin@example.com", "/admin/lockouts", http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ts := newTestServer(t, app.routes())
+			defer ts.Close()
+			if tt.email != "" {
+				ts.logInAs(t, tt.email)
+			}
+			code, _, _ := ts.get(t, tt.urlPath)
+			assert.Equal(t, code, tt.wantCode)
+		})
+	}
+}
+
+func TestAdminUsers(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+	ts.logInAs(t, "mod@example.com")
+
+	code, _, body := ts.get(t, "/admin?q=baz")
+	assert.Equal(t, code, http.StatusOK)
+	assert.StringContains(t, body, "<a href='/admin/users/3'>baz</a>")
+	assert.StringContains(t, body, "Unverified")
+	assert.Equal(t, strings.Contains(body, "foo@gmail.com"), false)
+
+	code, _, body = ts.get(t, "/admin/users/1")
+	assert.Equal(t, code, http.StatusOK)
+	assert.StringContains(t, body, "<form action='/admin/users/1/suspend' method='POST'>")
+	assert.StringContains(t, body, "<form action='/admin/snippets/1/delete' method='POST'>")
+	assert.Equal(t, strings.Contains(body, "/admin/users/1/delete"), false)
+
+	code, _, _ = ts.get(t, "/admin/users/99")
+	assert.Equal(t, code, http.StatusNotFound)
+}
+
+func TestAdminUserActions(t *testing.T) {
+	app := newTestApplication(t)
+
+	tests := []struct {
+		name         string
+		email        string
+		urlPath      string
+		form         url.Values
+		wantCode     int
+		wantLocation string
+	}{
+		{"Suspend user", "mod@example.com", "/admin/users/1/suspend", nil, http.StatusSeeOther, "/admin/users/1"},
+		{"Unsuspend user", "mod@example.com", "/admin/users/7/unsuspend", nil, http.StatusSeeOther, "/admin/users/7"},
+		{"Suspend admin as moderator", "mod@example.com", "/admin/users/5/suspend", nil, http.StatusForbidden, ""},
+		{"Suspend self", "mod@example.com", "/admin/users/6/suspend", nil, http.StatusSeeOther, "/admin/users/6"},
+		{"Suspend moderator as admin", "admin@example.com", "/admin/users/6/suspend", nil, http.StatusSeeOther, "/admin/users/6"},
+		{"Suspend unknown", "mod@example.com", "/admin/users/99/suspend", nil, http.StatusNotFound, ""},
+		{"Delete as moderator", "mod@example.com", "/admin/users/1/delete", nil, http.StatusForbidden, ""},
+		{"Delete as admin", "admin@example.com", "/admin/users/1/delete", nil, http.StatusSeeOther, "/admin"},
+		{"Set role", "admin@example.com", "/admin/users/1/role", url.Values{"role": {"moderator"}}, http.StatusSeeOther, "/admin/users/1"},
+		{"Set invalid role", "admin@example.com", "/admin/users/1/role", url.Values{"role": {"owner"}}, http.StatusBadRequest, ""},
+		{"Remove snippet", "mod@example.com", "/admin/snippets/1/delete", nil, http.StatusSeeOther, "/admin/users/1"},
+		{"Remove snippet without owner", "mod@example.com", "/admin/snippets/4/delete", nil, http.StatusSeeOther, "/admin"},
+		{"Remove unknown snippet", "mod@example.com", "/admin/snippets/99/delete", nil, http.StatusNotFound, ""},
+		{"Remove snippet as user", "foo@example.com", "/admin/snippets/1/delete", nil, http.StatusForbidden, ""},
+		{"Lift lockout", "admin@example.com", "/admin/lockouts/clear", url.Values{"key": {"email:foo@example.com"}}, http.StatusSeeOther, "/admin/lockouts"},
+		{"Lift blank lockout", "admin@example.com", "/admin/lockouts/clear", url.Values{"key": {""}}, http.StatusBadRequest, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ts := newTestServer(t, app.routes())
+			defer ts.Close()
+			ts.logInAs(t, tt.email)
+			_, _, body := ts.get(t, "/account/view")
+
+			form := url.Values{}
+			for k, v := range tt.form {
+				form[k] = v
+			}
+			form.Set("csrf_token", extractCSRFToken(t, body))
+			code, header, _ := ts.postForm(t, tt.urlPath, form)
+			assert.Equal(t, code, tt.wantCode)
+			assert.Equal(t, header.Get("Location"), tt.wantLocation)
+		})
+	}
+}
+
+func TestAuthenticateSuspended(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+	ts.logInAs(t, "qux@example.com")
+
+	code, header, _ := ts.get(t, "/account/view")
+	assert.Equal(t, code, http.StatusSeeOther)
+	assert.Equal(t, header.Get("Location"), "/user/login")
+
+	_, _, body := ts.get(t, "/user/login")
+	assert.StringContains(t, body, "your account has been suspended.")
+
+	header = http.Header{"Authorization": {"token suspended-token"}}
+	code, _, body = ts.request(t, http.MethodPost, "/api/gists", header, nil)
+	assert.Equal(t, code, http.StatusForbidden)
+	assert.StringContains(t, body, "Account suspended")
+}
@@ -0,0 +1,200 @@
+package main
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"time"
+
+	"github.com/MohammadLashkari/snippetbox/internal/models"
+)
+
+// An uploaded archive may be archiveMaxUploadSize bytes and hold
+// archiveMaxEntries snippets of up to archiveMaxFileSize bytes each, but no
+// more than archiveMaxTotalSize bytes of snippets once decompressed.
+const (
+	archiveManifestName  = "manifest.json"
+	archiveMaxUploadSize = 10 << 20
+	archiveMaxEntries    = 1000
+	archiveMaxFileSize   = 1 << 20
+	archiveMaxTotalSize  = 10 << 20
+)
+
+var errArchiveFileTooLarge = errors.New("file is too large")
+
+type archiveManifest struct {
+	Version    int                    `json:"version"`
+	ExportedAt time.Time              `json:"exported_at"`
+	Snippets   []archiveManifestEntry `json:"snippets"`
+}
+
+type archiveManifestEntry struct {
+	File    string    `json:"file"`
+	ID      int       `json:"id"`
+	Title   string    `json:"title"`
+	Created time.Time `json:"created"`
+	Updated time.Time `json:"updated"`
+	Expires time.Time `json:"expires"`
+}
+
+// importEntry is one snippet read back from an archive. Err is set when the
+// entry could not be read at all; otherwise Form still has to be validated.
+type importEntry struct {
+	File string
+	Form snippetCreateForm
+	Err  string
+}
+
+// writeSnippetArchive writes a zip archive holding one file per snippet
+// and a manifest describing them.
+func writeSnippetArchive(w io.Writer, snippets []*models.Snippet) error {
+	zw := zip.NewWriter(w)
+	manifest := archiveManifest{
+		Version:    1,
+		ExportedAt: time.Now().UTC().Truncate(time.Second),
+		Snippets:   []archiveManifestEntry{},
+	}
+	for _, s := range snippets {
+		name := fmt.Sprintf("snippets/%d.txt", s.ID)
+		f, err := zw.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate, Modified: s.Updated})
+		if err != nil {
+			return err
+		}
+		if _, err := io.WriteString(f, s.Content); err != nil {
+			return err
+		}
+		manifest.Snippets = append(manifest.Snippets, archiveManifestEntry{
+			File:    name,
+			ID:      s.ID,
+			Title:   s.Title,
+			Created: s.Created.UTC(),
+			Updated: s.Updated.UTC(),
+			Expires: s.Expires.UTC(),
+		})
+	}
+
+	f, err := zw.Create(archiveManifestName)
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(f)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(manifest); err != nil {
+		return err
+	}
+	return zw.Close()
+}
+
+// readSnippetArchive reads an archive produced by writeSnippetArchive. Each
+// snippet keeps the lifetime, in days, it was originally created with. The
+// whole archive is rejected once its snippets add up to more than
+// archiveMaxTotalSize bytes, counting what is actually decompressed rather
+// than the sizes the archive claims.
+func readSnippetArchive(ra io.ReaderAt, size int64) ([]importEntry, error) {
+	zr, err := zip.NewReader(ra, size)
+	if err != nil {
+		return nil, errors.New("the file is not a zip archive")
+	}
+	files := make(map[string]*zip.File, len(zr.File))
+	for _, f := range zr.File {
+		files[f.Name] = f
+	}
+
+	mf, ok := files[archiveManifestName]
+	if !ok {
+		return nil, fmt.Errorf("the archive has no %s", archiveManifestName)
+	}
+	var manifest archiveManifest
+	if err := readArchiveFile(mf, func(r io.Reader) error {
+		return json.NewDecoder(r).Decode(&manifest)
+	}); err != nil {
+		return nil, fmt.Errorf("%s is not valid: %w", archiveManifestName, err)
+	}
+	if len(manifest.Snippets) > archiveMaxEntries {
+		return nil, fmt.Errorf("the archive cannot hold more than %d snippets", archiveMaxEntries)
+	}
+
+	budget := int64(archiveMaxTotalSize)
+	seen := make(map[string]bool, len(manifest.Snippets))
+	entries := make([]importEntry, 0, len(manifest.Snippets))
+	for _, m := range manifest.Snippets {
+		entry := importEntry{
+			File: m.File,
+			Form: snippetCreateForm{
+				Title:   m.Title,
+				Expires: int(math.Round(m.Expires.Sub(m.Created).Hours() / 24)),
+			},
+		}
+		if seen[m.File] {
+			entry.Err = "file is listed more than once in the manifest"
+			entries = append(entries, entry)
+			continue
+		}
+		seen[m.File] = true
+		f, ok := files[m.File]
+		if !ok {
+			entry.Err = "file is missing from the archive"
+			entries = append(entries, entry)
+			continue
+		}
+		var overBudget bool
+		err := readArchiveFile(f, func(r io.Reader) error {
+			b, err := io.ReadAll(io.LimitReader(r, budget+1))
+			if int64(len(b)) > budget {
+				overBudget = true
+				return nil
+			}
+			budget -= int64(len(b))
+			entry.Form.Content = string(b)
+			return err
+		})
+		if overBudget {
+			return nil, fmt.Errorf("the snippets in the archive cannot add up to more than %dMB", archiveMaxTotalSize>>20)
+		}
+		if err != nil {
+			entry.Err = err.Error()
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// readArchiveFile passes the decompressed content of f to fn. Reading fails,
+// rather than stopping short, if f holds more than the archive says.
+func readArchiveFile(f *zip.File, fn func(io.Reader) error) error {
+	if f.UncompressedSize64 > archiveMaxFileSize {
+		return errArchiveFileTooLarge
+	}
+	rc, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	return fn(&sizedReader{r: rc, n: int64(f.UncompressedSize64)})
+}
+
+// sizedReader reads the n bytes left in r and fails with
+// errArchiveFileTooLarge if there is one more.
+type sizedReader struct {
+	r io.Reader
+	n int64
+}
+
+func (s *sizedReader) Read(p []byte) (int, error) {
+	if s.n < 0 {
+		return 0, errArchiveFileTooLarge
+	}
+	if int64(len(p)) > s.n+1 {
+		p = p[:s.n+1]
+	}
+	n, err := s.r.Read(p)
+	if int64(n) > s.n {
+		n, s.n = int(s.n), -1
+		return n, errArchiveFileTooLarge
+	}
+	s.n -= int64(n)
+	return n, err
+}
@@ -0,0 +1,195 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/MohammadLashkari/snippetbox/internal/assert"
+	"github.com/MohammadLashkari/snippetbox/internal/models"
+)
+
+func TestSnippetArchiveRoundTrip(t *testing.T) {
+	created := time.Date(2024, 06, 29, 21, 05, 0, 0, time.UTC)
+	snippets := []*models.Snippet{
+		{ID: 3, Title: "week", Content: "hello", Created: created, Updated: created, Expires: created.AddDate(0, 0, 7)},
+		{ID: 4, Title: "", Content: "world", Created: created, Updated: created, Expires: created.AddDate(0, 0, 30)},
+	}
+
+	var buf bytes.Buffer
+	if err := writeSnippetArchive(&buf, snippets); err != nil {
+		t.Fatal(err)
+	}
+	entries, err := readSnippetArchive(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, len(entries), 2)
+
+	assert.Equal(t, entries[0].File, "snippets/3.txt")
+	assert.Equal(t, entries[0].Form.Title, "week")
+	assert.Equal(t, entries[0].Form.Content, "hello")
+	assert.Equal(t, entries[0].Form.Expires, 7)
+	validateSnippetForm(&entries[0].Form)
+	assert.Equal(t, entries[0].Form.Valid(), true)
+
+	validateSnippetForm(&entries[1].Form)
+	assert.Equal(t, entries[1].Form.FieldErrors["title"], "this field cannot be blank")
+	assert.Equal(t, entries[1].Form.FieldErrors["expires"], "this field must equal 1, 7 or 365")
+}
+
+func TestReadSnippetArchiveErrors(t *testing.T) {
+	_, err := readSnippetArchive(bytes.NewReader([]byte("not a zip")), 9)
+	assert.Equal(t, err.Error(), "the file is not a zip archive")
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	f, _ := zw.Create(archiveManifestName)
+	f.Write([]byte(`{"version": 1, "snippets": [{"file": "snippets/1.txt", "title": "gone"}]}`))
+	zw.Close()
+
+	entries, err := readSnippetArchive(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, len(entries), 1)
+	assert.Equal(t, entries[0].Err, "file is missing from the archive")
+}
+
+// buildArchive zips a manifest listing the given snippets with the given
+// files.
+func buildArchive(t *testing.T, manifest string, files map[string]string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, content := range files {
+		f, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		f.Write([]byte(content))
+	}
+	f, err := zw.Create(archiveManifestName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Write([]byte(manifest))
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestReadSnippetArchiveLimits(t *testing.T) {
+	t.Run("Duplicate file", func(t *testing.T) {
+		archive := buildArchive(t, `{"version": 1, "snippets": [
+			{"file": "snippets/1.txt", "title": "first"},
+			{"file": "snippets/1.txt", "title": "again"}
+		]}`, map[string]string{"snippets/1.txt": "hello"})
+
+		entries, err := readSnippetArchive(bytes.NewReader(archive), int64(len(archive)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.Equal(t, len(entries), 2)
+		assert.Equal(t, entries[0].Err, "")
+		assert.Equal(t, entries[0].Form.Content, "hello")
+		assert.Equal(t, entries[1].Err, "file is listed more than once in the manifest")
+	})
+
+	t.Run("Total size", func(t *testing.T) {
+		files := map[string]string{}
+		var listed []string
+		content := strings.Repeat("a", archiveMaxFileSize)
+		for i := range archiveMaxTotalSize/archiveMaxFileSize + 1 {
+			name := fmt.Sprintf("snippets/%d.txt", i)
+			files[name] = content
+			listed = append(listed, fmt.Sprintf(`{"file": %q, "title": "big"}`, name))
+		}
+		archive := buildArchive(t, `{"version": 1, "snippets": [`+strings.Join(listed, ",")+`]}`, files)
+
+		_, err := readSnippetArchive(bytes.NewReader(archive), int64(len(archive)))
+		if err == nil {
+			t.Fatal("expected an error")
+		}
+		assert.Equal(t, err.Error(), "the snippets in the archive cannot add up to more than 10MB")
+	})
+
+	t.Run("More than the archive says", func(t *testing.T) {
+		b, err := io.ReadAll(&sizedReader{r: strings.NewReader("hello"), n: 5})
+		assert.Equal(t, string(b), "hello")
+		assert.Equal(t, err, nil)
+
+		b, err = io.ReadAll(&sizedReader{r: strings.NewReader("hello"), n: 4})
+		assert.Equal(t, string(b), "hell")
+		assert.Equal(t, err, errArchiveFileTooLarge)
+	})
+}
+
+func TestAccountImportPost(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+	ts.logIn(t)
+
+	_, _, body := ts.get(t, "/account/import")
+	csrfToken := extractCSRFToken(t, body)
+
+	upload := func(t *testing.T, archive []byte) (int, string) {
+		var buf bytes.Buffer
+		mw := multipart.NewWriter(&buf)
+		mw.WriteField("csrf_token", csrfToken)
+		fw, err := mw.CreateFormFile("archive", "snippets.zip")
+		if err != nil {
+			t.Fatal(err)
+		}
+		fw.Write(archive)
+		mw.Close()
+		header := http.Header{"Content-Type": {mw.FormDataContentType()}, "Origin": {ts.URL}}
+		code, _, body := ts.request(t, http.MethodPost, "/account/import", header, &buf)
+		return code, body
+	}
+
+	t.Run("Per-entry errors", func(t *testing.T) {
+		created := "2024-06-29T21:05:00Z"
+		week := "2024-07-06T21:05:00Z"
+		archive := buildArchive(t, `{"version": 1, "snippets": [
+			{"file": "snippets/1.txt", "title": "kept", "created": "`+created+`", "expires": "`+week+`"},
+			{"file": "snippets/2.txt", "title": "", "created": "`+created+`", "expires": "`+week+`"},
+			{"file": "snippets/3.txt", "title": "gone", "created": "`+created+`", "expires": "`+week+`"},
+			{"file": "snippets/1.txt", "title": "twice", "created": "`+created+`", "expires": "`+week+`"}
+		]}`, map[string]string{
+			"snippets/1.txt": "hello",
+			"snippets/2.txt": "untitled",
+		})
+
+		code, body := upload(t, archive)
+		assert.Equal(t, code, http.StatusOK)
+		assert.StringContains(t, body, "imported 1 of 4 snippets")
+		assert.StringContains(t, body, "Imported as #")
+		assert.StringContains(t, body, "title: this field cannot be blank")
+		assert.StringContains(t, body, "file is missing from the archive")
+		assert.StringContains(t, body, "file is listed more than once in the manifest")
+	})
+
+	t.Run("Not an archive", func(t *testing.T) {
+		code, body := upload(t, []byte("not a zip"))
+		assert.Equal(t, code, http.StatusUnprocessableEntity)
+		assert.StringContains(t, body, "the file is not a zip archive")
+	})
+
+	t.Run("Too large", func(t *testing.T) {
+		// The first upload is read, the second turned away unread.
+		for _, size := range []int{archiveMaxUploadSize + 1, archiveMaxUploadSize + 64<<10} {
+			code, body := upload(t, make([]byte, size))
+			assert.Equal(t, code, http.StatusUnprocessableEntity)
+			assert.StringContains(t, body, "the archive cannot be larger than 10MB")
+		}
+	})
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/MohammadLashkari/snippetbox/internal/models"
+)
+
+// auditEventLimit caps the number of audit log entries shown on a page.
+const auditEventLimit = 200
+
+// audit records a security event concerning the user in the audit log. A
+// failure to record it is logged rather than failing the request.
+func (app *application) audit(r *http.Request, userID int, event, detail string) {
+	app.recordAudit(r, &models.AuditEvent{UserID: userID, Event: event, Detail: detail})
+}
+
+// auditAdmin records an action taken by the logged in staff member on the
+// user's account.
+func (app *application) auditAdmin(r *http.Request, userID int, event, detail string) {
+	app.recordAudit(r, &models.AuditEvent{
+		UserID:  userID,
+		ActorID: app.sessionManager.GetInt(r.Context(), "authenticatedUserID"),
+		Event:   event,
+		Detail:  detail,
+	})
+}
+
+// auditLoginFailed records a failed password login against the account
+// with email, or with the address when there is no such account.
+func (app *application) auditLoginFailed(r *http.Request, email string) {
+	user, err := app.users.GetByEmail(email)
+	switch {
+	case err == nil:
+		app.audit(r, user.ID, models.AuditLoginFailed, "password")
+	case errors.Is(err, models.ErrNoRecord):
+		app.audit(r, 0, models.AuditLoginFailed, "password for unknown account "+email)
+	default:
+		app.logError(r.Context(), 1, err.Error())
+	}
+}
+
+func (app *application) recordAudit(r *http.Request, e *models.AuditEvent) {
+	e.IP = clientIP(r)
+	e.UserAgent = r.UserAgent()
+	if err := app.auditLog.Insert(e); err != nil {
+		app.logError(r.Context(), 3, err.Error(), "event", e.Event, "user_id", e.UserID)
+	}
+}
+
+func (app *application) accountHistory(w http.ResponseWriter, r *http.Request) {
+	id := app.sessionManager.GetInt(r.Context(), "authenticatedUserID")
+	events, err := app.auditLog.Search(models.AuditFilter{UserID: id, Limit: auditEventLimit})
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	data := app.newTemplateData(r)
+	data.AuditEvents = events
+	app.render(w, r, http.StatusOK, "history.tmpl", data)
+}
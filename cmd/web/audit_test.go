@@ -0,0 +1,120 @@
+package main
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/MohammadLashkari/snippetbox/internal/assert"
+	"github.com/MohammadLashkari/snippetbox/internal/models"
+)
+
+func TestAuditLogins(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	_, _, body := ts.get(t, "/user/login")
+	for _, email := range []string{"foo@gmail.com", "nobody@example.com"} {
+		form := url.Values{}
+		form.Add("email", email)
+		form.Add("password", "wrong password")
+		form.Add("csrf_token", extractCSRFToken(t, body))
+		code, _, _ := ts.postForm(t, "/user/login", form)
+		assert.Equal(t, code, http.StatusUnprocessableEntity)
+	}
+	ts.logIn(t)
+
+	events, err := app.auditLog.Search(models.AuditFilter{Limit: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, len(events), 3)
+	assert.Equal(t, events[0].Event, models.AuditLogin)
+	assert.Equal(t, events[0].UserID, 1)
+	assert.Equal(t, events[0].Detail, "password")
+	assert.Equal(t, events[1].Event, models.AuditLoginFailed)
+	assert.Equal(t, events[1].UserID, 0)
+	assert.Equal(t, events[1].Detail, "password for unknown account nobody@example.com")
+	assert.Equal(t, events[2].Event, models.AuditLoginFailed)
+	assert.Equal(t, events[2].UserID, 1)
+	assert.Equal(t, events[2].IP, "127.0.0.1")
+
+	code, _, body := ts.get(t, "/account/history")
+	assert.Equal(t, code, http.StatusOK)
+	assert.StringContains(t, body, "<td>login.failed</td>")
+	assert.Equal(t, strings.Count(body, "<td>login</td>"), 1)
+
+	form := url.Values{"csrf_token": {extractCSRFToken(t, body)}}
+	ts.postForm(t, "/user/logout", form)
+	events, err = app.auditLog.Search(models.AuditFilter{UserID: 1, Event: models.AuditLogout, Limit: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, len(events), 1)
+}
+
+func TestAdminAudit(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+	ts.logInAs(t, "admin@example.com")
+
+	_, _, body := ts.get(t, "/admin/users/1")
+	form := url.Values{"csrf_token": {extractCSRFToken(t, body)}}
+	code, _, _ := ts.postForm(t, "/admin/users/1/suspend", form)
+	assert.Equal(t, code, http.StatusSeeOther)
+
+	tests := []struct {
+		name     string
+		urlPath  string
+		wantCode int
+		wantBody string
+	}{
+		{"All", "/admin/audit", http.StatusOK, "<td>admin.suspend</td>"},
+		{"By user", "/admin/audit?user=1", http.StatusOK, "<a href='/admin/users/5'>#5</a>"},
+		{"By staff", "/admin/audit?actor=5", http.StatusOK, "<td>admin.suspend</td>"},
+		{"By staff without events", "/admin/audit?actor=1", http.StatusOK, "No events match."},
+		{"By event", "/admin/audit?event=login", http.StatusOK, "<option value='login' selected>login</option>"},
+		{"By IP", "/admin/audit?ip=192.0.2.1", http.StatusOK, "No events match."},
+		{"Bad user", "/admin/audit?user=abc", http.StatusBadRequest, ""},
+		{"Bad staff", "/admin/audit?actor=0", http.StatusBadRequest, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, _, body := ts.get(t, tt.urlPath)
+			assert.Equal(t, code, tt.wantCode)
+			assert.StringContains(t, body, tt.wantBody)
+		})
+	}
+
+	mod := newTestServer(t, app.routes())
+	defer mod.Close()
+	mod.logInAs(t, "mod@example.com")
+	code, _, _ = mod.get(t, "/admin/audit")
+	assert.Equal(t, code, http.StatusForbidden)
+}
+
+func TestAccountHistoryStaffActions(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	err := app.auditLog.Insert(&models.AuditEvent{UserID: 1, ActorID: 5, Event: models.AuditAdminSuspend, IP: "192.0.2.5", UserAgent: "staff-agent"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = app.auditLog.Insert(&models.AuditEvent{UserID: 2, ActorID: 1, Event: models.AuditAdminUnsuspend, IP: "192.0.2.1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts.logIn(t)
+
+	code, _, body := ts.get(t, "/account/history")
+	assert.Equal(t, code, http.StatusOK)
+	assert.StringContains(t, body, "<td>admin.suspend (by staff)</td>")
+	assert.Equal(t, strings.Contains(body, "192.0.2.5"), false)
+	assert.Equal(t, strings.Contains(body, "staff-agent"), false)
+	assert.Equal(t, strings.Contains(body, "admin.unsuspend"), false)
+}
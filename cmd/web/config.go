@@ -0,0 +1,282 @@
+package main
+
+import (
+	"encoding/hex"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/MohammadLashkari/snippetbox/internal/passwords"
+	"github.com/go-sql-driver/mysql"
+)
+
+// envPrefix starts the name of the environment variable for each flag,
+// e.g. SNIPPETBOX_SMTP_HOST for -smtp-host.
+const envPrefix = "SNIPPETBOX_"
+
+// redacted replaces secrets in the output of -print-config.
+const redacted = "REDACTED"
+
+// config holds every setting of the server. Settings come from, in
+// increasing order of precedence, the defaults, a TOML file given by
+// -config, SNIPPETBOX_* environment variables and command-line flags.
+type config struct {
+	File        string `toml:"-"`
+	PrintConfig bool   `toml:"-"`
+
+	Host    string `toml:"host"`
+	Port    string `toml:"port"`
+	BaseURL string `toml:"base_url"`
+	DSN     string `toml:"dsn"`
+	Debug   bool   `toml:"debug"`
+	Secret  string `toml:"secret"`
+	MailDir string `toml:"mail_dir"`
+
+	Log struct {
+		Format string `toml:"format"`
+	} `toml:"log"`
+
+	TLS struct {
+		Cert string `toml:"cert"`
+		Key  string `toml:"key"`
+	} `toml:"tls"`
+
+	Session struct {
+		Lifetime time.Duration `toml:"lifetime"`
+	} `toml:"session"`
+
+	Server struct {
+		IdleTimeout  time.Duration `toml:"idle_timeout"`
+		ReadTimeout  time.Duration `toml:"read_timeout"`
+		WriteTimeout time.Duration `toml:"write_timeout"`
+	} `toml:"server"`
+
+	Shutdown struct {
+		Delay   time.Duration `toml:"delay"`
+		Timeout time.Duration `toml:"timeout"`
+	} `toml:"shutdown"`
+
+	SMTP struct {
+		Host     string `toml:"host"`
+		Port     int    `toml:"port"`
+		Username string `toml:"username"`
+		Password string `toml:"password"`
+		Sender   string `toml:"sender"`
+	} `toml:"smtp"`
+
+	OIDC struct {
+		Issuer       string `toml:"issuer"`
+		ClientID     string `toml:"client_id"`
+		ClientSecret string `toml:"client_secret"`
+		Name         string `toml:"name"`
+	} `toml:"oidc"`
+
+	Passwords struct {
+		Hash              string `toml:"hash"`
+		Argon2Memory      uint   `toml:"argon2_memory"`
+		Argon2Iterations  uint   `toml:"argon2_iterations"`
+		Argon2Parallelism uint   `toml:"argon2_parallelism"`
+		BcryptCost        int    `toml:"bcrypt_cost"`
+	} `toml:"passwords"`
+}
+
+// flags defines a flag for every setting, setting each one to its default.
+func (cfg *config) flags(name string, output io.Writer) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(output)
+
+	fs.StringVar(&cfg.File, "config", "", "TOML configuration file")
+	fs.BoolVar(&cfg.PrintConfig, "print-config", false, "Print the effective configuration, with secrets redacted, and exit")
+
+	fs.StringVar(&cfg.Host, "host", "localhost", "HTTP network host")
+	fs.StringVar(&cfg.Port, "port", "8080", "HTTP network port")
+	fs.StringVar(&cfg.BaseURL, "base-url", "", "Public URL of the site, used in emailed links, single sign-on and passkeys (default https://host:port)")
+	fs.StringVar(&cfg.DSN, "dsn", "web:pass@/snippetbox?parseTime=true", "MySQL data source name")
+	fs.BoolVar(&cfg.Debug, "debug", false, "Enable debug mode")
+	fs.StringVar(&cfg.Secret, "secret", "", "Hex-encoded key for signing email links (at least 32 bytes)")
+	fs.StringVar(&cfg.MailDir, "mail-dir", "", "Write emails to .eml files in this directory instead of sending them")
+
+	fs.StringVar(&cfg.Log.Format, "log-format", "text", "Log format (text or json)")
+	fs.StringVar(&cfg.TLS.Cert, "tls-cert", "./tls/cert.pem", "TLS certificate file")
+	fs.StringVar(&cfg.TLS.Key, "tls-key", "./tls/key.pem", "TLS private key file")
+	fs.DurationVar(&cfg.Session.Lifetime, "session-lifetime", 12*time.Hour, "How long a login session lasts")
+	fs.DurationVar(&cfg.Server.IdleTimeout, "idle-timeout", time.Minute, "How long to keep idle keep-alive connections open")
+	fs.DurationVar(&cfg.Server.ReadTimeout, "read-timeout", 5*time.Second, "Maximum time to read a request")
+	fs.DurationVar(&cfg.Server.WriteTimeout, "write-timeout", 10*time.Second, "Maximum time to write a response")
+	fs.DurationVar(&cfg.Shutdown.Delay, "shutdown-delay", 0, "How long to keep serving after a shutdown signal while /ready reports unavailable")
+	fs.DurationVar(&cfg.Shutdown.Timeout, "shutdown-timeout", 30*time.Second, "Maximum time to wait for in-flight requests and background tasks on shutdown")
+
+	fs.StringVar(&cfg.SMTP.Host, "smtp-host", "", "SMTP host; emails are logged when neither this nor -mail-dir is set")
+	fs.IntVar(&cfg.SMTP.Port, "smtp-port", 587, "SMTP port")
+	fs.StringVar(&cfg.SMTP.Username, "smtp-username", "", "SMTP username")
+	fs.StringVar(&cfg.SMTP.Password, "smtp-password", "", "SMTP password")
+	fs.StringVar(&cfg.SMTP.Sender, "smtp-sender", "Snippetbox <no-reply@snippetbox.local>", "SMTP sender")
+
+	fs.StringVar(&cfg.OIDC.Issuer, "oidc-issuer", "", "OpenID Connect issuer URL; enables single sign-on")
+	fs.StringVar(&cfg.OIDC.ClientID, "oidc-client-id", "", "OpenID Connect client ID")
+	fs.StringVar(&cfg.OIDC.ClientSecret, "oidc-client-secret", "", "OpenID Connect client secret")
+	fs.StringVar(&cfg.OIDC.Name, "oidc-name", "single sign-on", "Name of the identity provider shown on the login page")
+
+	fs.StringVar(&cfg.Passwords.Hash, "password-hash", passwords.Argon2id, "Algorithm for new password hashes (argon2id or bcrypt)")
+	fs.UintVar(&cfg.Passwords.Argon2Memory, "argon2-memory", uint(passwords.DefaultArgon2idParams.Memory), "Argon2id memory in KiB")
+	fs.UintVar(&cfg.Passwords.Argon2Iterations, "argon2-iterations", uint(passwords.DefaultArgon2idParams.Iterations), "Argon2id iterations")
+	fs.UintVar(&cfg.Passwords.Argon2Parallelism, "argon2-parallelism", uint(passwords.DefaultArgon2idParams.Parallelism), "Argon2id parallelism")
+	fs.IntVar(&cfg.Passwords.BcryptCost, "bcrypt-cost", passwords.DefaultBcryptCost, "Bcrypt cost")
+	return fs
+}
+
+// envName returns the environment variable for the flag called name.
+func envName(name string) string {
+	return envPrefix + strings.ToUpper(strings.ReplaceAll(name, "-", "_"))
+}
+
+// loadConfig merges the defaults, the config file, the environment, read
+// through lookupEnv, and the command-line arguments, and validates the
+// result. It returns flag.ErrHelp if args ask for usage.
+func loadConfig(args []string, lookupEnv func(string) (string, bool), output io.Writer) (*config, error) {
+	cfg := &config{}
+	fs := cfg.flags("snippetbox", output)
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+	if fs.NArg() > 0 {
+		return nil, fmt.Errorf("unexpected argument %q", fs.Arg(0))
+	}
+
+	// Flags win over everything else, so note the ones given and put every
+	// setting back to its default before reading the file and environment.
+	given := map[string]string{}
+	fs.Visit(func(f *flag.Flag) {
+		given[f.Name] = f.Value.String()
+	})
+	fs.VisitAll(func(f *flag.Flag) {
+		f.Value.Set(f.DefValue)
+	})
+
+	file, ok := given["config"]
+	if !ok {
+		file, _ = lookupEnv(envName("config"))
+	}
+	if file != "" {
+		md, err := toml.DecodeFile(file, cfg)
+		if err != nil {
+			return nil, fmt.Errorf("config file: %w", err)
+		}
+		if undecoded := md.Undecoded(); len(undecoded) > 0 {
+			return nil, fmt.Errorf("config file: unknown setting %q", undecoded[0].String())
+		}
+	}
+
+	var err error
+	fs.VisitAll(func(f *flag.Flag) {
+		value, ok := lookupEnv(envName(f.Name))
+		if !ok || err != nil {
+			return
+		}
+		if setErr := f.Value.Set(value); setErr != nil {
+			err = fmt.Errorf("invalid value %q for %s: %w", value, envName(f.Name), setErr)
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for name, value := range given {
+		fs.Set(name, value)
+	}
+	cfg.File = file
+	if cfg.BaseURL == "" {
+		cfg.BaseURL = "https://" + cfg.addr()
+	}
+	cfg.BaseURL = strings.TrimSuffix(cfg.BaseURL, "/")
+
+	if err := cfg.validate(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// validate returns every problem with the configuration, joined.
+func (cfg *config) validate() error {
+	var errs []error
+	check := func(ok bool, message string) {
+		if !ok {
+			errs = append(errs, errors.New(message))
+		}
+	}
+
+	port, err := strconv.Atoi(cfg.Port)
+	check(err == nil && port > 0 && port <= math.MaxUint16, "port must be a number between 1 and 65535")
+	base, err := url.Parse(cfg.BaseURL)
+	check(err == nil && (base.Scheme == "https" || base.Scheme == "http") && base.Host != "" &&
+		base.Path == "" && base.RawQuery == "" && base.Fragment == "" && base.User == nil,
+		"base url must be an http or https URL without a path")
+	check(cfg.DSN != "", "dsn must be set")
+	key, err := hex.DecodeString(cfg.Secret)
+	check(err == nil && len(key) >= 32, "secret must be at least 32 hex-encoded bytes")
+	check(cfg.Log.Format == "text" || cfg.Log.Format == "json", "log format must be text or json")
+	check(cfg.TLS.Cert != "" && cfg.TLS.Key != "", "tls cert and key must be set")
+	check(cfg.Session.Lifetime > 0, "session lifetime must be positive")
+	check(cfg.Server.IdleTimeout > 0 && cfg.Server.ReadTimeout > 0 && cfg.Server.WriteTimeout > 0,
+		"server timeouts must be positive")
+	check(cfg.Shutdown.Delay >= 0, "shutdown delay must not be negative")
+	check(cfg.Shutdown.Timeout > 0, "shutdown timeout must be positive")
+	check(cfg.SMTP.Port > 0 && cfg.SMTP.Port <= math.MaxUint16, "smtp port must be between 1 and 65535")
+	check(cfg.OIDC.Issuer == "" || cfg.OIDC.ClientID != "", "oidc client id must be set with an issuer")
+	check(cfg.Passwords.Argon2Memory <= math.MaxUint32, "argon2 memory must be at most 4294967295 KiB")
+	check(cfg.Passwords.Argon2Iterations <= math.MaxUint32, "argon2 iterations must be at most 4294967295")
+	check(cfg.Passwords.Argon2Parallelism <= math.MaxUint8, "argon2 parallelism must be at most 255")
+	if len(errs) == 0 {
+		if err := cfg.hasher().Validate(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// addr is the network address the server listens on.
+func (cfg *config) addr() string {
+	return net.JoinHostPort(cfg.Host, cfg.Port)
+}
+
+// hasher builds the password hasher from the password settings.
+func (cfg *config) hasher() *passwords.Hasher {
+	hasher := passwords.Default()
+	hasher.Algorithm = cfg.Passwords.Hash
+	hasher.Argon2id.Memory = uint32(cfg.Passwords.Argon2Memory)
+	hasher.Argon2id.Iterations = uint32(cfg.Passwords.Argon2Iterations)
+	hasher.Argon2id.Parallelism = uint8(cfg.Passwords.Argon2Parallelism)
+	hasher.BcryptCost = cfg.Passwords.BcryptCost
+	return hasher
+}
+
+// print writes the configuration as TOML, in the format -config reads,
+// with the signing key, passwords and client secret redacted.
+func (cfg *config) print(w io.Writer) error {
+	c := *cfg
+	redact := func(s *string) {
+		if *s != "" {
+			*s = redacted
+		}
+	}
+	redact(&c.Secret)
+	redact(&c.SMTP.Password)
+	redact(&c.OIDC.ClientSecret)
+	if dsn, err := mysql.ParseDSN(c.DSN); err == nil {
+		redact(&dsn.Passwd)
+		c.DSN = dsn.FormatDSN()
+	} else {
+		c.DSN = redacted
+	}
+	enc := toml.NewEncoder(w)
+	enc.Indent = ""
+	return enc.Encode(c)
+}
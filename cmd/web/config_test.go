@@ -0,0 +1,256 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/MohammadLashkari/snippetbox/internal/assert"
+)
+
+const testSecret = "00112233445566778899aabbccddeeff00112233445566778899aabbccddeeff"
+
+func writeConfigFile(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "snippetbox.toml")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func testEnv(env map[string]string) func(string) (string, bool) {
+	return func(name string) (string, bool) {
+		value, ok := env[name]
+		return value, ok
+	}
+}
+
+func TestLoadConfig(t *testing.T) {
+	file := writeConfigFile(t, `
+port = "4000"
+secret = "`+testSecret+`"
+base_url = "https://snippetbox.example.com/"
+
+[session]
+lifetime = "2h"
+
+[smtp]
+host = "smtp.example.com"
+port = 2525
+`)
+
+	t.Run("Defaults", func(t *testing.T) {
+		cfg, err := loadConfig([]string{"-secret", testSecret}, testEnv(nil), io.Discard)
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.Equal(t, cfg.addr(), "localhost:8080")
+		assert.Equal(t, cfg.BaseURL, "https://localhost:8080")
+		assert.Equal(t, cfg.TLS.Cert, "./tls/cert.pem")
+		assert.Equal(t, cfg.TLS.Key, "./tls/key.pem")
+		assert.Equal(t, cfg.Session.Lifetime, 12*time.Hour)
+		assert.Equal(t, cfg.Server.ReadTimeout, 5*time.Second)
+		assert.Equal(t, cfg.Log.Format, "text")
+		assert.Equal(t, cfg.Shutdown.Delay, time.Duration(0))
+		assert.Equal(t, cfg.Shutdown.Timeout, 30*time.Second)
+		assert.Equal(t, cfg.SMTP.Port, 587)
+		assert.Equal(t, cfg.hasher().Algorithm, "argon2id")
+	})
+
+	t.Run("File", func(t *testing.T) {
+		cfg, err := loadConfig([]string{"-config", file}, testEnv(nil), io.Discard)
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.Equal(t, cfg.File, file)
+		assert.Equal(t, cfg.addr(), "localhost:4000")
+		assert.Equal(t, cfg.BaseURL, "https://snippetbox.example.com")
+		assert.Equal(t, cfg.Session.Lifetime, 2*time.Hour)
+		assert.Equal(t, cfg.SMTP.Host, "smtp.example.com")
+		assert.Equal(t, cfg.SMTP.Port, 2525)
+		assert.Equal(t, cfg.Server.WriteTimeout, 10*time.Second)
+	})
+
+	t.Run("Environment over file", func(t *testing.T) {
+		env := testEnv(map[string]string{
+			"SNIPPETBOX_CONFIG":           file,
+			"SNIPPETBOX_PORT":             "5000",
+			"SNIPPETBOX_SESSION_LIFETIME": "30m",
+			"SNIPPETBOX_DEBUG":            "true",
+		})
+		cfg, err := loadConfig(nil, env, io.Discard)
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.Equal(t, cfg.addr(), "localhost:5000")
+		assert.Equal(t, cfg.Session.Lifetime, 30*time.Minute)
+		assert.Equal(t, cfg.Debug, true)
+		assert.Equal(t, cfg.SMTP.Port, 2525)
+	})
+
+	t.Run("Flags over environment", func(t *testing.T) {
+		env := testEnv(map[string]string{"SNIPPETBOX_PORT": "5000", "SNIPPETBOX_HOST": "0.0.0.0"})
+		cfg, err := loadConfig([]string{"-config", file, "-port", "6000", "-smtp-port", "25"}, env, io.Discard)
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.Equal(t, cfg.addr(), "0.0.0.0:6000")
+		assert.Equal(t, cfg.SMTP.Port, 25)
+	})
+
+	t.Run("Flag set to its default", func(t *testing.T) {
+		env := testEnv(map[string]string{"SNIPPETBOX_PORT": "5000"})
+		cfg, err := loadConfig([]string{"-config", file, "-port", "8080"}, env, io.Discard)
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.Equal(t, cfg.Port, "8080")
+	})
+
+	t.Run("Help", func(t *testing.T) {
+		_, err := loadConfig([]string{"-h"}, testEnv(nil), io.Discard)
+		assert.Equal(t, errors.Is(err, flag.ErrHelp), true)
+	})
+}
+
+func TestLoadConfigErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string
+		args    []string
+		env     map[string]string
+		wantErr string
+	}{
+		{
+			name:    "Missing secret",
+			wantErr: "secret must be at least 32 hex-encoded bytes",
+		},
+		{
+			name:    "Bad port",
+			args:    []string{"-secret", testSecret, "-port", "http"},
+			wantErr: "port must be a number between 1 and 65535",
+		},
+		{
+			name:    "Bad environment value",
+			args:    []string{"-secret", testSecret},
+			env:     map[string]string{"SNIPPETBOX_READ_TIMEOUT": "soon"},
+			wantErr: `invalid value "soon" for SNIPPETBOX_READ_TIMEOUT`,
+		},
+		{
+			name:    "Unknown setting",
+			file:    "secret = \"" + testSecret + "\"\nsmtp_host = \"smtp.example.com\"\n",
+			wantErr: `config file: unknown setting "smtp_host"`,
+		},
+		{
+			name:    "Malformed file",
+			file:    "port = \n",
+			wantErr: "config file:",
+		},
+		{
+			name:    "Negative duration",
+			args:    []string{"-secret", testSecret, "-session-lifetime", "-1h"},
+			wantErr: "session lifetime must be positive",
+		},
+		{
+			name:    "Base URL with a path",
+			args:    []string{"-secret", testSecret, "-base-url", "https://snippetbox.example.com/app"},
+			wantErr: "base url must be an http or https URL without a path",
+		},
+		{
+			name:    "Relative base URL",
+			args:    []string{"-secret", testSecret, "-base-url", "snippetbox.example.com"},
+			wantErr: "base url must be an http or https URL without a path",
+		},
+		{
+			name:    "Bad log format",
+			args:    []string{"-secret", testSecret, "-log-format", "xml"},
+			wantErr: "log format must be text or json",
+		},
+		{
+			name:    "Zero shutdown timeout",
+			args:    []string{"-secret", testSecret, "-shutdown-timeout", "0s"},
+			wantErr: "shutdown timeout must be positive",
+		},
+		{
+			name:    "Issuer without client",
+			args:    []string{"-secret", testSecret, "-oidc-issuer", "https://id.example.com"},
+			wantErr: "oidc client id must be set with an issuer",
+		},
+		{
+			name:    "Bad hasher",
+			args:    []string{"-secret", testSecret, "-password-hash", "md5"},
+			wantErr: `passwords: unknown algorithm "md5"`,
+		},
+		{
+			name:    "Argon2 memory out of range",
+			args:    []string{"-secret", testSecret, "-argon2-memory", "4294967296"},
+			wantErr: "argon2 memory must be at most 4294967295 KiB",
+		},
+		{
+			name:    "Argon2 iterations out of range",
+			file:    "secret = \"" + testSecret + "\"\n[passwords]\nargon2_iterations = 4294967296\n",
+			wantErr: "argon2 iterations must be at most 4294967295",
+		},
+		{
+			name:    "Extra argument",
+			args:    []string{"-secret", testSecret, "serve"},
+			wantErr: `unexpected argument "serve"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			args := tt.args
+			if tt.file != "" {
+				args = append([]string{"-config", writeConfigFile(t, tt.file)}, args...)
+			}
+			_, err := loadConfig(args, testEnv(tt.env), io.Discard)
+			if err == nil {
+				t.Fatal("expected an error")
+			}
+			assert.StringContains(t, err.Error(), tt.wantErr)
+		})
+	}
+}
+
+func TestConfigPrint(t *testing.T) {
+	args := []string{
+		"-secret", testSecret,
+		"-dsn", "web:hunter2@tcp(db:3306)/snippetbox?parseTime=true",
+		"-smtp-password", "mailpass",
+		"-oidc-issuer", "https://id.example.com",
+		"-oidc-client-id", "snippetbox",
+		"-oidc-client-secret", "clientsecret",
+	}
+	cfg, err := loadConfig(args, testEnv(nil), io.Discard)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := cfg.print(&buf); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+
+	for _, secret := range []string{testSecret, "hunter2", "mailpass", "clientsecret"} {
+		assert.Equal(t, strings.Contains(out, secret), false)
+	}
+	assert.StringContains(t, out, `secret = "REDACTED"`)
+	assert.StringContains(t, out, `dsn = "web:REDACTED@tcp(db:3306)/snippetbox?parseTime=true"`)
+	assert.StringContains(t, out, `client_id = "snippetbox"`)
+	assert.StringContains(t, out, `lifetime = "12h0m0s"`)
+
+	// The output can be read back as a config file.
+	printed := writeConfigFile(t, out)
+	_, err = loadConfig([]string{"-config", printed, "-secret", testSecret}, testEnv(nil), io.Discard)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
@@ -2,4 +2,10 @@ package main
 
 type contextKey string
 
-const isAuthenticatedContextKey = contextKey("isAuthenticated")
+const (
+	isAuthenticatedContextKey = contextKey("isAuthenticated")
+	userRoleContextKey        = contextKey("userRole")
+	apiUserIDContextKey       = contextKey("apiUserID")
+	requestIDContextKey       = contextKey("requestID")
+	bodyTooLargeContextKey    = contextKey("bodyTooLarge")
+)
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/MohammadLashkari/snippetbox/internal/models"
+	"github.com/MohammadLashkari/snippetbox/internal/validator"
+)
+
+const changeEmailTTL = 48 * time.Hour
+
+type accountEmailUpdateForm struct {
+	Email               string `form:"email"`
+	Password            string `form:"password"`
+	validator.Validator `form:"-"`
+}
+
+func (app *application) accountEmailUpdate(w http.ResponseWriter, r *http.Request) {
+	data := app.newTemplateData(r)
+	data.Form = accountEmailUpdateForm{}
+	app.render(w, r, http.StatusOK, "email.tmpl", data)
+}
+
+// accountEmailUpdatePost records a pending change, mails a confirmation
+// link to the new address and lets the old address know a change was asked
+// for. The email address itself only changes once the link is followed.
+func (app *application) accountEmailUpdatePost(w http.ResponseWriter, r *http.Request) {
+	var form accountEmailUpdateForm
+	if err := app.decodePostForm(r, &form); err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+	id := app.sessionManager.GetInt(r.Context(), "authenticatedUserID")
+	user, err := app.users.Get(id)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	form.Email = strings.TrimSpace(form.Email)
+	form.CheckField(validator.NotBlank(form.Email), "email", "this field cannot be empty")
+	form.CheckField(validator.Matches(form.Email, validator.EmailRX), "email", "this field must be a valid email address")
+	form.CheckField(!strings.EqualFold(form.Email, user.Email), "email", "this is already your email address")
+	form.CheckField(validator.NotBlank(form.Password), "password", "this field cannot be empty")
+	if form.Valid() {
+		err := app.users.CheckPassword(id, form.Password)
+		if err != nil {
+			if !errors.Is(err, models.ErrInvalidCredentials) {
+				app.serverError(w, r, err)
+				return
+			}
+			form.AddFieldError("password", "password is incorrect")
+		}
+	}
+	if form.Valid() {
+		_, err := app.users.GetByEmail(form.Email)
+		switch {
+		case err == nil:
+			form.AddFieldError("email", "email address is already in use")
+		case !errors.Is(err, models.ErrNoRecord):
+			app.serverError(w, r, err)
+			return
+		}
+	}
+	if !form.Valid() {
+		data := app.newTemplateData(r)
+		data.Form = form
+		app.render(w, r, http.StatusUnprocessableEntity, "email.tmpl", data)
+		return
+	}
+
+	// The change remembers the current address too, so its link stops
+	// working if the address changes some other way first.
+	token, err := app.emailChanges.New(user.ID, user.Email, form.Email, changeEmailTTL)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	app.sendEmail(r, form.Email, "changeemail.tmpl", map[string]any{
+		"Name":    user.Name,
+		"URL":     app.baseURL + "/user/email/confirm?token=" + url.QueryEscape(token),
+		"Expires": "48 hours",
+	})
+	app.sendEmail(r, user.Email, "changenotice.tmpl", map[string]any{
+		"Name":     user.Name,
+		"Email":    form.Email,
+		"IP":       clientIP(r),
+		"ResetURL": app.baseURL + "/user/password/forgot",
+	})
+	app.sessionManager.Put(r.Context(), "flash", "we've sent a confirmation link to "+form.Email+". Your email address will change once you follow it.")
+	http.Redirect(w, r, "/account/view", http.StatusSeeOther)
+}
+
+func (app *application) userEmailConfirm(w http.ResponseWriter, r *http.Request) {
+	change, err := app.emailChanges.Consume(r.URL.Query().Get("token"))
+	if err != nil {
+		if !errors.Is(err, models.ErrNoRecord) {
+			app.serverError(w, r, err)
+			return
+		}
+		app.sessionManager.Put(r.Context(), "flash", "that confirmation link is invalid or has expired.")
+		http.Redirect(w, r, "/account/view", http.StatusSeeOther)
+		return
+	}
+
+	err = app.users.SetEmail(change.UserID, change.OldEmail, change.NewEmail)
+	if err != nil {
+		switch {
+		case errors.Is(err, models.ErrNoRecord):
+			app.sessionManager.Put(r.Context(), "flash", "that confirmation link has already been used.")
+			http.Redirect(w, r, "/account/view", http.StatusSeeOther)
+		case errors.Is(err, models.ErrDuplicateEmail):
+			app.sessionManager.Put(r.Context(), "flash", "that email address is already in use by another account.")
+			http.Redirect(w, r, "/account/view", http.StatusSeeOther)
+		default:
+			app.serverError(w, r, err)
+		}
+		return
+	}
+	app.audit(r, change.UserID, models.AuditEmailChange, change.OldEmail+" to "+change.NewEmail)
+	app.sessionManager.Put(r.Context(), "flash", "your email address has been changed to "+change.NewEmail+".")
+	http.Redirect(w, r, "/account/view", http.StatusSeeOther)
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"bytes"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/MohammadLashkari/snippetbox/internal/assert"
+	"github.com/MohammadLashkari/snippetbox/internal/mailer"
+	"github.com/MohammadLashkari/snippetbox/internal/models"
+)
+
+var confirmEmailLinkRX = regexp.MustCompile(`https://[^\s\\"]+/user/email/confirm\?token=[^\s\\"]+`)
+
+func TestAccountEmailUpdatePost(t *testing.T) {
+	app := newTestApplication(t)
+	var buf bytes.Buffer
+	app.mailer = &mailer.Log{Logger: newLogger(&buf, "text")}
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+	ts.logIn(t)
+
+	_, _, body := ts.get(t, "/account/email/update")
+	validCSRFToken := extractCSRFToken(t, body)
+
+	tests := []struct {
+		name      string
+		email     string
+		password  string
+		wantCode  int
+		wantError string
+	}{
+		{"Blank email", "", "password", http.StatusUnprocessableEntity, "this field cannot be empty"},
+		{"Invalid email", "foo@", "password", http.StatusUnprocessableEntity, "this field must be a valid email address"},
+		{"Same email", "FOO@gmail.com", "password", http.StatusUnprocessableEntity, "this is already your email address"},
+		{"Email in use", "bar@example.com", "password", http.StatusUnprocessableEntity, "email address is already in use"},
+		{"Wrong password", "new@example.com", "wrong", http.StatusUnprocessableEntity, "password is incorrect"},
+		{"Valid", "new@example.com", "password", http.StatusSeeOther, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			form := url.Values{}
+			form.Add("email", tt.email)
+			form.Add("password", tt.password)
+			form.Add("csrf_token", validCSRFToken)
+			code, _, body := ts.postForm(t, "/account/email/update", form)
+			assert.Equal(t, code, tt.wantCode)
+			assert.StringContains(t, body, tt.wantError)
+		})
+	}
+
+	app.wg.Wait()
+	assert.StringContains(t, buf.String(), `to=new@example.com subject="Confirm your new Snippetbox email address"`)
+	assert.StringContains(t, buf.String(), `to=foo@gmail.com subject="Your Snippetbox email address is being changed"`)
+	assert.Equal(t, strings.Count(buf.String(), "msg=email "), 2)
+
+	link := confirmEmailLinkRX.FindString(buf.String())
+	if link == "" {
+		t.Fatalf("no confirmation link in %q", buf.String())
+	}
+	u, err := url.Parse(link)
+	if err != nil {
+		t.Fatal(err)
+	}
+	change, err := app.emailChanges.Consume(u.Query().Get("token"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	change.Expires = time.Time{}
+	assert.Equal(t, *change, models.EmailChange{UserID: 1, OldEmail: "foo@gmail.com", NewEmail: "new@example.com"})
+
+	// Nothing changes until the link is followed.
+	_, _, body = ts.get(t, "/account/view")
+	assert.StringContains(t, body, "foo@gmail.com")
+}
+
+func TestUserEmailConfirm(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	tests := []struct {
+		name      string
+		oldEmail  string
+		newEmail  string
+		ttl       time.Duration
+		wantFlash string
+	}{
+		{"Valid", "foo@gmail.com", "new@example.com", time.Hour, "your email address has been changed to new@example.com."},
+		{"Email changed", "old@example.com", "new@example.com", time.Hour, "that confirmation link has already been used."},
+		{"Email taken", "foo@gmail.com", "bar@example.com", time.Hour, "that email address is already in use by another account."},
+		{"Expired", "foo@gmail.com", "new@example.com", -time.Hour, "that confirmation link is invalid or has expired."},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			token, err := app.emailChanges.New(1, tt.oldEmail, tt.newEmail, tt.ttl)
+			if err != nil {
+				t.Fatal(err)
+			}
+			code, header, _ := ts.get(t, "/user/email/confirm?token="+url.QueryEscape(token))
+			assert.Equal(t, code, http.StatusSeeOther)
+			assert.Equal(t, header.Get("Location"), "/account/view")
+
+			_, _, body := ts.get(t, "/about")
+			assert.StringContains(t, body, tt.wantFlash)
+		})
+	}
+
+	events, err := app.auditLog.Search(models.AuditFilter{UserID: 1, Event: models.AuditEmailChange, Limit: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, len(events), 1)
+	assert.Equal(t, events[0].Detail, "foo@gmail.com to new@example.com")
+}
+
+func TestUserEmailConfirmRevoked(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	first, err := app.emailChanges.New(1, "foo@gmail.com", "new@example.com", time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := app.emailChanges.New(1, "foo@gmail.com", "other@example.com", time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name      string
+		token     string
+		wantFlash string
+	}{
+		{"Replaced", first, "that confirmation link is invalid or has expired."},
+		{"Latest", second, "your email address has been changed to other@example.com."},
+		{"Used twice", second, "that confirmation link is invalid or has expired."},
+		{"Unknown", "wrong", "that confirmation link is invalid or has expired."},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, _, _ := ts.get(t, "/user/email/confirm?token="+url.QueryEscape(tt.token))
+			assert.Equal(t, code, http.StatusSeeOther)
+
+			_, _, body := ts.get(t, "/about")
+			assert.StringContains(t, body, tt.wantFlash)
+		})
+	}
+}
@@ -0,0 +1,303 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+
+	"github.com/MohammadLashkari/snippetbox/internal/gist"
+	"github.com/MohammadLashkari/snippetbox/internal/models"
+	"github.com/MohammadLashkari/snippetbox/internal/validator"
+)
+
+// Snippets created through the API have no expiry choice, so they get the
+// longest one offered by the HTML form.
+const gistExpires = 365
+
+func (app *application) gistList(w http.ResponseWriter, r *http.Request) {
+	var (
+		snippets []*models.Snippet
+		err      error
+	)
+	if id := app.apiUserID(r); id != 0 {
+		snippets, err = app.snippets.ByUser(id)
+	} else {
+		snippets, err = app.snippets.Latest()
+	}
+	if err != nil {
+		app.apiServerError(w, r, err)
+		return
+	}
+
+	owners := map[int]*gist.Owner{}
+	gists := make([]*gist.Gist, 0, len(snippets))
+	for _, s := range snippets {
+		owner, ok := owners[s.UserID]
+		if !ok {
+			owner, err = app.gistOwner(s.UserID)
+			if err != nil {
+				app.apiServerError(w, r, err)
+				return
+			}
+			owners[s.UserID] = owner
+		}
+		gists = append(gists, newGist(app.baseURL, s, owner, false))
+	}
+	app.writeJSON(w, r, http.StatusOK, gists)
+}
+
+func (app *application) gistGet(w http.ResponseWriter, r *http.Request) {
+	snippet, ok := app.gistSnippet(w, r)
+	if !ok {
+		return
+	}
+	owner, err := app.gistOwner(snippet.UserID)
+	if err != nil {
+		app.apiServerError(w, r, err)
+		return
+	}
+	app.writeJSON(w, r, http.StatusOK, newGist(app.baseURL, snippet, owner, true))
+}
+
+func (app *application) gistCreate(w http.ResponseWriter, r *http.Request) {
+	var req gist.Request
+	if err := app.readJSON(w, r, &req); err != nil {
+		app.apiError(w, r, http.StatusBadRequest, "Problems parsing JSON")
+		return
+	}
+
+	var (
+		title, content string
+		v              validator.Validator
+	)
+	if len(req.Files) == 0 {
+		v.AddFieldError("files", "missing_field")
+	}
+	applyGistRequest(&req, &title, &content, &v)
+	if !v.Valid() {
+		app.gistValidationFailed(w, r, v)
+		return
+	}
+
+	userID := app.apiUserID(r)
+	user, err := app.users.Get(userID)
+	if err != nil {
+		app.apiServerError(w, r, err)
+		return
+	}
+	if !user.Verified {
+		app.apiError(w, r, http.StatusForbidden, "Email address must be verified")
+		return
+	}
+	id, err := app.snippets.Insert(userID, title, content, gistExpires)
+	if err != nil {
+		app.apiServerError(w, r, err)
+		return
+	}
+	snippet := insertedSnippet(id, userID, title, content, gistExpires)
+	app.notifySnippet(r, models.EventSnippetCreated, snippet)
+	g := newGist(app.baseURL, snippet, &gist.Owner{ID: userID, Login: gistLogin(user)}, true)
+	w.Header().Set("Location", g.URL)
+	app.writeJSON(w, r, http.StatusCreated, g)
+}
+
+func (app *application) gistUpdate(w http.ResponseWriter, r *http.Request) {
+	snippet, ok := app.gistSnippetOwned(w, r)
+	if !ok {
+		return
+	}
+
+	var req gist.Request
+	if err := app.readJSON(w, r, &req); err != nil {
+		app.apiError(w, r, http.StatusBadRequest, "Problems parsing JSON")
+		return
+	}
+
+	var v validator.Validator
+	applyGistRequest(&req, &snippet.Title, &snippet.Content, &v)
+	if !v.Valid() {
+		app.gistValidationFailed(w, r, v)
+		return
+	}
+
+	if err := app.snippets.Update(snippet.ID, snippet.Title, snippet.Content); err != nil {
+		app.apiServerError(w, r, err)
+		return
+	}
+	snippet, err := app.snippets.Get(snippet.ID)
+	if err != nil {
+		app.apiServerError(w, r, err)
+		return
+	}
+	app.notifySnippet(r, models.EventSnippetUpdated, snippet)
+	owner, err := app.gistOwner(snippet.UserID)
+	if err != nil {
+		app.apiServerError(w, r, err)
+		return
+	}
+	app.writeJSON(w, r, http.StatusOK, newGist(app.baseURL, snippet, owner, true))
+}
+
+func (app *application) gistDelete(w http.ResponseWriter, r *http.Request) {
+	snippet, ok := app.gistSnippetOwned(w, r)
+	if !ok {
+		return
+	}
+	if err := app.snippets.Delete(snippet.ID); err != nil {
+		app.apiServerError(w, r, err)
+		return
+	}
+	app.notifySnippet(r, models.EventSnippetDeleted, snippet)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (app *application) snippetRaw(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil || id < 1 {
+		app.notFound(w)
+		return
+	}
+	snippet, err := app.snippets.Get(id)
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			app.notFound(w)
+		} else {
+			app.serverError(w, r, err)
+		}
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Write([]byte(snippet.Content))
+}
+
+// applyGistRequest copies the description and the single file of req onto
+// title and content, then checks them with the rules of the snippet form.
+// Fields that have no snippet equivalent, such as public, are ignored.
+func applyGistRequest(req *gist.Request, title, content *string, v *validator.Validator) {
+	if len(req.Files) > 1 {
+		v.AddFieldError("files", "only single-file gists are supported")
+	}
+	for name, file := range req.Files {
+		if file == nil {
+			v.AddFieldError("files", "a snippet must keep exactly one file")
+			continue
+		}
+		if file.Content != nil {
+			*content = *file.Content
+		}
+		if *title == "" {
+			*title = name
+		}
+	}
+	if req.Description != nil && *req.Description != "" {
+		*title = *req.Description
+	}
+
+	v.CheckField(validator.NotBlank(*title), "description", "missing_field")
+	v.CheckField(validator.MaxChars(*title, 100), "description", "cannot be more than 100 characters long")
+	v.CheckField(validator.NotBlank(*content), "files", "missing_field")
+}
+
+func (app *application) gistValidationFailed(w http.ResponseWriter, r *http.Request, v validator.Validator) {
+	fields := make([]string, 0, len(v.FieldErrors))
+	for field := range v.FieldErrors {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+
+	body := &gist.Error{
+		Message:          "Validation Failed",
+		DocumentationURL: gist.DocumentationURL,
+	}
+	for _, field := range fields {
+		e := gist.FieldError{Resource: "Gist", Field: field, Code: v.FieldErrors[field]}
+		if e.Code != "missing_field" {
+			e.Code, e.Message = "custom", v.FieldErrors[field]
+		}
+		body.Errors = append(body.Errors, e)
+	}
+	app.writeJSON(w, r, http.StatusUnprocessableEntity, body)
+}
+
+// gistSnippet looks up the snippet named by the {id} wildcard and writes a
+// 404 response when there is none.
+func (app *application) gistSnippet(w http.ResponseWriter, r *http.Request) (*models.Snippet, bool) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil || id < 1 {
+		app.apiError(w, r, http.StatusNotFound, "Not Found")
+		return nil, false
+	}
+	snippet, err := app.snippets.Get(id)
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			app.apiError(w, r, http.StatusNotFound, "Not Found")
+		} else {
+			app.apiServerError(w, r, err)
+		}
+		return nil, false
+	}
+	return snippet, true
+}
+
+// gistSnippetOwned is gistSnippet for calls that modify the snippet. Like
+// GitHub, it answers 404 rather than 403 for snippets of other users.
+func (app *application) gistSnippetOwned(w http.ResponseWriter, r *http.Request) (*models.Snippet, bool) {
+	snippet, ok := app.gistSnippet(w, r)
+	if !ok {
+		return nil, false
+	}
+	if snippet.UserID != app.apiUserID(r) {
+		app.apiError(w, r, http.StatusNotFound, "Not Found")
+		return nil, false
+	}
+	return snippet, true
+}
+
+func (app *application) gistOwner(userID int) (*gist.Owner, error) {
+	if userID == 0 {
+		return nil, nil
+	}
+	user, err := app.users.Get(userID)
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &gist.Owner{ID: userID, Login: gistLogin(user)}, nil
+}
+
+// gistLogin is the name a user goes by in the API: their username, or their
+// name if they have not chosen one.
+func gistLogin(user *models.User) string {
+	if user.Username != "" {
+		return user.Username
+	}
+	return user.Name
+}
+
+func newGist(base string, s *models.Snippet, owner *gist.Owner, withContent bool) *gist.Gist {
+	file := &gist.File{
+		Filename: gist.DefaultFilename,
+		Type:     "text/plain",
+		Language: "Text",
+		RawURL:   fmt.Sprintf("%s/snippet/raw/%d", base, s.ID),
+		Size:     len(s.Content),
+	}
+	if withContent {
+		file.Content = s.Content
+	}
+	return &gist.Gist{
+		ID:          strconv.Itoa(s.ID),
+		URL:         fmt.Sprintf("%s/api/gists/%d", base, s.ID),
+		HTMLURL:     fmt.Sprintf("%s/snippet/view/%d", base, s.ID),
+		Files:       map[string]*gist.File{gist.DefaultFilename: file},
+		Public:      true,
+		CreatedAt:   s.Created.UTC(),
+		UpdatedAt:   s.Updated.UTC(),
+		Description: s.Title,
+		Owner:       owner,
+	}
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/MohammadLashkari/snippetbox/internal/assert"
+	"github.com/MohammadLashkari/snippetbox/internal/gist"
+)
+
+func TestGistGet(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	status, _, body := ts.request(t, http.MethodGet, "/api/gists/1", nil, nil)
+	assert.Equal(t, status, http.StatusOK)
+
+	var g gist.Gist
+	if err := json.Unmarshal([]byte(body), &g); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, g.ID, "1")
+	assert.Equal(t, g.Description, "hello world")
+	assert.Equal(t, g.Files[gist.DefaultFilename].Content, "hello world")
+	assert.Equal(t, g.Owner.Login, "foo")
+
+	status, _, body = ts.request(t, http.MethodGet, "/api/gists/2", nil, nil)
+	assert.Equal(t, status, http.StatusNotFound)
+	assert.StringContains(t, body, `"message": "Not Found"`)
+}
+
+func TestGistCreate(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	tests := []struct {
+		name     string
+		token    string
+		body     string
+		wantCode int
+		wantBody string
+	}{
+		{
+			name:     "Valid",
+			token:    "valid-token",
+			body:     `{"description": "hello world", "public": false, "files": {"main.go": {"content": "hello world"}}}`,
+			wantCode: http.StatusCreated,
+			wantBody: `"description": "hello world"`,
+		},
+		{
+			name:     "Anonymous",
+			body:     `{"files": {"main.go": {"content": "hello world"}}}`,
+			wantCode: http.StatusUnauthorized,
+			wantBody: "Requires authentication",
+		},
+		{
+			name:     "Bad token",
+			token:    "wrong-token",
+			body:     `{"files": {"main.go": {"content": "hello world"}}}`,
+			wantCode: http.StatusUnauthorized,
+			wantBody: "Bad credentials",
+		},
+		{
+			name:     "Malformed JSON",
+			token:    "valid-token",
+			body:     `{"files": `,
+			wantCode: http.StatusBadRequest,
+			wantBody: "Problems parsing JSON",
+		},
+		{
+			name:     "Missing files",
+			token:    "valid-token",
+			body:     `{"description": "hello world"}`,
+			wantCode: http.StatusUnprocessableEntity,
+			wantBody: `"code": "missing_field"`,
+		},
+		{
+			name:     "Several files",
+			token:    "valid-token",
+			body:     `{"files": {"a.go": {"content": "a"}, "b.go": {"content": "b"}}}`,
+			wantCode: http.StatusUnprocessableEntity,
+			wantBody: "only single-file gists are supported",
+		},
+		{
+			name:     "Unverified email",
+			token:    "unverified-token",
+			body:     `{"files": {"main.go": {"content": "hello world"}}}`,
+			wantCode: http.StatusForbidden,
+			wantBody: "Email address must be verified",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			header := http.Header{}
+			if tt.token != "" {
+				header.Set("Authorization", "token "+tt.token)
+			}
+			code, _, body := ts.request(t, http.MethodPost, "/api/gists", header, strings.NewReader(tt.body))
+			assert.Equal(t, code, tt.wantCode)
+			assert.StringContains(t, body, tt.wantBody)
+		})
+	}
+}
+
+func TestGistDelete(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	tests := []struct {
+		name     string
+		token    string
+		urlPath  string
+		wantCode int
+	}{
+		{"Owner", "valid-token", "/api/gists/1", http.StatusNoContent},
+		{"Other user", "other-token", "/api/gists/1", http.StatusNotFound},
+		{"Non-existent ID", "valid-token", "/api/gists/2", http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			header := http.Header{"Authorization": {"Bearer " + tt.token}}
+			code, _, _ := ts.request(t, http.MethodDelete, tt.urlPath, header, nil)
+			assert.Equal(t, code, tt.wantCode)
+		})
+	}
+}
@@ -3,11 +3,14 @@ package main
 import (
 	"errors"
 	"fmt"
+	"mime/multipart"
 	"net/http"
 	"strconv"
+	"strings"
 
 	"github.com/MohammadLashkari/snippetbox/internal/models"
 	"github.com/MohammadLashkari/snippetbox/internal/validator"
+	"github.com/MohammadLashkari/snippetbox/internal/webhooks"
 )
 
 // learning handlers test
@@ -15,6 +18,16 @@ func ping(w http.ResponseWriter, r *http.Request) {
 	w.Write([]byte("OK"))
 }
 
+// ready is the readiness check. Unlike ping it fails as soon as shutdown
+// begins, so load balancers stop routing new requests here.
+func (app *application) ready(w http.ResponseWriter, r *http.Request) {
+	if app.shuttingDown.Load() {
+		http.Error(w, "shutting down", http.StatusServiceUnavailable)
+		return
+	}
+	w.Write([]byte("OK"))
+}
+
 func (app *application) homeHandler(w http.ResponseWriter, r *http.Request) {
 	if r.URL.Path != "/" {
 		app.notFound(w)
@@ -22,12 +35,12 @@ func (app *application) homeHandler(w http.ResponseWriter, r *http.Request) {
 	}
 	snippets, err := app.snippets.Latest()
 	if err != nil {
-		app.serverError(w, err)
+		app.serverError(w, r, err)
 		return
 	}
 	data := app.newTemplateData(r)
 	data.Snippets = snippets
-	app.render(w, http.StatusOK, "home.tmpl", data)
+	app.render(w, r, http.StatusOK, "home.tmpl", data)
 
 }
 
@@ -42,13 +55,32 @@ func (app *application) snippetView(w http.ResponseWriter, r *http.Request) {
 		if errors.Is(err, models.ErrNoRecord) {
 			app.notFound(w)
 		} else {
-			app.serverError(w, err)
+			app.serverError(w, r, err)
 		}
 		return
 	}
+	// Snippets kept from deleted accounts have no author.
+	var author *models.User
+	if snippet.UserID != 0 {
+		author, err = app.users.Get(snippet.UserID)
+		if err != nil && !errors.Is(err, models.ErrNoRecord) {
+			app.serverError(w, r, err)
+			return
+		}
+	}
+	app.renderSnippet(w, r, snippet, author)
+}
+
+// renderSnippet shows snippet, with a link to its author's page unless
+// author is nil.
+func (app *application) renderSnippet(w http.ResponseWriter, r *http.Request, snippet *models.Snippet, author *models.User) {
 	data := app.newTemplateData(r)
 	data.Snippet = snippet
-	app.render(w, http.StatusOK, "view.tmpl", data)
+	data.Author = author
+	data.Meta.Type = "article"
+	data.Meta.Title = snippet.Title
+	data.Meta.Description = excerpt(snippet.Content, 200)
+	app.render(w, r, http.StatusOK, "view.tmpl", data)
 }
 
 type snippetCreateForm struct {
@@ -58,12 +90,21 @@ type snippetCreateForm struct {
 	validator.Validator `form:"-"`
 }
 
+// validateSnippetForm holds the rules every new snippet must satisfy,
+// whether it comes from the create form or from an imported archive.
+func validateSnippetForm(form *snippetCreateForm) {
+	form.CheckField(validator.NotBlank(form.Title), "title", "this field cannot be blank")
+	form.CheckField(validator.MaxChars(form.Title, 100), "title", "this field cannot be more than 100 characters long")
+	form.CheckField(validator.NotBlank(form.Content), "content", "this field cannot be blank")
+	form.CheckField(validator.PermittedValue(form.Expires, 1, 7, 365), "expires", "this field must equal 1, 7 or 365")
+}
+
 func (app *application) snippetCreate(w http.ResponseWriter, r *http.Request) {
 	data := app.newTemplateData(r)
 	data.Form = snippetCreateForm{
 		Expires: 1,
 	}
-	app.render(w, http.StatusOK, "create.tmpl", data)
+	app.render(w, r, http.StatusOK, "create.tmpl", data)
 
 }
 
@@ -74,27 +115,28 @@ func (app *application) snippetCreatePost(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	form.CheckField(validator.NotBlank(form.Title), "title", "this field cannot be blank")
-	form.CheckField(validator.MaxChars(form.Title, 100), "title", "this field cannot be more than 100 characters long")
-	form.CheckField(validator.NotBlank(form.Content), "content", "this field cannot be blank")
-	form.CheckField(validator.PermittedValue(form.Expires, 1, 7, 365), "expires", "this field must equal 1, 7 or 365")
+	validateSnippetForm(&form)
 	if !form.Valid() {
 		data := app.newTemplateData(r)
 		data.Form = form
-		app.render(w, http.StatusUnprocessableEntity, "create.tmpl", data)
+		app.render(w, r, http.StatusUnprocessableEntity, "create.tmpl", data)
 		return
 	}
 
-	id, err := app.snippets.Insert(form.Title, form.Content, form.Expires)
+	userID := app.sessionManager.GetInt(r.Context(), "authenticatedUserID")
+	id, err := app.snippets.Insert(userID, form.Title, form.Content, form.Expires)
 	if err != nil {
-		app.serverError(w, err)
+		app.serverError(w, r, err)
+		return
 	}
+	app.notifySnippet(r, models.EventSnippetCreated, insertedSnippet(id, userID, form.Title, form.Content, form.Expires))
 	app.sessionManager.Put(r.Context(), "flash", "snippet successfully created!")
 	http.Redirect(w, r, fmt.Sprintf("/snippet/view/%d", id), http.StatusSeeOther)
 }
 
 type userSingupForm struct {
 	Name                string `form:"name"`
+	Username            string `form:"username"`
 	Email               string `form:"email"`
 	Password            string `form:"password"`
 	validator.Validator `form:"_"`
@@ -103,7 +145,7 @@ type userSingupForm struct {
 func (app *application) userSignup(w http.ResponseWriter, r *http.Request) {
 	data := app.newTemplateData(r)
 	data.Form = userSingupForm{}
-	app.render(w, http.StatusOK, "signup.tmpl", data)
+	app.render(w, r, http.StatusOK, "signup.tmpl", data)
 }
 
 func (app *application) userSignupPost(w http.ResponseWriter, r *http.Request) {
@@ -114,30 +156,41 @@ func (app *application) userSignupPost(w http.ResponseWriter, r *http.Request) {
 	}
 
 	form.CheckField(validator.NotBlank(form.Name), "name", "this field cannot be empty")
+	// A username is optional at signup and can be chosen later.
+	form.Username = strings.TrimSpace(form.Username)
+	if form.Username != "" {
+		form.CheckUsername("username", form.Username)
+	}
 	form.CheckField(validator.NotBlank(form.Email), "email", "this field cannot be empty")
 	form.CheckField(validator.Matches(form.Email, validator.EmailRX), "email", "this field must be a valid email address")
 	form.CheckField(validator.NotBlank(form.Password), "password", "this field cannot be empty")
-	form.CheckField(validator.MinChars(form.Password, 8), "password", "this filed must be at least 8 characters long")
+	form.CheckPassword("password", form.Password, form.Name, form.Username, form.Email)
 	if !form.Valid() {
 		data := app.newTemplateData(r)
 		data.Form = form
-		app.render(w, http.StatusUnprocessableEntity, "signup.tmpl", data)
+		app.render(w, r, http.StatusUnprocessableEntity, "signup.tmpl", data)
 		return
 	}
 
-	if err := app.users.Insert(form.Name, form.Email, form.Password); err != nil {
-		if errors.Is(err, models.ErrDuplicateEmail) {
+	id, err := app.users.Insert(form.Name, form.Username, form.Email, form.Password)
+	if err != nil {
+		switch {
+		case errors.Is(err, models.ErrDuplicateEmail):
 			form.AddFieldError("email", "email address is already in use")
-			data := app.newTemplateData(r)
-			data.Form = form
-			app.render(w, http.StatusUnprocessableEntity, "signup.tmpl", data)
-		} else {
-			app.serverError(w, err)
-
+		case errors.Is(err, models.ErrDuplicateUsername):
+			form.AddFieldError("username", "this username is already taken")
+		default:
+			app.serverError(w, r, err)
+			return
 		}
+		data := app.newTemplateData(r)
+		data.Form = form
+		app.render(w, r, http.StatusUnprocessableEntity, "signup.tmpl", data)
 		return
 	}
-	app.sessionManager.Put(r.Context(), "flash", "your singup wass successful. please login.")
+	app.audit(r, id, models.AuditSignup, "")
+	app.sendVerificationEmail(r, &models.User{ID: id, Name: form.Name, Email: form.Email})
+	app.sessionManager.Put(r.Context(), "flash", "your singup wass successful. check your email to verify your address, then please login.")
 	http.Redirect(w, r, "/user/login", http.StatusSeeOther)
 }
 
@@ -150,7 +203,7 @@ type userLoginForm struct {
 func (app *application) userLogin(w http.ResponseWriter, r *http.Request) {
 	data := app.newTemplateData(r)
 	data.Form = userLoginForm{}
-	app.render(w, http.StatusOK, "login.tmpl", data)
+	app.render(w, r, http.StatusOK, "login.tmpl", data)
 }
 
 func (app *application) userLoginPost(w http.ResponseWriter, r *http.Request) {
@@ -166,41 +219,60 @@ func (app *application) userLoginPost(w http.ResponseWriter, r *http.Request) {
 	if !form.Valid() {
 		data := app.newTemplateData(r)
 		data.Form = form
-		app.render(w, http.StatusUnprocessableEntity, "login.tmpl", data)
+		app.render(w, r, http.StatusUnprocessableEntity, "login.tmpl", data)
+		return
+	}
+
+	if app.loginThrottled(w, r, form) {
 		return
 	}
 
 	id, err := app.users.Authenticate(form.Email, form.Password)
 	if err != nil {
 		if errors.Is(err, models.ErrInvalidCredentials) {
+			if err := app.loginFailed(r, form.Email); err != nil {
+				app.serverError(w, r, err)
+				return
+			}
+			app.auditLoginFailed(r, form.Email)
 			form.AddNonFieldError("email or password is incorrect")
 			data := app.newTemplateData(r)
 			data.Form = form
-			app.render(w, http.StatusUnprocessableEntity, "login.tmpl", data)
+			app.render(w, r, http.StatusUnprocessableEntity, "login.tmpl", data)
 		} else {
-			app.serverError(w, err)
+			app.serverError(w, r, err)
 		}
 		return
 	}
 	if err := app.sessionManager.RenewToken(r.Context()); err != nil {
-		app.serverError(w, err)
+		app.serverError(w, r, err)
 		return
 	}
-	app.sessionManager.Put(r.Context(), "authenticatedUserID", id)
-	redirectPath := app.sessionManager.PopString(r.Context(), "redirectPathAfterLogin")
-	if redirectPath != "" {
-		http.Redirect(w, r, redirectPath, http.StatusSeeOther)
-		return
 
+	// Failures are only cleared once the second factor, if any, has been
+	// checked too, so that codes cannot be guessed without limit.
+	_, err = app.twoFactor.Get(id)
+	if err == nil {
+		app.startTwoFactor(w, r, id, "password")
+		return
+	}
+	if !errors.Is(err, models.ErrNoRecord) {
+		app.serverError(w, r, err)
+		return
 	}
-	http.Redirect(w, r, "/snippet/create", http.StatusSeeOther)
+	if err := app.loginAttempts.Clear(loginAccountKey(form.Email)); err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	app.logIn(w, r, id, "password")
 }
 
 func (app *application) userLogoutPost(w http.ResponseWriter, r *http.Request) {
 	if err := app.sessionManager.RenewToken(r.Context()); err != nil {
-		app.serverError(w, err)
+		app.serverError(w, r, err)
 		return
 	}
+	app.audit(r, app.sessionManager.GetInt(r.Context(), "authenticatedUserID"), models.AuditLogout, "")
 	app.sessionManager.Remove(r.Context(), "authenticatedUserID")
 	app.sessionManager.Put(r.Context(), "flash", "you've been logged out successfully")
 	http.Redirect(w, r, "/", http.StatusSeeOther)
@@ -214,32 +286,33 @@ func (app *application) accountView(w http.ResponseWriter, r *http.Request) {
 			http.Redirect(w, r, "/user/login", http.StatusSeeOther)
 
 		} else {
-			app.serverError(w, err)
+			app.serverError(w, r, err)
 		}
 		return
 	}
 	data := app.newTemplateData(r)
 	data.User = user
-	app.render(w, http.StatusOK, "account.tmpl", data)
+	app.render(w, r, http.StatusOK, "account.tmpl", data)
 }
 
 func (app *application) about(w http.ResponseWriter, r *http.Request) {
 	data := app.newTemplateData(r)
-	app.render(w, http.StatusOK, "about.tmpl", data)
+	app.render(w, r, http.StatusOK, "about.tmpl", data)
 }
 
 type accountPasswordUpdateForm struct {
 	CurrentPassword         string `form:"currentPassword"`
 	NewPassword             string `form:"newPassword"`
 	NewPasswordConfirmation string `form:"newPasswordConfirmation"`
+	SignOutOthers           bool   `form:"signOutOthers"`
 	validator.Validator     `form:"_"`
 }
 
 func (app *application) accountPasswordUpdate(w http.ResponseWriter, r *http.Request) {
 	data := app.newTemplateData(r)
-	form := accountPasswordUpdateForm{}
+	form := accountPasswordUpdateForm{SignOutOthers: true}
 	data.Form = form
-	app.render(w, http.StatusOK, "password.tmpl", data)
+	app.render(w, r, http.StatusOK, "password.tmpl", data)
 }
 
 func (app *application) accountPasswordUpdatePost(w http.ResponseWriter, r *http.Request) {
@@ -249,29 +322,264 @@ func (app *application) accountPasswordUpdatePost(w http.ResponseWriter, r *http
 		return
 	}
 	form.CheckField(validator.NotBlank(form.CurrentPassword), "currentPassword", "this field cannot be empty")
+	id := app.sessionManager.GetInt(r.Context(), "authenticatedUserID")
+	user, err := app.users.Get(id)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
 	form.CheckField(validator.NotBlank(form.NewPassword), "newPassword", "this field cannot be empty")
-	form.CheckField(validator.MinChars(form.NewPassword, 8), "newPassword", "this field must be at least 8 characters long")
+	form.CheckPassword("newPassword", form.NewPassword, user.Name, user.Username, user.Email)
 	form.CheckField(validator.NotBlank(form.NewPasswordConfirmation), "newPasswordConfirmation", "this field cannot be empty")
 	form.CheckField(form.NewPassword == form.NewPasswordConfirmation, "newPasswordConfirmation", "password donot match")
 	if !form.Valid() {
 		data := app.newTemplateData(r)
 		data.Form = form
-		app.render(w, http.StatusUnprocessableEntity, "password.tmpl", data)
+		app.render(w, r, http.StatusUnprocessableEntity, "password.tmpl", data)
 		return
 	}
-	id := app.sessionManager.GetInt(r.Context(), "authenticatedUserID")
-	err := app.users.PasswordUpdate(id, form.CurrentPassword, form.NewPassword)
+	err = app.users.PasswordUpdate(id, form.CurrentPassword, form.NewPassword)
 	if err != nil {
 		if errors.Is(err, models.ErrInvalidCredentials) {
 			form.AddFieldError("currentPassword", "current password is incorrect")
 			data := app.newTemplateData(r)
 			data.Form = form
-			app.render(w, http.StatusUnprocessableEntity, "password.tmpl", data)
+			app.render(w, r, http.StatusUnprocessableEntity, "password.tmpl", data)
 		} else {
-			app.serverError(w, err)
+			app.serverError(w, r, err)
 		}
 		return
 	}
+	detail := ""
+	if form.SignOutOthers {
+		if _, err := app.revokeOtherSessions(r); err != nil {
+			app.serverError(w, r, err)
+			return
+		}
+		detail = "signed out of other sessions"
+	}
+	app.audit(r, id, models.AuditPasswordChange, detail)
 	app.sessionManager.Put(r.Context(), "flash", "Your password has been updated")
 	http.Redirect(w, r, "/account/view", http.StatusSeeOther)
 }
+
+func (app *application) accountTokenCreatePost(w http.ResponseWriter, r *http.Request) {
+	id := app.sessionManager.GetInt(r.Context(), "authenticatedUserID")
+	token, err := app.tokens.New(id)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	app.audit(r, id, models.AuditTokenCreate, "")
+	app.sessionManager.Put(r.Context(), "flash", fmt.Sprintf("your new API token is %s. copy it now, it won't be shown again", token))
+	http.Redirect(w, r, "/account/view", http.StatusSeeOther)
+}
+
+type webhookCreateForm struct {
+	URL                 string   `form:"url"`
+	Events              []string `form:"events"`
+	validator.Validator `form:"-"`
+}
+
+func (f webhookCreateForm) HasEvent(event string) bool {
+	return validator.PermittedValue(event, f.Events...)
+}
+
+func (app *application) accountWebhooks(w http.ResponseWriter, r *http.Request) {
+	data, err := app.newWebhooksTemplateData(r)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	data.Form = webhookCreateForm{Events: models.WebhookEvents}
+	app.render(w, r, http.StatusOK, "webhooks.tmpl", data)
+}
+
+func (app *application) accountWebhookCreatePost(w http.ResponseWriter, r *http.Request) {
+	var form webhookCreateForm
+	if err := app.decodePostForm(r, &form); err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	form.CheckField(validator.NotBlank(form.URL), "url", "this field cannot be empty")
+	form.CheckField(validator.MaxChars(form.URL, 2048), "url", "this field cannot be more than 2048 characters long")
+	form.CheckField(validator.IsURL(form.URL, "http", "https"), "url", "this field must be an http or https URL")
+	if form.Valid() {
+		form.CheckField(webhooks.CheckURL(form.URL) == nil, "url", "this URL points to a private or local address")
+	}
+	form.CheckField(len(form.Events) > 0, "events", "select at least one event")
+	for _, event := range form.Events {
+		form.CheckField(validator.PermittedValue(event, models.WebhookEvents...), "events", "unknown event")
+	}
+	if !form.Valid() {
+		data, err := app.newWebhooksTemplateData(r)
+		if err != nil {
+			app.serverError(w, r, err)
+			return
+		}
+		data.Form = form
+		app.render(w, r, http.StatusUnprocessableEntity, "webhooks.tmpl", data)
+		return
+	}
+
+	secret, err := webhooks.NewSecret()
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	id := app.sessionManager.GetInt(r.Context(), "authenticatedUserID")
+	if _, err := app.webhooks.Insert(id, form.URL, secret, form.Events); err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	app.sessionManager.Put(r.Context(), "flash", "webhook successfully added!")
+	http.Redirect(w, r, "/account/webhooks", http.StatusSeeOther)
+}
+
+func (app *application) accountWebhookDeletePost(w http.ResponseWriter, r *http.Request) {
+	webhookID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil || webhookID < 1 {
+		app.notFound(w)
+		return
+	}
+	id := app.sessionManager.GetInt(r.Context(), "authenticatedUserID")
+	if err := app.webhooks.Delete(webhookID, id); err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			app.notFound(w)
+		} else {
+			app.serverError(w, r, err)
+		}
+		return
+	}
+	app.sessionManager.Put(r.Context(), "flash", "webhook removed")
+	http.Redirect(w, r, "/account/webhooks", http.StatusSeeOther)
+}
+
+func (app *application) newWebhooksTemplateData(r *http.Request) (*templateData, error) {
+	id := app.sessionManager.GetInt(r.Context(), "authenticatedUserID")
+	hooks, err := app.webhooks.ByUser(id)
+	if err != nil {
+		return nil, err
+	}
+	deliveries, err := app.webhooks.Deliveries(id, 50)
+	if err != nil {
+		return nil, err
+	}
+	data := app.newTemplateData(r)
+	data.Webhooks = hooks
+	data.Deliveries = deliveries
+	return data, nil
+}
+
+func (app *application) accountExport(w http.ResponseWriter, r *http.Request) {
+	id := app.sessionManager.GetInt(r.Context(), "authenticatedUserID")
+	snippets, err := app.snippets.ByUser(id)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", `attachment; filename="snippetbox-export.zip"`)
+	if err := writeSnippetArchive(w, snippets); err != nil {
+		// The response has already started, so all that can be done is to
+		// log the error and leave the client with a truncated archive.
+		app.logError(r.Context(), 1, err.Error())
+	}
+}
+
+type importResult struct {
+	File      string
+	Title     string
+	SnippetID int
+	Errors    []string
+}
+
+type accountImportForm struct {
+	Results             []importResult
+	validator.Validator `form:"-"`
+}
+
+func (app *application) accountImport(w http.ResponseWriter, r *http.Request) {
+	data := app.newTemplateData(r)
+	data.Form = accountImportForm{}
+	app.render(w, r, http.StatusOK, "import.tmpl", data)
+}
+
+func (app *application) accountImportPost(w http.ResponseWriter, r *http.Request) {
+	var form accountImportForm
+	tooLarge := fmt.Sprintf("the archive cannot be larger than %dMB", archiveMaxUploadSize>>20)
+
+	var (
+		file   multipart.File
+		header *multipart.FileHeader
+		err    error
+	)
+	if bodyTooLarge(r) {
+		form.AddFieldError("archive", tooLarge)
+	} else {
+		file, header, err = r.FormFile("archive")
+		var maxBytesError *http.MaxBytesError
+		switch {
+		case errors.As(err, &maxBytesError):
+			form.AddFieldError("archive", tooLarge)
+		case errors.Is(err, http.ErrMissingFile):
+			form.AddFieldError("archive", "choose an archive to import")
+		case err != nil:
+			app.clientError(w, http.StatusBadRequest)
+			return
+		default:
+			defer file.Close()
+			form.CheckField(header.Size <= archiveMaxUploadSize, "archive", tooLarge)
+		}
+	}
+
+	var entries []importEntry
+	if form.Valid() {
+		entries, err = readSnippetArchive(file, header.Size)
+		if err != nil {
+			form.AddFieldError("archive", err.Error())
+		}
+	}
+	if !form.Valid() {
+		data := app.newTemplateData(r)
+		data.Form = form
+		app.render(w, r, http.StatusUnprocessableEntity, "import.tmpl", data)
+		return
+	}
+
+	userID := app.sessionManager.GetInt(r.Context(), "authenticatedUserID")
+	imported := 0
+	for _, entry := range entries {
+		result := importResult{File: entry.File, Title: entry.Form.Title}
+		if entry.Err != "" {
+			result.Errors = append(result.Errors, entry.Err)
+			form.Results = append(form.Results, result)
+			continue
+		}
+		validateSnippetForm(&entry.Form)
+		if !entry.Form.Valid() {
+			for _, key := range []string{"title", "content", "expires"} {
+				if msg, ok := entry.Form.FieldErrors[key]; ok {
+					result.Errors = append(result.Errors, fmt.Sprintf("%s: %s", key, msg))
+				}
+			}
+			form.Results = append(form.Results, result)
+			continue
+		}
+		id, err := app.snippets.Insert(userID, entry.Form.Title, entry.Form.Content, entry.Form.Expires)
+		if err != nil {
+			app.serverError(w, r, err)
+			return
+		}
+		app.notifySnippet(r, models.EventSnippetCreated, insertedSnippet(id, userID, entry.Form.Title, entry.Form.Content, entry.Form.Expires))
+		result.SnippetID = id
+		form.Results = append(form.Results, result)
+		imported++
+	}
+
+	data := app.newTemplateData(r)
+	data.Flash = fmt.Sprintf("imported %d of %d snippets", imported, len(entries))
+	data.Form = form
+	app.render(w, r, http.StatusOK, "import.tmpl", data)
+}
@@ -2,6 +2,7 @@ package main
 
 import (
 	"net/http"
+	"net/url"
 	"testing"
 
 	"github.com/MohammadLashkari/snippetbox/internal/assert"
@@ -17,6 +18,24 @@ func TestPing(t *testing.T) {
 	assert.Equal(t, body, "OK")
 }
 
+func TestReady(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	status, _, body := ts.get(t, "/ready")
+	assert.Equal(t, status, http.StatusOK)
+	assert.Equal(t, body, "OK")
+
+	app.shuttingDown.Store(true)
+	status, _, _ = ts.get(t, "/ready")
+	assert.Equal(t, status, http.StatusServiceUnavailable)
+
+	// Liveness is unaffected.
+	status, _, _ = ts.get(t, "/ping")
+	assert.Equal(t, status, http.StatusOK)
+}
+
 func TestSnippetViewHandler(t *testing.T) {
 	app := newTestApplication(t)
 	ts := newTestServer(t, app.routes())
@@ -71,3 +90,347 @@ func TestSnippetViewHandler(t *testing.T) {
 		})
 	}
 }
+
+func TestOEmbed(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	viewURL := app.baseURL + "/snippet/view/1"
+	tests := []struct {
+		name     string
+		query    url.Values
+		wantCode int
+		wantBody string
+	}{
+		{
+			name:     "Valid",
+			query:    url.Values{"url": {viewURL}, "format": {"json"}},
+			wantCode: http.StatusOK,
+			wantBody: `"type": "rich"`,
+		},
+		{
+			name:     "Permalink",
+			query:    url.Values{"url": {app.baseURL + "/u/foo/1"}},
+			wantCode: http.StatusOK,
+			wantBody: `"type": "rich"`,
+		},
+		{
+			name:     "Permalink under old username",
+			query:    url.Values{"url": {app.baseURL + "/u/oldfoo/1"}},
+			wantCode: http.StatusOK,
+			wantBody: `"type": "rich"`,
+		},
+		{
+			name:     "Permalink under another user",
+			query:    url.Values{"url": {app.baseURL + "/u/jane/1"}},
+			wantCode: http.StatusNotFound,
+		},
+		{
+			name:     "Permalink without username",
+			query:    url.Values{"url": {app.baseURL + "/u//1"}},
+			wantCode: http.StatusNotFound,
+		},
+		{
+			name:     "XML",
+			query:    url.Values{"url": {viewURL}, "format": {"xml"}},
+			wantCode: http.StatusNotImplemented,
+		},
+		{
+			name:     "Other host",
+			query:    url.Values{"url": {"https://example.com/snippet/view/1"}},
+			wantCode: http.StatusNotFound,
+		},
+		{
+			name:     "Request host",
+			query:    url.Values{"url": {ts.URL + "/snippet/view/1"}},
+			wantCode: http.StatusNotFound,
+		},
+		{
+			name:     "Not a snippet",
+			query:    url.Values{"url": {app.baseURL + "/about"}},
+			wantCode: http.StatusNotFound,
+		},
+		{
+			name:     "Non-existent ID",
+			query:    url.Values{"url": {app.baseURL + "/snippet/view/2"}},
+			wantCode: http.StatusNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, _, body := ts.get(t, "/oembed?"+tt.query.Encode())
+			assert.Equal(t, code, tt.wantCode)
+			if tt.wantBody != "" {
+				assert.StringContains(t, body, tt.wantBody)
+			}
+		})
+	}
+}
+
+func TestSnippetViewMeta(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	_, _, body := ts.get(t, "/snippet/view/1")
+	assert.StringContains(t, body, `<meta property='og:title' content='hello world'>`)
+	assert.StringContains(t, body, `<meta property='og:type' content='article'>`)
+	assert.StringContains(t, body, `type='application/json+oembed'`)
+}
+
+func TestUserSignup(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	_, _, body := ts.get(t, "/user/signup")
+	validCSRFToken := extractCSRFToken(t, body)
+
+	const (
+		validName     = "Bob"
+		validPassword = "validPa$$word"
+		validEmail    = "bob@example.com"
+		formTag       = "<form action='/user/signup' method='POST' novalidate>"
+	)
+
+	tests := []struct {
+		name         string
+		userName     string
+		userEmail    string
+		userPassword string
+		csrfToken    string
+		wantCode     int
+		wantFormTag  string
+	}{
+		{
+			name:         "Valid submission",
+			userName:     validName,
+			userEmail:    validEmail,
+			userPassword: validPassword,
+			csrfToken:    validCSRFToken,
+			wantCode:     http.StatusSeeOther,
+		},
+		{
+			name:         "Invalid CSRF Token",
+			userName:     validName,
+			userEmail:    validEmail,
+			userPassword: validPassword,
+			csrfToken:    "wrongToken",
+			wantCode:     http.StatusBadRequest,
+		},
+		{
+			name:         "Empty name",
+			userName:     "",
+			userEmail:    validEmail,
+			userPassword: validPassword,
+			csrfToken:    validCSRFToken,
+			wantCode:     http.StatusUnprocessableEntity,
+			wantFormTag:  formTag,
+		},
+		{
+			name:         "Invalid email",
+			userName:     validName,
+			userEmail:    "bob@example.",
+			userPassword: validPassword,
+			csrfToken:    validCSRFToken,
+			wantCode:     http.StatusUnprocessableEntity,
+			wantFormTag:  formTag,
+		},
+		{
+			name:         "Short password",
+			userName:     validName,
+			userEmail:    validEmail,
+			userPassword: "pa$$",
+			csrfToken:    validCSRFToken,
+			wantCode:     http.StatusUnprocessableEntity,
+			wantFormTag:  formTag,
+		},
+		{
+			name:         "Duplicate email",
+			userName:     validName,
+			userEmail:    "dupe@example.com",
+			userPassword: validPassword,
+			csrfToken:    validCSRFToken,
+			wantCode:     http.StatusUnprocessableEntity,
+			wantFormTag:  formTag,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			form := url.Values{}
+			form.Add("name", tt.userName)
+			form.Add("email", tt.userEmail)
+			form.Add("password", tt.userPassword)
+			form.Add("csrf_token", tt.csrfToken)
+
+			code, _, body := ts.postForm(t, "/user/signup", form)
+			assert.Equal(t, code, tt.wantCode)
+			if tt.wantFormTag != "" {
+				assert.StringContains(t, body, tt.wantFormTag)
+			}
+		})
+	}
+}
+
+func TestUserSignupWeakPassword(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	_, _, body := ts.get(t, "/user/signup")
+	csrfToken := extractCSRFToken(t, body)
+
+	tests := []struct {
+		name      string
+		password  string
+		wantError string
+	}{
+		{"Breached", "Password123!", "this password is too common or has appeared in a data breach"},
+		{"Contains name", "bobby-tables-77", "this field must not contain your name or email address"},
+		{"Keyboard pattern", "sdfghjkl;'", "this password is too easy to guess"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			form := url.Values{}
+			form.Add("name", "Bobby")
+			form.Add("email", "bobby@example.com")
+			form.Add("password", tt.password)
+			form.Add("csrf_token", csrfToken)
+
+			code, _, body := ts.postForm(t, "/user/signup", form)
+			assert.Equal(t, code, http.StatusUnprocessableEntity)
+			assert.StringContains(t, body, tt.wantError)
+		})
+	}
+}
+
+func TestAccountPasswordUpdateWeakPassword(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+	// The moderator's username is jane.
+	ts.logInAs(t, "mod@example.com")
+
+	_, _, body := ts.get(t, "/account/password/update")
+	csrfToken := extractCSRFToken(t, body)
+
+	tests := []struct {
+		name      string
+		password  string
+		wantError string
+	}{
+		{"Breached", "letmein!", "this password is too common or has appeared in a data breach"},
+		{"Contains username", "jane-tables-77", "this field must not contain your name or email address"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			form := url.Values{}
+			form.Add("currentPassword", "password")
+			form.Add("newPassword", tt.password)
+			form.Add("newPasswordConfirmation", tt.password)
+			form.Add("csrf_token", csrfToken)
+
+			code, _, body := ts.postForm(t, "/account/password/update", form)
+			assert.Equal(t, code, http.StatusUnprocessableEntity)
+			assert.StringContains(t, body, tt.wantError)
+		})
+	}
+}
+
+func TestUserSignupUsername(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	_, _, body := ts.get(t, "/user/signup")
+	csrfToken := extractCSRFToken(t, body)
+
+	tests := []struct {
+		name      string
+		username  string
+		wantCode  int
+		wantError string
+	}{
+		{"Valid", "bobby", http.StatusSeeOther, ""},
+		{"Reserved", "static", http.StatusUnprocessableEntity, "this username is reserved"},
+		{"Invalid", "b", http.StatusUnprocessableEntity, "this field must be 3 to 30 letters"},
+		{"Taken", "Foo", http.StatusUnprocessableEntity, "this username is already taken"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			form := url.Values{}
+			form.Add("name", "Bobby")
+			form.Add("username", tt.username)
+			form.Add("email", "bobby@example.com")
+			form.Add("password", "validPa$$word")
+			form.Add("csrf_token", csrfToken)
+
+			code, _, body := ts.postForm(t, "/user/signup", form)
+			assert.Equal(t, code, tt.wantCode)
+			assert.StringContains(t, body, tt.wantError)
+		})
+	}
+}
+
+func TestSnippetCreate(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	t.Run("Unauthenticated", func(t *testing.T) {
+		code, header, _ := ts.get(t, "/snippet/create")
+		assert.Equal(t, code, http.StatusSeeOther)
+		assert.Equal(t, header.Get("Location"), "/user/login")
+	})
+
+	t.Run("Authenticated", func(t *testing.T) {
+		_, _, body := ts.get(t, "/user/login")
+		form := url.Values{}
+		form.Add("email", "foo@example.com")
+		form.Add("password", "password")
+		form.Add("csrf_token", extractCSRFToken(t, body))
+		ts.postForm(t, "/user/login", form)
+
+		code, _, body := ts.get(t, "/snippet/create")
+		assert.Equal(t, code, http.StatusOK)
+		assert.StringContains(t, body, "<form action='/snippet/create' method='POST'>")
+	})
+}
+
+func TestAccountWebhookCreate(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+	ts.logIn(t)
+
+	_, _, body := ts.get(t, "/account/webhooks")
+	csrfToken := extractCSRFToken(t, body)
+
+	tests := []struct {
+		name      string
+		url       string
+		wantCode  int
+		wantError string
+	}{
+		{"Public", "https://hooks.example.com/snippetbox", http.StatusSeeOther, ""},
+		{"Not a URL", "hooks.example.com", http.StatusUnprocessableEntity, "this field must be an http or https URL"},
+		{"Localhost", "http://localhost:8080/hook", http.StatusUnprocessableEntity, "this URL points to a private or local address"},
+		{"Metadata service", "http://169.254.169.254/latest/meta-data/", http.StatusUnprocessableEntity, "this URL points to a private or local address"},
+		{"Private network", "http://10.0.0.5/hook", http.StatusUnprocessableEntity, "this URL points to a private or local address"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			form := url.Values{}
+			form.Add("url", tt.url)
+			form.Add("events", "snippet.created")
+			form.Add("csrf_token", csrfToken)
+
+			code, _, body := ts.postForm(t, "/account/webhooks/create", form)
+			assert.Equal(t, code, tt.wantCode)
+			assert.StringContains(t, body, tt.wantError)
+		})
+	}
+}
@@ -2,20 +2,29 @@ package main
 
 import (
 	"bytes"
+	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"net"
 	"net/http"
 	"runtime/debug"
+	"strings"
 	"time"
 
+	"github.com/MohammadLashkari/snippetbox/internal/gist"
+	"github.com/MohammadLashkari/snippetbox/internal/mailer"
+	"github.com/MohammadLashkari/snippetbox/internal/models"
+	"github.com/MohammadLashkari/snippetbox/ui"
 	"github.com/go-playground/form"
+	"github.com/justinas/nosurf"
 )
 
-func (app *application) serverError(w http.ResponseWriter, err error) {
-	trace := fmt.Sprintf("%s\n%s", err.Error(), debug.Stack())
-	app.errorLog.Output(2, trace)
+func (app *application) serverError(w http.ResponseWriter, r *http.Request, err error) {
+	trace := string(debug.Stack())
+	app.logError(r.Context(), 2, err.Error(), "method", r.Method, "uri", r.URL.RequestURI(), "trace", trace)
 	if app.debug {
-		http.Error(w, trace, http.StatusInternalServerError)
+		http.Error(w, err.Error()+"\n"+trace, http.StatusInternalServerError)
 		return
 	}
 	http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
@@ -29,18 +38,18 @@ func (app *application) notFound(w http.ResponseWriter) {
 	app.clientError(w, http.StatusNotFound)
 }
 
-func (app *application) render(w http.ResponseWriter, status int, page string, data *templateData) {
+func (app *application) render(w http.ResponseWriter, r *http.Request, status int, page string, data *templateData) {
 	tmpl, ok := app.templateCache[page]
 	if !ok {
 		err := fmt.Errorf("the template %s does not exist", page)
-		app.serverError(w, err)
+		app.serverError(w, r, err)
 		return
 	}
 
 	buf := new(bytes.Buffer)
 	err := tmpl.ExecuteTemplate(buf, "base", data)
 	if err != nil {
-		app.serverError(w, err)
+		app.serverError(w, r, err)
 		return
 	}
 
@@ -49,11 +58,25 @@ func (app *application) render(w http.ResponseWriter, status int, page string, d
 }
 
 func (app *application) newTemplateData(r *http.Request) *templateData {
-	return &templateData{
-		CurrentYear:     time.Now().Year(),
+	data := &templateData{
+		CurrentYear: time.Now().Year(),
+		Meta: pageMeta{
+			SiteURL:     app.baseURL,
+			URL:         app.baseURL + r.URL.Path,
+			Type:        "website",
+			Title:       "Snippetbox",
+			Description: "Paste and share snippets of text and code.",
+		},
 		Flash:           app.sessionManager.PopString(r.Context(), "flash"),
 		IsAuthenticated: app.isAuthenticated(r),
+		IsModerator:     app.hasRole(r, models.RoleModerator),
+		IsAdmin:         app.hasRole(r, models.RoleAdmin),
+		CSRFToken:       nosurf.Token(r),
 	}
+	if app.oidc != nil {
+		data.SSOName = app.oidcName
+	}
+	return data
 }
 
 func (app *application) decodePostForm(r *http.Request, dst any) error {
@@ -78,3 +101,165 @@ func (app *application) isAuthenticated(r *http.Request) bool {
 	}
 	return isAuthenticated
 }
+
+// hasRole reports whether the logged in user has role or a more privileged
+// one. It relies on the role recorded by authenticate.
+func (app *application) hasRole(r *http.Request, role string) bool {
+	userRole, ok := r.Context().Value(userRoleContextKey).(string)
+	if !ok {
+		return false
+	}
+	return models.RoleIncludes(userRole, role)
+}
+
+func (app *application) apiUserID(r *http.Request) int {
+	id, ok := r.Context().Value(apiUserIDContextKey).(int)
+	if !ok {
+		return 0
+	}
+	return id
+}
+
+func (app *application) writeJSON(w http.ResponseWriter, r *http.Request, status int, data any) {
+	js, err := json.MarshalIndent(data, "", "  ")
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	w.Write(append(js, '\n'))
+}
+
+func (app *application) readJSON(w http.ResponseWriter, r *http.Request, dst any) error {
+	r.Body = http.MaxBytesReader(w, r.Body, 1_048_576)
+	return json.NewDecoder(r.Body).Decode(dst)
+}
+
+func (app *application) apiError(w http.ResponseWriter, r *http.Request, status int, message string) {
+	app.writeJSON(w, r, status, &gist.Error{
+		Message:          message,
+		DocumentationURL: gist.DocumentationURL,
+	})
+}
+
+func (app *application) apiServerError(w http.ResponseWriter, r *http.Request, err error) {
+	app.logError(r.Context(), 2, err.Error(), "method", r.Method, "uri", r.URL.RequestURI(), "trace", string(debug.Stack()))
+	app.apiError(w, r, http.StatusInternalServerError, http.StatusText(http.StatusInternalServerError))
+}
+
+// clientIP returns the IP address the request came from, without the port.
+func clientIP(r *http.Request) string {
+	ip, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return ip
+}
+
+// insertedSnippet describes a snippet just written by Insert without reading
+// it back from the database.
+func insertedSnippet(id, userID int, title, content string, expires int) *models.Snippet {
+	now := time.Now().UTC().Truncate(time.Second)
+	return &models.Snippet{
+		ID:      id,
+		UserID:  userID,
+		Title:   title,
+		Content: content,
+		Created: now,
+		Updated: now,
+		Expires: now.AddDate(0, 0, expires),
+	}
+}
+
+// notifySnippet queues a webhook event for the owner of s. Failing to queue
+// it is logged rather than failing the request that changed the snippet.
+func (app *application) notifySnippet(r *http.Request, event string, s *models.Snippet) {
+	if s.UserID == 0 {
+		return
+	}
+	if err := app.dispatcher.Notify(s.UserID, event, newGist(app.baseURL, s, nil, true)); err != nil {
+		app.logError(r.Context(), 2, err.Error())
+	}
+}
+
+// excerpt collapses the whitespace of s and shortens it to at most n runes.
+func excerpt(s string, n int) string {
+	runes := []rune(strings.Join(strings.Fields(s), " "))
+	if len(runes) <= n {
+		return string(runes)
+	}
+	return strings.TrimSpace(string(runes[:n-1])) + "…"
+}
+
+// logIn puts the user in the session, whose token must already have been
+// renewed, and sends them where they were going before having to log in.
+// method names how they logged in, for the audit log.
+func (app *application) logIn(w http.ResponseWriter, r *http.Request, id int, method string) {
+	app.startSession(r, id, method)
+	http.Redirect(w, r, app.redirectPathAfterLogin(r), http.StatusSeeOther)
+}
+
+func (app *application) startSession(r *http.Request, id int, method string) {
+	app.sessionManager.Put(r.Context(), "authenticatedUserID", id)
+	app.sessionManager.Put(r.Context(), "loginMethod", method)
+	app.sessionManager.Put(r.Context(), "loginTime", time.Now().Unix())
+	// Record the renewed session on the next request.
+	app.sessionManager.Remove(r.Context(), "sessionSeen")
+	app.audit(r, id, models.AuditLogin, method)
+}
+
+func (app *application) redirectPathAfterLogin(r *http.Request) string {
+	redirectPath := app.sessionManager.PopString(r.Context(), "redirectPathAfterLogin")
+	if redirectPath != "" {
+		return redirectPath
+	}
+	return "/snippet/create"
+}
+
+// background runs fn in a new goroutine that is tracked by app.wg, recovering
+// and logging any panic so it cannot take down the server. ctx only tags
+// the log lines, with the ID of the request that started the task.
+func (app *application) background(ctx context.Context, fn func()) {
+	app.wg.Add(1)
+	go func() {
+		defer app.wg.Done()
+		defer func() {
+			if err := recover(); err != nil {
+				app.logError(ctx, 2, fmt.Sprintf("panic: %v", err), "trace", string(debug.Stack()))
+			}
+		}()
+		fn()
+	}()
+}
+
+// sendEmail renders the email template ui/html/email/name with data and
+// sends it to the given address in the background. Failures are logged, as
+// the request that triggered the email has usually been answered already.
+func (app *application) sendEmail(r *http.Request, to, name string, data any) {
+	// The email goes out after the response, so the request being done must
+	// not cancel it.
+	ctx := context.WithoutCancel(r.Context())
+	app.background(ctx, func() {
+		msg, err := mailer.Render(ui.Files, "html/email/"+name, to, data)
+		if err != nil {
+			app.logError(ctx, 1, err.Error())
+			return
+		}
+		if err := app.mailer.Send(ctx, msg); err != nil {
+			app.logError(ctx, 1, err.Error(), "to", to, "template", name)
+		}
+	})
+}
+
+// destroyUserSessions logs the user out everywhere by destroying every stored
+// session that is logged in as them or half way through logging in.
+func (app *application) destroyUserSessions(ctx context.Context, userID int) error {
+	return app.sessionManager.Iterate(ctx, func(ctx context.Context) error {
+		if app.sessionManager.GetInt(ctx, "authenticatedUserID") != userID &&
+			app.sessionManager.GetInt(ctx, "twoFactorUserID") != userID {
+			return nil
+		}
+		return app.sessionManager.Destroy(ctx)
+	})
+}
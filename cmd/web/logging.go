@@ -0,0 +1,90 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log/slog"
+	"path/filepath"
+	"runtime"
+)
+
+// requestIDHeader carries the request ID in from a proxy or client and back
+// out in the response.
+const requestIDHeader = "X-Request-ID"
+
+// newLogger returns a logger writing to w in the given format, "text" or
+// "json", that adds the request ID of the context to every record.
+func newLogger(w io.Writer, format string) *slog.Logger {
+	var h slog.Handler
+	if format == "json" {
+		h = slog.NewJSONHandler(w, nil)
+	} else {
+		h = slog.NewTextHandler(w, nil)
+	}
+	return slog.New(&contextHandler{h})
+}
+
+// contextHandler adds the request ID found in the context, if any, to each
+// record before passing it on.
+type contextHandler struct {
+	slog.Handler
+}
+
+func (h *contextHandler) Handle(ctx context.Context, r slog.Record) error {
+	if id := requestIDFromContext(ctx); id != "" {
+		r.AddAttrs(slog.String("request_id", id))
+	}
+	return h.Handler.Handle(ctx, r)
+}
+
+func (h *contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &contextHandler{h.Handler.WithAttrs(attrs)}
+}
+
+func (h *contextHandler) WithGroup(name string) slog.Handler {
+	return &contextHandler{h.Handler.WithGroup(name)}
+}
+
+func requestIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDContextKey).(string)
+	return id
+}
+
+// newRequestID returns 16 random bytes, hex-encoded.
+func newRequestID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(b)
+}
+
+// validRequestID reports whether an incoming request ID is safe to pass on
+// and log: 1 to 128 letters, digits, dots, dashes, underscores or colons.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > 128 {
+		return false
+	}
+	for _, c := range id {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9':
+		case c == '.', c == '-', c == '_', c == ':':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// logError logs msg at error level with the request ID of ctx. Like
+// log.Output, calldepth 1 reports the file and line of the caller of
+// logError, 2 the caller's caller and so on.
+func (app *application) logError(ctx context.Context, calldepth int, msg string, args ...any) {
+	if _, file, line, ok := runtime.Caller(calldepth); ok {
+		args = append(args, slog.String("source", fmt.Sprintf("%s:%d", filepath.Base(file), line)))
+	}
+	app.logger.ErrorContext(ctx, msg, args...)
+}
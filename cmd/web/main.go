@@ -1,17 +1,28 @@
 package main
 
 import (
+	"context"
 	"crypto/tls"
 	"database/sql"
+	"encoding/hex"
+	"errors"
 	"flag"
+	"fmt"
 	"html/template"
-	"log"
+	"log/slog"
 	"net"
 	"net/http"
 	"os"
-	"time"
+	"os/signal"
+	"sync"
+	"sync/atomic"
+	"syscall"
 
+	"github.com/MohammadLashkari/snippetbox/internal/mailer"
 	"github.com/MohammadLashkari/snippetbox/internal/models"
+	"github.com/MohammadLashkari/snippetbox/internal/oidc"
+	"github.com/MohammadLashkari/snippetbox/internal/signing"
+	"github.com/MohammadLashkari/snippetbox/internal/webhooks"
 	"github.com/alexedwards/scs/mysqlstore"
 	"github.com/alexedwards/scs/v2"
 	"github.com/go-playground/form"
@@ -21,50 +32,124 @@ import (
 
 type application struct {
 	debug          bool
-	infoLog        *log.Logger
-	errorLog       *log.Logger
+	baseURL        string
+	logger         *slog.Logger
 	snippets       models.SnippetModelInterface
 	users          models.UserModelInterface
+	tokens         models.TokenModelInterface
+	webhooks       models.WebhookModelInterface
+	twoFactor      models.TwoFactorModelInterface
+	passwordResets models.PasswordResetModelInterface
+	emailChanges   models.EmailChangeModelInterface
+	loginAttempts  models.LoginAttemptModelInterface
+	sessions       models.SessionModelInterface
+	identities     models.IdentityModelInterface
+	passkeys       models.PasskeyModelInterface
+	auditLog       models.AuditModelInterface
+	oidc           *oidc.Provider
+	oidcName       string
+	dispatcher     *webhooks.Dispatcher
+	mailer         mailer.Mailer
+	signer         *signing.Signer
+	wg             sync.WaitGroup
+	shuttingDown   atomic.Bool
 	templateCache  map[string]*template.Template
 	formDecoder    *form.Decoder
 	sessionManager *scs.SessionManager
 }
 
 func main() {
+	cfg, err := loadConfig(os.Args[1:], os.LookupEnv, os.Stderr)
+	if err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			os.Exit(0)
+		}
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	if cfg.PrintConfig {
+		if err := cfg.print(os.Stdout); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	logger := newLogger(os.Stdout, cfg.Log.Format)
+	if cfg.File != "" {
+		logger.Info("loaded configuration", "file", cfg.File)
+	}
+
+	// validate has checked the key.
+	key, _ := hex.DecodeString(cfg.Secret)
 
-	host := flag.String("host", "localhost", "HTTP network host")
-	port := flag.String("port", "8080", "HTTP network port")
-	dsn := flag.String("dsn", "web:pass@/snippetbox?parseTime=true", "MySQL data source name")
-	debug := flag.Bool("debug", false, "Enable debug mode")
-	flag.Parse()
+	var m mailer.Mailer
+	switch {
+	case cfg.SMTP.Host != "":
+		m = &mailer.SMTP{
+			Host:     cfg.SMTP.Host,
+			Port:     cfg.SMTP.Port,
+			Username: cfg.SMTP.Username,
+			Password: cfg.SMTP.Password,
+			Sender:   cfg.SMTP.Sender,
+		}
+	case cfg.MailDir != "":
+		m = &mailer.File{Dir: cfg.MailDir, Sender: cfg.SMTP.Sender}
+	default:
+		m = &mailer.Log{Logger: logger}
+	}
 
-	infoLog := log.New(os.Stdout, "INFO\t", log.Ldate|log.Ltime)
-	errorLog := log.New(os.Stderr, "ERROR\t", log.Ldate|log.Ltime|log.Lshortfile)
+	var oidcProvider *oidc.Provider
+	if cfg.OIDC.Issuer != "" {
+		config := oidc.Config{ClientID: cfg.OIDC.ClientID, ClientSecret: cfg.OIDC.ClientSecret, Scopes: []string{"email", "profile"}}
+		oidcProvider, err = oidc.Discover(context.Background(), cfg.OIDC.Issuer, config, nil)
+		if err != nil {
+			fatal(logger, err)
+		}
+	}
 
-	db, err := openDB(*dsn)
+	db, err := openDB(cfg.DSN)
 	if err != nil {
-		errorLog.Fatal(err)
+		fatal(logger, err)
 	}
 	defer db.Close()
 
 	templateCache, err := newTemplateCache()
 	if err != nil {
-		errorLog.Fatal(err)
+		fatal(logger, err)
 	}
 
 	formDecoder := form.NewDecoder()
 
 	sessionManager := scs.New()
 	sessionManager.Store = mysqlstore.New(db)
-	sessionManager.Lifetime = 12 * time.Hour
+	sessionManager.Lifetime = cfg.Session.Lifetime
 	sessionManager.Cookie.Secure = true
 
-	app := application{
-		debug:          *debug,
-		errorLog:       errorLog,
-		infoLog:        infoLog,
+	webhookModel := &models.WebhookModel{DB: db}
+	dispatcher := webhooks.NewDispatcher(webhookModel, logger)
+
+	app := &application{
+		debug:          cfg.Debug,
+		baseURL:        cfg.BaseURL,
+		logger:         logger,
 		snippets:       &models.SnippetModel{DB: db},
-		users:          &models.UserModel{DB: db},
+		users:          &models.UserModel{DB: db, Hasher: cfg.hasher()},
+		tokens:         &models.TokenModel{DB: db},
+		webhooks:       webhookModel,
+		twoFactor:      &models.TwoFactorModel{DB: db},
+		passwordResets: &models.PasswordResetModel{DB: db},
+		emailChanges:   &models.EmailChangeModel{DB: db},
+		loginAttempts:  &models.LoginAttemptModel{DB: db},
+		sessions:       &models.SessionModel{DB: db},
+		identities:     &models.IdentityModel{DB: db},
+		passkeys:       &models.PasskeyModel{DB: db},
+		auditLog:       &models.AuditModel{DB: db},
+		oidc:           oidcProvider,
+		oidcName:       cfg.OIDC.Name,
+		dispatcher:     dispatcher,
+		mailer:         m,
+		signer:         &signing.Signer{Key: key},
 		templateCache:  templateCache,
 		formDecoder:    formDecoder,
 		sessionManager: sessionManager,
@@ -75,17 +160,34 @@ func main() {
 		MinVersion:       tls.VersionTLS13,
 	}
 
-	srv := http.Server{
-		Addr:         net.JoinHostPort(*host, *port),
-		ErrorLog:     errorLog,
+	srv := &http.Server{
+		Addr:         cfg.addr(),
+		ErrorLog:     slog.NewLogLogger(logger.Handler(), slog.LevelError),
 		Handler:      app.routes(),
 		TLSConfig:    tlsConfig,
-		IdleTimeout:  time.Minute,
-		ReadTimeout:  5 * time.Second,
-		WriteTimeout: 10 * time.Second,
+		IdleTimeout:  cfg.Server.IdleTimeout,
+		ReadTimeout:  cfg.Server.ReadTimeout,
+		WriteTimeout: cfg.Server.WriteTimeout,
+	}
+	ln, err := net.Listen("tcp", cfg.addr())
+	if err != nil {
+		fatal(logger, err)
+	}
+
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+
+	logger.Info("starting server", "addr", cfg.addr())
+	if err := app.serve(srv, ln, cfg, quit); err != nil {
+		db.Close()
+		fatal(logger, err)
 	}
-	infoLog.Printf("startig server on %s\n", net.JoinHostPort(*host, *port))
-	errorLog.Fatal(srv.ListenAndServeTLS("./tls/cert.pem", "./tls/key.pem"))
+}
+
+// fatal logs err and exits with status 1.
+func fatal(logger *slog.Logger, err error) {
+	logger.Error(err.Error())
+	os.Exit(1)
 }
 
 func openDB(dsn string) (*sql.DB, error) {
@@ -2,8 +2,16 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"math"
 	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/MohammadLashkari/snippetbox/internal/models"
+	"github.com/MohammadLashkari/snippetbox/internal/ratelimit"
+	"github.com/justinas/nosurf"
 )
 
 // type middleware func(http.Handler) http.Handler
@@ -31,9 +39,59 @@ func secureHeaders(next http.Handler) http.Handler {
 	})
 }
 
+func noSurf(next http.Handler) http.Handler {
+	csrfHandler := nosurf.New(next)
+	csrfHandler.SetBaseCookie(http.Cookie{
+		HttpOnly: true,
+		Path:     "/",
+		Secure:   true,
+	})
+	// Bodies flagged by limitBody are never read, so there is no token to
+	// check, and handlers do nothing with them but turn them away.
+	csrfHandler.ExemptFunc(bodyTooLarge)
+	return csrfHandler
+}
+
+// limitBody caps the request body at n bytes. It must come before noSurf,
+// which reads the whole form looking for the CSRF token. A body that is
+// declared larger up front is flagged, for the handler to report, and left
+// unread.
+func limitBody(n int64) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.ContentLength > n {
+				r = r.WithContext(context.WithValue(r.Context(), bodyTooLargeContextKey, true))
+			}
+			r.Body = http.MaxBytesReader(w, r.Body, n)
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func bodyTooLarge(r *http.Request) bool {
+	tooLarge, _ := r.Context().Value(bodyTooLargeContextKey).(bool)
+	return tooLarge
+}
+
+// requestID tags the request with the X-Request-ID header sent by a proxy
+// or client, or a new random ID when there is no valid one, so that every
+// log line about the request can be found. The ID is echoed in the response.
+func requestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(requestIDHeader)
+		if !validRequestID(id) {
+			id = newRequestID()
+		}
+		w.Header().Set(requestIDHeader, id)
+		ctx := context.WithValue(r.Context(), requestIDContextKey, id)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
 func (app *application) logRequest(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		app.infoLog.Printf("%s - %s %s %s", r.RemoteAddr, r.Proto, r.Method, r.URL.RequestURI())
+		app.logger.InfoContext(r.Context(), "request",
+			"ip", r.RemoteAddr, "proto", r.Proto, "method", r.Method, "uri", r.URL.RequestURI())
 		next.ServeHTTP(w, r)
 	})
 }
@@ -43,7 +101,7 @@ func (app *application) recoverPanic(next http.Handler) http.Handler {
 		defer func() {
 			if err := recover(); err != nil {
 				w.Header().Set("Connection", "close")
-				app.serverError(w, fmt.Errorf("%s", err))
+				app.serverError(w, r, fmt.Errorf("panic: %v", err))
 			}
 		}()
 		next.ServeHTTP(w, r)
@@ -62,6 +120,35 @@ func (app *application) requireAuthentication(next http.Handler) http.Handler {
 	})
 }
 
+// requireVerified sends users who have not verified their email address
+// back to their account page. It must come after requireAuthentication.
+func (app *application) requireVerified(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, err := app.users.Get(app.sessionManager.GetInt(r.Context(), "authenticatedUserID"))
+		if err != nil {
+			if !errors.Is(err, models.ErrNoRecord) {
+				app.serverError(w, r, err)
+				return
+			}
+			// The account was deleted since authenticate looked it up.
+			if err := app.sessionManager.Destroy(r.Context()); err != nil {
+				app.serverError(w, r, err)
+				return
+			}
+			http.Redirect(w, r, "/user/login", http.StatusSeeOther)
+			return
+		}
+		if !user.Verified {
+			app.sessionManager.Put(r.Context(), "flash", "please verify your email address before creating snippets.")
+			http.Redirect(w, r, "/account/view", http.StatusSeeOther)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// authenticate marks requests from logged in users as authenticated and
+// records their role. Suspended users are logged out of every session.
 func (app *application) authenticate(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		id := app.sessionManager.GetInt(r.Context(), "authenticatedUserID")
@@ -69,14 +156,119 @@ func (app *application) authenticate(next http.Handler) http.Handler {
 			next.ServeHTTP(w, r)
 			return
 		}
-		exists, err := app.users.Exists(id)
-		if err != nil {
-			app.serverError(w, err)
+		user, err := app.users.Get(id)
+		if err != nil && !errors.Is(err, models.ErrNoRecord) {
+			app.serverError(w, r, err)
+			return
 		}
-		if exists {
+		if user != nil && user.Suspended {
+			if err := app.sessionManager.Destroy(r.Context()); err != nil {
+				app.serverError(w, r, err)
+				return
+			}
+			if err := app.destroyUserSessions(r.Context(), id); err != nil {
+				app.serverError(w, r, err)
+				return
+			}
+			app.sessionManager.Put(r.Context(), "flash", "your account has been suspended.")
+			user = nil
+		}
+		if user != nil {
 			ctx := context.WithValue(r.Context(), isAuthenticatedContextKey, true)
+			ctx = context.WithValue(ctx, userRoleContextKey, user.Role)
 			r = r.WithContext(ctx)
 		}
 		next.ServeHTTP(w, r)
 	})
 }
+
+// requireRole returns a middleware that only lets through users with role
+// or a more privileged one. It must come after requireAuthentication.
+func (app *application) requireRole(role string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !app.hasRole(r, role) {
+				app.clientError(w, http.StatusForbidden)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// authenticateToken accepts the "token" and "Bearer" authorization schemes
+// used by GitHub API clients. Requests without the header carry on
+// anonymously, but a header with an unknown token is rejected outright.
+func (app *application) authenticateToken(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Vary", "Authorization")
+		header := r.Header.Get("Authorization")
+		if header == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		scheme, token, ok := strings.Cut(header, " ")
+		if !ok || !(strings.EqualFold(scheme, "token") || strings.EqualFold(scheme, "bearer")) {
+			app.apiError(w, r, http.StatusUnauthorized, "Bad credentials")
+			return
+		}
+		id, err := app.tokens.UserID(strings.TrimSpace(token))
+		if err != nil {
+			if errors.Is(err, models.ErrNoRecord) {
+				app.apiError(w, r, http.StatusUnauthorized, "Bad credentials")
+			} else {
+				app.apiServerError(w, r, err)
+			}
+			return
+		}
+		user, err := app.users.Get(id)
+		if err != nil {
+			app.apiServerError(w, r, err)
+			return
+		}
+		if user.Suspended {
+			app.apiError(w, r, http.StatusForbidden, "Account suspended")
+			return
+		}
+		ctx := context.WithValue(r.Context(), apiUserIDContextKey, id)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+func (app *application) requireToken(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if app.apiUserID(r) == 0 {
+			app.apiError(w, r, http.StatusUnauthorized, "Requires authentication")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// rateLimit returns a middleware that limits requests with l. Clients are
+// keyed by user ID once authenticated, by session or API token, and by IP
+// address otherwise, so it must come after authenticate or
+// authenticateToken in a chain.
+func (app *application) rateLimit(l *ratelimit.Limiter) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ok, wait := l.Allow(app.rateLimitKey(r))
+			if !ok {
+				w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+				app.clientError(w, http.StatusTooManyRequests)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func (app *application) rateLimitKey(r *http.Request) string {
+	if id := app.apiUserID(r); id != 0 {
+		return fmt.Sprintf("user:%d", id)
+	}
+	if app.isAuthenticated(r) {
+		return fmt.Sprintf("user:%d", app.sessionManager.GetInt(r.Context(), "authenticatedUserID"))
+	}
+	return "ip:" + clientIP(r)
+}
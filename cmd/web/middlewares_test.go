@@ -2,12 +2,16 @@ package main
 
 import (
 	"bytes"
+	"encoding/json"
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/MohammadLashkari/snippetbox/internal/assert"
+	"github.com/MohammadLashkari/snippetbox/internal/ratelimit"
 )
 
 func TestSecureHeaders(t *testing.T) {
@@ -47,3 +51,134 @@ func TestSecureHeaders(t *testing.T) {
 	bytes.TrimSpace(body)
 	assert.Equal(t, string(body), "OK")
 }
+
+func TestRequestID(t *testing.T) {
+	tests := []struct {
+		name     string
+		header   string
+		wantSame bool
+	}{
+		{"None", "", false},
+		{"Propagated", "lb-7f3a:42", true},
+		{"Invalid characters", "id with spaces", false},
+		{"Too long", strings.Repeat("a", 129), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got string
+			next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				got = requestIDFromContext(r.Context())
+			})
+			rr := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.header != "" {
+				r.Header.Set("X-Request-ID", tt.header)
+			}
+			requestID(next).ServeHTTP(rr, r)
+
+			assert.Equal(t, rr.Header().Get("X-Request-ID"), got)
+			if tt.wantSame {
+				assert.Equal(t, got, tt.header)
+			} else {
+				assert.Equal(t, len(got), 32)
+			}
+		})
+	}
+}
+
+func TestLogRequest(t *testing.T) {
+	app := newTestApplication(t)
+	var buf bytes.Buffer
+	app.logger = newLogger(&buf, "text")
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	r, err := http.NewRequest(http.MethodGet, ts.URL+"/ping", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.Header.Set("X-Request-ID", "req-7")
+	rs, err := ts.Client().Do(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rs.Body.Close()
+
+	assert.Equal(t, rs.Header.Get("X-Request-ID"), "req-7")
+	assert.StringContains(t, buf.String(), "level=INFO msg=request")
+	assert.StringContains(t, buf.String(), "method=GET uri=/ping request_id=req-7")
+}
+
+func TestRecoverPanicLogsRequestID(t *testing.T) {
+	app := newTestApplication(t)
+	var buf bytes.Buffer
+	app.logger = newLogger(&buf, "json")
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic("boom")
+	})
+
+	rr := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/snippet/view/1", nil)
+	r.Header.Set("X-Request-ID", "req-42")
+	requestID(app.recoverPanic(next)).ServeHTTP(rr, r)
+	assert.Equal(t, rr.Code, http.StatusInternalServerError)
+
+	var entry map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, entry["level"], any("ERROR"))
+	assert.Equal(t, entry["msg"], any("panic: boom"))
+	assert.Equal(t, entry["request_id"], any("req-42"))
+	assert.Equal(t, entry["uri"], any("/snippet/view/1"))
+	trace, _ := entry["trace"].(string)
+	assert.StringContains(t, trace, "goroutine")
+}
+
+func TestRateLimit(t *testing.T) {
+	app := newTestApplication(t)
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("OK"))
+	})
+	limit := app.rateLimit(ratelimit.New(ratelimit.Per(1, time.Minute), 2, 10))(next)
+
+	tests := []struct {
+		name           string
+		remoteAddr     string
+		wantCode       int
+		wantRetryAfter string
+	}{
+		{"First", "192.0.2.1:1234", http.StatusOK, ""},
+		{"Second", "192.0.2.1:5678", http.StatusOK, ""},
+		{"Exhausted", "192.0.2.1:1234", http.StatusTooManyRequests, "60"},
+		{"Other client", "192.0.2.2:1234", http.StatusOK, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			r.RemoteAddr = tt.remoteAddr
+			limit.ServeHTTP(rr, r)
+			assert.Equal(t, rr.Code, tt.wantCode)
+			assert.Equal(t, rr.Header().Get("Retry-After"), tt.wantRetryAfter)
+		})
+	}
+}
+
+func TestRequireVerifiedDeletedUser(t *testing.T) {
+	app := newTestApplication(t)
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("the next handler was called")
+	})
+	// A session still logged in as an account that no longer exists.
+	h := app.sessionManager.LoadAndSave(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		app.sessionManager.Put(r.Context(), "authenticatedUserID", 99)
+		app.requireVerified(next).ServeHTTP(w, r)
+	}))
+
+	rr := httptest.NewRecorder()
+	h.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/snippet/create", nil))
+	assert.Equal(t, rr.Code, http.StatusSeeOther)
+	assert.Equal(t, rr.Header().Get("Location"), "/user/login")
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"html/template"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/MohammadLashkari/snippetbox/internal/models"
+)
+
+// The embed is sized from the number of lines shown, assuming a line height
+// of oembedLineHeight pixels plus the title and padding.
+const (
+	oembedWidth      = 600
+	oembedMaxLines   = 20
+	oembedLineHeight = 18
+	oembedChrome     = 60
+)
+
+type oembedResponse struct {
+	Version      string `json:"version"`
+	Type         string `json:"type"`
+	ProviderName string `json:"provider_name"`
+	ProviderURL  string `json:"provider_url"`
+	Title        string `json:"title"`
+	AuthorName   string `json:"author_name,omitempty"`
+	HTML         string `json:"html"`
+	Width        int    `json:"width"`
+	Height       int    `json:"height"`
+	CacheAge     int    `json:"cache_age"`
+}
+
+var oembedTemplate = template.Must(template.New("oembed").Parse(
+	`<blockquote class="snippetbox-embed">` +
+		`<p><a href="{{.URL}}">{{.Title}}</a> on Snippetbox</p>` +
+		`<pre style="max-width:{{.Width}}px;overflow:auto"><code>{{.Content}}</code></pre>` +
+		`</blockquote>`))
+
+// oembed implements the JSON flavour of https://oembed.com for snippet
+// view URLs and permalinks on this host.
+func (app *application) oembed(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	if format := query.Get("format"); format != "" && format != "json" {
+		app.clientError(w, http.StatusNotImplemented)
+		return
+	}
+
+	site, err := url.Parse(app.baseURL)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	id, username, ok := snippetIDFromURL(query.Get("url"), site.Host)
+	if !ok {
+		app.notFound(w)
+		return
+	}
+	snippet, err := app.snippets.Get(id)
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			app.notFound(w)
+		} else {
+			app.serverError(w, r, err)
+		}
+		return
+	}
+	if username != "" {
+		user, err := app.users.GetByUsername(username)
+		if err != nil {
+			if errors.Is(err, models.ErrNoRecord) {
+				app.notFound(w)
+			} else {
+				app.serverError(w, r, err)
+			}
+			return
+		}
+		if snippet.UserID != user.ID {
+			app.notFound(w)
+			return
+		}
+	}
+
+	width := oembedWidth
+	if maxWidth, err := strconv.Atoi(query.Get("maxwidth")); err == nil && maxWidth > 0 && maxWidth < width {
+		width = maxWidth
+	}
+	lines := strings.Split(snippet.Content, "\n")
+	if len(lines) > oembedMaxLines {
+		lines = append(lines[:oembedMaxLines], "…")
+	}
+
+	var html strings.Builder
+	err = oembedTemplate.Execute(&html, map[string]any{
+		"URL":     fmt.Sprintf("%s/snippet/view/%d", app.baseURL, snippet.ID),
+		"Title":   snippet.Title,
+		"Width":   width,
+		"Content": strings.Join(lines, "\n"),
+	})
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	rs := oembedResponse{
+		Version:      "1.0",
+		Type:         "rich",
+		ProviderName: "Snippetbox",
+		ProviderURL:  app.baseURL,
+		Title:        snippet.Title,
+		HTML:         html.String(),
+		Width:        width,
+		Height:       oembedChrome + oembedLineHeight*len(lines),
+		CacheAge:     3600,
+	}
+	owner, err := app.gistOwner(snippet.UserID)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	if owner != nil {
+		rs.AuthorName = owner.Login
+	}
+	app.writeJSON(w, r, http.StatusOK, rs)
+}
+
+// snippetIDFromURL extracts the ID from a /snippet/view/{id} URL or a
+// /u/{username}/{id} permalink pointing at host. For a permalink it also
+// returns the username, which the snippet's author must have.
+func snippetIDFromURL(rawURL, host string) (id int, username string, ok bool) {
+	u, err := url.Parse(rawURL)
+	if err != nil || !strings.EqualFold(u.Host, host) {
+		return 0, "", false
+	}
+	rest, ok := strings.CutPrefix(u.Path, "/snippet/view/")
+	if !ok {
+		rest, ok = strings.CutPrefix(u.Path, "/u/")
+		if !ok {
+			return 0, "", false
+		}
+		username, rest, ok = strings.Cut(rest, "/")
+		if !ok || username == "" {
+			return 0, "", false
+		}
+	}
+	id, err = strconv.Atoi(rest)
+	if err != nil || id < 1 {
+		return 0, "", false
+	}
+	return id, username, true
+}
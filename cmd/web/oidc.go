@@ -0,0 +1,137 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/MohammadLashkari/snippetbox/internal/models"
+	"github.com/MohammadLashkari/snippetbox/internal/oidc"
+)
+
+// oidcRedirectURL is the callback registered with the identity provider.
+func (app *application) oidcRedirectURL() string {
+	return app.baseURL + "/user/login/oidc/callback"
+}
+
+// userLoginOIDC sends the user to the identity provider. The state, nonce
+// and PKCE verifier are kept in the session until they come back.
+func (app *application) userLoginOIDC(w http.ResponseWriter, r *http.Request) {
+	if app.oidc == nil {
+		app.notFound(w)
+		return
+	}
+	var values [3]string
+	for i := range values {
+		v, err := oidc.RandomString()
+		if err != nil {
+			app.serverError(w, r, err)
+			return
+		}
+		values[i] = v
+	}
+	state, nonce, verifier := values[0], values[1], values[2]
+	app.sessionManager.Put(r.Context(), "oidcState", state)
+	app.sessionManager.Put(r.Context(), "oidcNonce", nonce)
+	app.sessionManager.Put(r.Context(), "oidcVerifier", verifier)
+	http.Redirect(w, r, app.oidc.AuthCodeURL(app.oidcRedirectURL(), state, nonce, oidc.S256Challenge(verifier)), http.StatusSeeOther)
+}
+
+func (app *application) userLoginOIDCCallback(w http.ResponseWriter, r *http.Request) {
+	if app.oidc == nil {
+		app.notFound(w)
+		return
+	}
+	state := app.sessionManager.PopString(r.Context(), "oidcState")
+	nonce := app.sessionManager.PopString(r.Context(), "oidcNonce")
+	verifier := app.sessionManager.PopString(r.Context(), "oidcVerifier")
+
+	q := r.URL.Query()
+	if state == "" || q.Get("state") != state {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+	if q.Get("error") != "" {
+		app.oidcFailed(w, r, "single sign-on was cancelled.")
+		return
+	}
+
+	claims, err := app.oidc.Exchange(r.Context(), q.Get("code"), app.oidcRedirectURL(), verifier, nonce)
+	if err != nil {
+		app.logError(r.Context(), 1, err.Error())
+		app.oidcFailed(w, r, "single sign-on failed. please try again.")
+		return
+	}
+
+	id, err := app.identities.UserID(app.oidc.Issuer, claims.Subject)
+	if errors.Is(err, models.ErrNoRecord) {
+		if claims.Email == "" || !claims.EmailVerified {
+			app.oidcFailed(w, r, "your identity provider did not share a verified email address.")
+			return
+		}
+		id, err = app.linkOIDCUser(claims)
+	}
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	if err := app.sessionManager.RenewToken(r.Context()); err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	// The identity provider stands in for the password only, so accounts
+	// with two-factor authentication still need their second factor.
+	_, err = app.twoFactor.Get(id)
+	if err == nil {
+		app.startTwoFactor(w, r, id, "oidc")
+		return
+	}
+	if !errors.Is(err, models.ErrNoRecord) {
+		app.serverError(w, r, err)
+		return
+	}
+	app.logIn(w, r, id, "oidc")
+}
+
+// linkOIDCUser links the identity in claims to the account with the same
+// email address, creating one if there is none. The provider has verified
+// the address, so the account is marked as verified too.
+func (app *application) linkOIDCUser(claims *oidc.Claims) (int, error) {
+	var id int
+	user, err := app.users.GetByEmail(claims.Email)
+	switch {
+	case err == nil:
+		id = user.ID
+	case errors.Is(err, models.ErrNoRecord):
+		name := claims.Name
+		if name == "" {
+			name, _, _ = strings.Cut(claims.Email, "@")
+		}
+		// The account gets a random password nobody knows. The user can
+		// still set one through the password reset flow.
+		password, err := oidc.RandomString()
+		if err != nil {
+			return 0, err
+		}
+		id, err = app.users.Insert(name, "", claims.Email, password)
+		if err != nil {
+			return 0, err
+		}
+	default:
+		return 0, err
+	}
+
+	if err := app.users.SetVerified(id, claims.Email); err != nil && !errors.Is(err, models.ErrNoRecord) {
+		return 0, err
+	}
+	if err := app.identities.Insert(id, app.oidc.Issuer, claims.Subject); err != nil {
+		return 0, err
+	}
+	return id, nil
+}
+
+func (app *application) oidcFailed(w http.ResponseWriter, r *http.Request, message string) {
+	app.sessionManager.Put(r.Context(), "flash", message)
+	http.Redirect(w, r, "/user/login", http.StatusSeeOther)
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/MohammadLashkari/snippetbox/internal/assert"
+	"github.com/MohammadLashkari/snippetbox/internal/models"
+	"github.com/MohammadLashkari/snippetbox/internal/oidc"
+	"github.com/MohammadLashkari/snippetbox/internal/oidc/oidctest"
+)
+
+func TestUserLoginOIDC(t *testing.T) {
+	idp := oidctest.NewServer(oidctest.User{})
+	defer idp.Close()
+
+	app := newTestApplication(t)
+	config := oidc.Config{ClientID: oidctest.ClientID, ClientSecret: oidctest.ClientSecret}
+	provider, err := oidc.Discover(context.Background(), idp.URL, config, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	app.oidc = provider
+	app.oidcName = "Example SSO"
+
+	tests := []struct {
+		name         string
+		user         oidctest.User
+		wantLocation string
+		wantUserID   int
+	}{
+		{
+			name:         "New account",
+			user:         oidctest.User{Subject: "1001", Email: "carol@example.com", EmailVerified: true, Name: "Carol"},
+			wantLocation: "/snippet/create",
+			wantUserID:   4,
+		},
+		{
+			name:         "Existing account",
+			user:         oidctest.User{Subject: "1002", Email: "foo@gmail.com", EmailVerified: true},
+			wantLocation: "/snippet/create",
+			wantUserID:   1,
+		},
+		{
+			name:         "Account with two-factor authentication",
+			user:         oidctest.User{Subject: "1004", Email: "bar@example.com", EmailVerified: true},
+			wantLocation: "/user/login/2fa",
+			wantUserID:   2,
+		},
+		{
+			name:         "Unverified email",
+			user:         oidctest.User{Subject: "1003", Email: "dave@example.com"},
+			wantLocation: "/user/login",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ts := newTestServer(t, app.routes())
+			defer ts.Close()
+			idp.SetUser(tt.user)
+
+			_, _, body := ts.get(t, "/user/login")
+			assert.StringContains(t, body, "<a href='/user/login/oidc'>Log in with Example SSO</a>")
+
+			code, header, _ := ts.get(t, "/user/login/oidc")
+			assert.Equal(t, code, http.StatusSeeOther)
+			authURL := header.Get("Location")
+			if !strings.HasPrefix(authURL, idp.URL+"/authorize?") {
+				t.Fatalf("redirected to %q", authURL)
+			}
+
+			// The provider logs the user in straight away and sends them back.
+			rs, err := ts.Client().Get(authURL)
+			if err != nil {
+				t.Fatal(err)
+			}
+			rs.Body.Close()
+			callback, err := url.Parse(rs.Header.Get("Location"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			assert.Equal(t, callback.Path, "/user/login/oidc/callback")
+
+			code, header, _ = ts.get(t, callback.RequestURI())
+			assert.Equal(t, code, http.StatusSeeOther)
+			assert.Equal(t, header.Get("Location"), tt.wantLocation)
+
+			id, _ := app.identities.UserID(idp.URL, tt.user.Subject)
+			assert.Equal(t, id, tt.wantUserID)
+
+			if tt.wantLocation == "/user/login/2fa" {
+				// Single sign-on stands in for the password only.
+				code, header, _ = ts.get(t, "/account/view")
+				assert.Equal(t, header.Get("Location"), "/user/login")
+
+				_, _, body = ts.get(t, "/user/login/2fa")
+				form := url.Values{"code": {"aaaaa-bbbbb"}, "csrf_token": {extractCSRFToken(t, body)}}
+				code, header, _ = ts.postForm(t, "/user/login/2fa", form)
+				assert.Equal(t, code, http.StatusSeeOther)
+				assert.Equal(t, header.Get("Location"), "/account/view")
+
+				events, err := app.auditLog.Search(models.AuditFilter{UserID: id, Event: models.AuditLogin})
+				if err != nil {
+					t.Fatal(err)
+				}
+				assert.Equal(t, len(events), 1)
+				assert.Equal(t, events[0].Detail, "oidc and two-factor code")
+			}
+		})
+	}
+
+	t.Run("State mismatch", func(t *testing.T) {
+		ts := newTestServer(t, app.routes())
+		defer ts.Close()
+		ts.get(t, "/user/login/oidc")
+		code, _, _ := ts.get(t, "/user/login/oidc/callback?code=x&state=forged")
+		assert.Equal(t, code, http.StatusBadRequest)
+	})
+}
+
+func TestUserLoginOIDCDisabled(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	_, _, body := ts.get(t, "/user/login")
+	assert.Equal(t, strings.Contains(body, "/user/login/oidc"), false)
+	code, _, _ := ts.get(t, "/user/login/oidc")
+	assert.Equal(t, code, http.StatusNotFound)
+}
@@ -0,0 +1,187 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/MohammadLashkari/snippetbox/internal/models"
+	"github.com/MohammadLashkari/snippetbox/internal/validator"
+	"github.com/MohammadLashkari/snippetbox/internal/webauthn"
+)
+
+// webauthnConfig scopes passkeys to the host name of the site's base URL.
+func (app *application) webauthnConfig() webauthn.Config {
+	u, _ := url.Parse(app.baseURL)
+	return webauthn.Config{RPID: u.Hostname(), RPName: "Snippetbox", Origin: app.baseURL}
+}
+
+// newWebAuthnChallenge creates a challenge and keeps it in the session for
+// the response to be checked against.
+func (app *application) newWebAuthnChallenge(r *http.Request) (string, error) {
+	challenge, err := webauthn.NewChallenge()
+	if err != nil {
+		return "", err
+	}
+	app.sessionManager.Put(r.Context(), "webauthnChallenge", challenge)
+	return challenge, nil
+}
+
+// passkeyError answers one of the JSON endpoints used by the passkey script.
+func (app *application) passkeyError(w http.ResponseWriter, r *http.Request, status int, message string) {
+	app.writeJSON(w, r, status, map[string]string{"message": message})
+}
+
+func (app *application) accountPasskeys(w http.ResponseWriter, r *http.Request) {
+	id := app.sessionManager.GetInt(r.Context(), "authenticatedUserID")
+	passkeys, err := app.passkeys.ByUser(id)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	data := app.newTemplateData(r)
+	data.Passkeys = passkeys
+	app.render(w, r, http.StatusOK, "passkeys.tmpl", data)
+}
+
+func (app *application) accountPasskeyRegisterBegin(w http.ResponseWriter, r *http.Request) {
+	id := app.sessionManager.GetInt(r.Context(), "authenticatedUserID")
+	user, err := app.users.Get(id)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	passkeys, err := app.passkeys.ByUser(id)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	exclude := make([][]byte, len(passkeys))
+	for i, p := range passkeys {
+		exclude[i] = p.CredentialID
+	}
+	challenge, err := app.newWebAuthnChallenge(r)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	userHandle := []byte(strconv.Itoa(id))
+	app.writeJSON(w, r, http.StatusOK, app.webauthnConfig().CreationOptions(challenge, userHandle, user.Email, user.Name, exclude))
+}
+
+type passkeyRegisterRequest struct {
+	Name       string                         `json:"name"`
+	Credential *webauthn.RegistrationResponse `json:"credential"`
+}
+
+func (app *application) accountPasskeyRegisterFinish(w http.ResponseWriter, r *http.Request) {
+	var req passkeyRegisterRequest
+	if err := app.readJSON(w, r, &req); err != nil || req.Credential == nil {
+		app.passkeyError(w, r, http.StatusBadRequest, "malformed request")
+		return
+	}
+	req.Name = strings.TrimSpace(req.Name)
+	if !validator.NotBlank(req.Name) || !validator.MaxChars(req.Name, 100) {
+		app.passkeyError(w, r, http.StatusUnprocessableEntity, "give the passkey a name of at most 100 characters")
+		return
+	}
+
+	challenge := app.sessionManager.PopString(r.Context(), "webauthnChallenge")
+	cred, err := app.webauthnConfig().VerifyRegistration(req.Credential, challenge)
+	if err != nil {
+		app.passkeyError(w, r, http.StatusBadRequest, "the passkey could not be verified")
+		return
+	}
+	id := app.sessionManager.GetInt(r.Context(), "authenticatedUserID")
+	if _, err := app.passkeys.Insert(id, req.Name, cred.ID, cred.PublicKey, cred.SignCount); err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	app.audit(r, id, models.AuditPasskeyAdd, req.Name)
+	app.sessionManager.Put(r.Context(), "flash", fmt.Sprintf("passkey %q added", req.Name))
+	app.writeJSON(w, r, http.StatusOK, map[string]string{"redirect": "/account/passkeys"})
+}
+
+func (app *application) accountPasskeyDeletePost(w http.ResponseWriter, r *http.Request) {
+	passkeyID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil || passkeyID < 1 {
+		app.notFound(w)
+		return
+	}
+	id := app.sessionManager.GetInt(r.Context(), "authenticatedUserID")
+	if err := app.passkeys.Delete(passkeyID, id); err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			app.notFound(w)
+		} else {
+			app.serverError(w, r, err)
+		}
+		return
+	}
+	app.audit(r, id, models.AuditPasskeyRemove, "")
+	app.sessionManager.Put(r.Context(), "flash", "passkey removed")
+	http.Redirect(w, r, "/account/passkeys", http.StatusSeeOther)
+}
+
+func (app *application) userLoginPasskeyBegin(w http.ResponseWriter, r *http.Request) {
+	challenge, err := app.newWebAuthnChallenge(r)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	app.writeJSON(w, r, http.StatusOK, app.webauthnConfig().RequestOptions(challenge))
+}
+
+// userLoginPasskeyFinish logs the user in with a passkey. A passkey proves
+// both possession and, usually, a local PIN or biometric check, so it
+// stands in for the password and the second factor.
+func (app *application) userLoginPasskeyFinish(w http.ResponseWriter, r *http.Request) {
+	var resp webauthn.AssertionResponse
+	if err := app.readJSON(w, r, &resp); err != nil {
+		app.passkeyError(w, r, http.StatusBadRequest, "malformed request")
+		return
+	}
+	challenge := app.sessionManager.PopString(r.Context(), "webauthnChallenge")
+
+	credentialID, err := resp.CredentialID()
+	if err != nil {
+		app.passkeyError(w, r, http.StatusBadRequest, "malformed request")
+		return
+	}
+	passkey, err := app.passkeys.GetByCredentialID(credentialID)
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			app.passkeyError(w, r, http.StatusUnauthorized, "this passkey is not registered")
+		} else {
+			app.serverError(w, r, err)
+		}
+		return
+	}
+	if handle, err := resp.UserHandle(); err != nil || (len(handle) > 0 && string(handle) != strconv.Itoa(passkey.UserID)) {
+		app.passkeyError(w, r, http.StatusUnauthorized, "this passkey belongs to another account")
+		return
+	}
+
+	signCount, err := app.webauthnConfig().VerifyAssertion(&resp, challenge, passkey.PublicKey, passkey.SignCount)
+	if err != nil {
+		if errors.Is(err, webauthn.ErrCloned) {
+			app.logger.WarnContext(r.Context(), "passkey may have been cloned", "passkey_id", passkey.ID, "user_id", passkey.UserID)
+		}
+		app.audit(r, passkey.UserID, models.AuditLoginFailed, "passkey "+passkey.Name)
+		app.passkeyError(w, r, http.StatusUnauthorized, "the passkey could not be verified")
+		return
+	}
+	if err := app.passkeys.Use(passkey.ID, signCount); err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	if err := app.sessionManager.RenewToken(r.Context()); err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	app.startSession(r, passkey.UserID, "passkey")
+	app.writeJSON(w, r, http.StatusOK, map[string]string{"redirect": app.redirectPathAfterLogin(r)})
+}
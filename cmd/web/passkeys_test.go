@@ -0,0 +1,122 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/MohammadLashkari/snippetbox/internal/assert"
+	"github.com/MohammadLashkari/snippetbox/internal/webauthn"
+	"github.com/MohammadLashkari/snippetbox/internal/webauthn/webauthntest"
+)
+
+// postJSON posts v the way the passkey script does, with the CSRF token in
+// a header, and decodes the JSON response into dst.
+func (ts *testServer) postJSON(t *testing.T, urlPath, csrfToken string, v, dst any) int {
+	body, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	header := http.Header{
+		"Content-Type": {"application/json"},
+		"Origin":       {ts.URL},
+		"X-Csrf-Token": {csrfToken},
+	}
+	code, _, rs := ts.request(t, http.MethodPost, urlPath, header, strings.NewReader(string(body)))
+	if dst != nil {
+		if err := json.Unmarshal([]byte(rs), dst); err != nil {
+			t.Fatalf("decoding %q: %v", rs, err)
+		}
+	}
+	return code
+}
+
+func TestPasskeys(t *testing.T) {
+	app := newTestApplication(t)
+	laptop := newTestServer(t, app.routes())
+	defer laptop.Close()
+	laptop.logIn(t)
+
+	// Passkeys are scoped to the configured base URL, not to the address
+	// of the test server.
+	authenticator := webauthntest.New("snippetbox.test", app.baseURL)
+
+	_, _, body := laptop.get(t, "/account/passkeys")
+	assert.StringContains(t, body, "You haven't added any passkeys yet.")
+	csrfToken := extractCSRFToken(t, body)
+
+	var creation webauthn.CreationOptions
+	code := laptop.postJSON(t, "/account/passkeys/register/begin", csrfToken, nil, &creation)
+	assert.Equal(t, code, http.StatusOK)
+	assert.Equal(t, creation.RP.ID, "snippetbox.test")
+	assert.Equal(t, creation.Attestation, "none")
+
+	var result map[string]string
+	code = laptop.postJSON(t, "/account/passkeys/register/finish", csrfToken, map[string]any{
+		"name":       "Work laptop",
+		"credential": authenticator.Create(&creation),
+	}, &result)
+	assert.Equal(t, code, http.StatusOK)
+	assert.Equal(t, result["redirect"], "/account/passkeys")
+
+	_, _, body = laptop.get(t, "/account/passkeys")
+	assert.StringContains(t, body, "passkey &#34;Work laptop&#34; added")
+	assert.StringContains(t, body, "<td>Work laptop</td>")
+
+	t.Run("Register replayed", func(t *testing.T) {
+		code := laptop.postJSON(t, "/account/passkeys/register/finish", csrfToken, map[string]any{
+			"name":       "Again",
+			"credential": authenticator.Create(&creation),
+		}, nil)
+		assert.Equal(t, code, http.StatusBadRequest)
+	})
+
+	loginWith := func(ts *testServer, a *webauthntest.Authenticator) (int, map[string]string) {
+		_, _, body := ts.get(t, "/user/login")
+		assert.StringContains(t, body, "id='passkey-login'")
+		csrfToken := extractCSRFToken(t, body)
+		var request webauthn.RequestOptions
+		ts.postJSON(t, "/user/login/passkey/begin", csrfToken, nil, &request)
+		var result map[string]string
+		code := ts.postJSON(t, "/user/login/passkey/finish", csrfToken, a.Get(&request), &result)
+		return code, result
+	}
+
+	t.Run("Login", func(t *testing.T) {
+		ts := newTestServer(t, app.routes())
+		defer ts.Close()
+		code, result := loginWith(ts, authenticator)
+		assert.Equal(t, code, http.StatusOK)
+		assert.Equal(t, result["redirect"], "/snippet/create")
+
+		code, _, _ = ts.get(t, "/account/view")
+		assert.Equal(t, code, http.StatusOK)
+	})
+
+	t.Run("Cloned authenticator", func(t *testing.T) {
+		ts := newTestServer(t, app.routes())
+		defer ts.Close()
+		clone := *authenticator
+		clone.SignCount = 0
+		code, result := loginWith(ts, &clone)
+		assert.Equal(t, code, http.StatusUnauthorized)
+		assert.Equal(t, result["message"], "the passkey could not be verified")
+	})
+
+	t.Run("Unknown passkey", func(t *testing.T) {
+		ts := newTestServer(t, app.routes())
+		defer ts.Close()
+		code, _ := loginWith(ts, webauthntest.New("snippetbox.test", app.baseURL))
+		assert.Equal(t, code, http.StatusUnauthorized)
+	})
+
+	t.Run("Remove", func(t *testing.T) {
+		form := url.Values{"csrf_token": {csrfToken}}
+		code, _, _ := laptop.postForm(t, "/account/passkeys/1/delete", form)
+		assert.Equal(t, code, http.StatusSeeOther)
+		code, _, _ = laptop.postForm(t, "/account/passkeys/1/delete", form)
+		assert.Equal(t, code, http.StatusNotFound)
+	})
+}
@@ -0,0 +1,364 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/MohammadLashkari/snippetbox/internal/models"
+	"github.com/MohammadLashkari/snippetbox/internal/validator"
+)
+
+// accountData is everything stored about a user, as downloaded from
+// /account/data. Password hashes, secrets and tokens are left out, and so
+// are audit events about other accounts and the IP address and user agent
+// of staff actions on this one.
+type accountData struct {
+	ExportedAt         time.Time               `json:"exported_at"`
+	Profile            accountDataProfile      `json:"profile"`
+	PreviousUsernames  []string                `json:"previous_usernames"`
+	PendingEmailChange *accountDataEmailChange `json:"pending_email_change"`
+	TwoFactorEnabled   bool                    `json:"two_factor_enabled"`
+	Identities         []accountDataIdentity   `json:"identities"`
+	Snippets           []accountDataSnippet    `json:"snippets"`
+	Sessions           []accountDataSession    `json:"sessions"`
+	Passkeys           []accountDataPasskey    `json:"passkeys"`
+	APITokens          []accountDataToken      `json:"api_tokens"`
+	Webhooks           []accountDataWebhook    `json:"webhooks"`
+	WebhookDeliveries  []accountDataDelivery   `json:"webhook_deliveries"`
+	AuditEvents        []accountDataEvent      `json:"audit_events"`
+}
+
+type accountDataProfile struct {
+	ID       int       `json:"id"`
+	Username string    `json:"username"`
+	Name     string    `json:"name"`
+	Email    string    `json:"email"`
+	Bio      string    `json:"bio"`
+	Website  string    `json:"website"`
+	Verified bool      `json:"verified"`
+	Role     string    `json:"role"`
+	Created  time.Time `json:"created"`
+}
+
+type accountDataEmailChange struct {
+	NewEmail string    `json:"new_email"`
+	Expires  time.Time `json:"expires"`
+}
+
+type accountDataIdentity struct {
+	Issuer  string    `json:"issuer"`
+	Subject string    `json:"subject"`
+	Created time.Time `json:"created"`
+}
+
+type accountDataSnippet struct {
+	ID      int       `json:"id"`
+	Title   string    `json:"title"`
+	Content string    `json:"content"`
+	Created time.Time `json:"created"`
+	Updated time.Time `json:"updated"`
+	Expires time.Time `json:"expires"`
+}
+
+type accountDataSession struct {
+	UserAgent string    `json:"user_agent"`
+	IP        string    `json:"ip"`
+	Created   time.Time `json:"created"`
+	LastSeen  time.Time `json:"last_seen"`
+}
+
+type accountDataPasskey struct {
+	Name     string     `json:"name"`
+	Created  time.Time  `json:"created"`
+	LastUsed *time.Time `json:"last_used"`
+}
+
+type accountDataToken struct {
+	Created time.Time `json:"created"`
+}
+
+type accountDataWebhook struct {
+	URL     string    `json:"url"`
+	Events  []string  `json:"events"`
+	Created time.Time `json:"created"`
+}
+
+type accountDataDelivery struct {
+	URL            string    `json:"url"`
+	Event          string    `json:"event"`
+	Status         string    `json:"status"`
+	Attempts       int       `json:"attempts"`
+	ResponseStatus int       `json:"response_status"`
+	LastError      string    `json:"last_error"`
+	Created        time.Time `json:"created"`
+}
+
+type accountDataEvent struct {
+	Event     string    `json:"event"`
+	Detail    string    `json:"detail"`
+	ByStaff   bool      `json:"by_staff"`
+	IP        string    `json:"ip"`
+	UserAgent string    `json:"user_agent"`
+	Created   time.Time `json:"created"`
+}
+
+func (app *application) accountData(w http.ResponseWriter, r *http.Request) {
+	id := app.sessionManager.GetInt(r.Context(), "authenticatedUserID")
+	data, err := app.collectAccountData(id)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Content-Disposition", `attachment; filename="snippetbox-data.json"`)
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(data); err != nil {
+		app.logError(r.Context(), 1, err.Error())
+	}
+}
+
+func (app *application) collectAccountData(id int) (*accountData, error) {
+	user, err := app.users.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	data := &accountData{
+		ExportedAt: time.Now().UTC().Truncate(time.Second),
+		Profile: accountDataProfile{
+			ID:       user.ID,
+			Username: user.Username,
+			Name:     user.Name,
+			Email:    user.Email,
+			Bio:      user.Bio,
+			Website:  user.Website,
+			Verified: user.Verified,
+			Role:     user.Role,
+			Created:  user.Created.UTC(),
+		},
+		Identities:        []accountDataIdentity{},
+		Snippets:          []accountDataSnippet{},
+		Sessions:          []accountDataSession{},
+		Passkeys:          []accountDataPasskey{},
+		APITokens:         []accountDataToken{},
+		Webhooks:          []accountDataWebhook{},
+		WebhookDeliveries: []accountDataDelivery{},
+		AuditEvents:       []accountDataEvent{},
+	}
+
+	data.PreviousUsernames, err = app.users.PreviousUsernames(id)
+	if err != nil {
+		return nil, err
+	}
+
+	change, err := app.emailChanges.Pending(id)
+	if err != nil && !errors.Is(err, models.ErrNoRecord) {
+		return nil, err
+	}
+	if err == nil {
+		data.PendingEmailChange = &accountDataEmailChange{NewEmail: change.NewEmail, Expires: change.Expires.UTC()}
+	}
+
+	_, err = app.twoFactor.Get(id)
+	if err != nil && !errors.Is(err, models.ErrNoRecord) {
+		return nil, err
+	}
+	data.TwoFactorEnabled = err == nil
+
+	identities, err := app.identities.ByUser(id)
+	if err != nil {
+		return nil, err
+	}
+	for _, i := range identities {
+		data.Identities = append(data.Identities, accountDataIdentity{
+			Issuer:  i.Issuer,
+			Subject: i.Subject,
+			Created: i.Created.UTC(),
+		})
+	}
+
+	snippets, err := app.snippets.ByUser(id)
+	if err != nil {
+		return nil, err
+	}
+	for _, s := range snippets {
+		data.Snippets = append(data.Snippets, accountDataSnippet{
+			ID:      s.ID,
+			Title:   s.Title,
+			Content: s.Content,
+			Created: s.Created.UTC(),
+			Updated: s.Updated.UTC(),
+			Expires: s.Expires.UTC(),
+		})
+	}
+
+	sessions, err := app.sessions.ByUser(id)
+	if err != nil {
+		return nil, err
+	}
+	for _, s := range sessions {
+		data.Sessions = append(data.Sessions, accountDataSession{
+			UserAgent: s.UserAgent,
+			IP:        s.IP,
+			Created:   s.Created.UTC(),
+			LastSeen:  s.LastSeen.UTC(),
+		})
+	}
+
+	passkeys, err := app.passkeys.ByUser(id)
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range passkeys {
+		passkey := accountDataPasskey{Name: p.Name, Created: p.Created.UTC()}
+		if !p.LastUsed.IsZero() {
+			lastUsed := p.LastUsed.UTC()
+			passkey.LastUsed = &lastUsed
+		}
+		data.Passkeys = append(data.Passkeys, passkey)
+	}
+
+	tokens, err := app.tokens.ByUser(id)
+	if err != nil {
+		return nil, err
+	}
+	for _, t := range tokens {
+		data.APITokens = append(data.APITokens, accountDataToken{Created: t.Created.UTC()})
+	}
+
+	webhooks, err := app.webhooks.ByUser(id)
+	if err != nil {
+		return nil, err
+	}
+	for _, wh := range webhooks {
+		data.Webhooks = append(data.Webhooks, accountDataWebhook{
+			URL:     wh.URL,
+			Events:  wh.Events,
+			Created: wh.Created.UTC(),
+		})
+	}
+
+	deliveries, err := app.webhooks.Deliveries(id, 0)
+	if err != nil {
+		return nil, err
+	}
+	for _, d := range deliveries {
+		data.WebhookDeliveries = append(data.WebhookDeliveries, accountDataDelivery{
+			URL:            d.URL,
+			Event:          d.Event,
+			Status:         d.Status,
+			Attempts:       d.Attempts,
+			ResponseStatus: d.ResponseStatus,
+			LastError:      d.LastError,
+			Created:        d.Created.UTC(),
+		})
+	}
+
+	events, err := app.auditLog.Search(models.AuditFilter{UserID: id})
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range events {
+		event := accountDataEvent{
+			Event:   e.Event,
+			Detail:  e.Detail,
+			ByStaff: e.ByStaff(),
+			Created: e.Created.UTC(),
+		}
+		if !e.ByStaff() {
+			event.IP = e.IP
+			event.UserAgent = e.UserAgent
+		}
+		data.AuditEvents = append(data.AuditEvents, event)
+	}
+	return data, nil
+}
+
+// ssoReauthWindow is how recently a user must have logged in with single
+// sign-on to delete their account without a password. Accounts created
+// through single sign-on have a random password nobody knows.
+const ssoReauthWindow = 10 * time.Minute
+
+type accountDeleteForm struct {
+	Password            string `form:"password"`
+	Snippets            string `form:"snippets"`
+	RecentSSOLogin      bool   `form:"-"`
+	validator.Validator `form:"-"`
+}
+
+func (app *application) accountDelete(w http.ResponseWriter, r *http.Request) {
+	data := app.newTemplateData(r)
+	data.Form = accountDeleteForm{Snippets: "delete", RecentSSOLogin: app.recentSSOLogin(r)}
+	app.render(w, r, http.StatusOK, "delete.tmpl", data)
+}
+
+// accountDeleteReauth sends the user through single sign-on again and back
+// to /account/delete, where the fresh login stands in for the password.
+func (app *application) accountDeleteReauth(w http.ResponseWriter, r *http.Request) {
+	if app.oidc == nil {
+		app.notFound(w)
+		return
+	}
+	app.sessionManager.Put(r.Context(), "redirectPathAfterLogin", "/account/delete")
+	http.Redirect(w, r, "/user/login/oidc", http.StatusSeeOther)
+}
+
+func (app *application) recentSSOLogin(r *http.Request) bool {
+	if !strings.HasPrefix(app.sessionManager.GetString(r.Context(), "loginMethod"), "oidc") {
+		return false
+	}
+	loginTime := time.Unix(app.sessionManager.GetInt64(r.Context(), "loginTime"), 0)
+	return time.Since(loginTime) < ssoReauthWindow
+}
+
+func (app *application) accountDeletePost(w http.ResponseWriter, r *http.Request) {
+	var form accountDeleteForm
+	if err := app.decodePostForm(r, &form); err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+	form.RecentSSOLogin = app.recentSSOLogin(r)
+	if !form.RecentSSOLogin {
+		form.CheckField(validator.NotBlank(form.Password), "password", "this field cannot be empty")
+	}
+	form.CheckField(validator.PermittedValue(form.Snippets, "delete", "anonymize"), "snippets", "choose what happens to your snippets")
+
+	id := app.sessionManager.GetInt(r.Context(), "authenticatedUserID")
+	if form.Valid() && !form.RecentSSOLogin {
+		err := app.users.CheckPassword(id, form.Password)
+		if err != nil {
+			if !errors.Is(err, models.ErrInvalidCredentials) {
+				app.serverError(w, r, err)
+				return
+			}
+			form.AddFieldError("password", "password is incorrect")
+		}
+	}
+	if !form.Valid() {
+		data := app.newTemplateData(r)
+		data.Form = form
+		app.render(w, r, http.StatusUnprocessableEntity, "delete.tmpl", data)
+		return
+	}
+
+	if err := app.users.Delete(id, form.Snippets == "anonymize"); err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	app.audit(r, id, models.AuditAccountDelete, form.Snippets+" snippets")
+	// Destroy the current session first so that saving it at the end of the
+	// request does not bring it back.
+	if err := app.sessionManager.Destroy(r.Context()); err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	if err := app.destroyUserSessions(r.Context(), id); err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	app.sessionManager.Put(r.Context(), "flash", "your account has been deleted")
+	http.Redirect(w, r, "/", http.StatusSeeOther)
+}
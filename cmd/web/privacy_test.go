@@ -0,0 +1,162 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/MohammadLashkari/snippetbox/internal/assert"
+	"github.com/MohammadLashkari/snippetbox/internal/models"
+	"github.com/MohammadLashkari/snippetbox/internal/oidc"
+	"github.com/MohammadLashkari/snippetbox/internal/oidc/oidctest"
+)
+
+func TestAccountData(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+	ts.logIn(t)
+	if err := app.auditLog.Insert(&models.AuditEvent{UserID: 2, ActorID: 1, Event: models.AuditAdminSuspend}); err != nil {
+		t.Fatal(err)
+	}
+	err := app.auditLog.Insert(&models.AuditEvent{UserID: 1, ActorID: 5, Event: models.AuditAdminUnsuspend, IP: "192.0.2.5", UserAgent: "staff-agent"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := app.identities.Insert(1, "https://sso.example.com", "subject-1"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := app.emailChanges.New(1, "foo@gmail.com", "new@example.com", time.Hour); err != nil {
+		t.Fatal(err)
+	}
+
+	code, header, body := ts.get(t, "/account/data")
+	assert.Equal(t, code, http.StatusOK)
+	assert.Equal(t, header.Get("Content-Type"), "application/json")
+	assert.Equal(t, header.Get("Content-Disposition"), `attachment; filename="snippetbox-data.json"`)
+
+	var data accountData
+	if err := json.Unmarshal([]byte(body), &data); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, data.Profile.ID, 1)
+	assert.Equal(t, data.Profile.Username, "foo")
+	assert.Equal(t, data.Profile.Email, "foo@gmail.com")
+	assert.Equal(t, len(data.PreviousUsernames), 1)
+	assert.Equal(t, data.PreviousUsernames[0], "oldfoo")
+	assert.Equal(t, data.PendingEmailChange.NewEmail, "new@example.com")
+	assert.Equal(t, len(data.Identities), 1)
+	assert.Equal(t, data.Identities[0].Subject, "subject-1")
+	assert.Equal(t, len(data.Snippets), 1)
+	assert.Equal(t, data.Snippets[0].Title, "hello world")
+	assert.Equal(t, len(data.Sessions), 1)
+	assert.Equal(t, len(data.APITokens), 1)
+	assert.Equal(t, len(data.WebhookDeliveries), 1)
+	assert.Equal(t, data.WebhookDeliveries[0].Status, models.DeliveryDelivered)
+	// The login and the staff action on user 1 are in the audit log, but not
+	// what user 1 did to others nor where the staff member acted from.
+	assert.Equal(t, len(data.AuditEvents), 2)
+	assert.Equal(t, data.AuditEvents[0].Event, models.AuditAdminUnsuspend)
+	assert.Equal(t, data.AuditEvents[0].ByStaff, true)
+	assert.Equal(t, data.AuditEvents[0].IP, "")
+	assert.Equal(t, data.AuditEvents[0].UserAgent, "")
+	assert.Equal(t, data.AuditEvents[1].Event, models.AuditLogin)
+	assert.Equal(t, data.AuditEvents[1].Detail, "password")
+}
+
+func TestAccountDeletePost(t *testing.T) {
+	app := newTestApplication(t)
+
+	tests := []struct {
+		name         string
+		password     string
+		snippets     string
+		wantCode     int
+		wantError    string
+		wantLoggedIn bool
+	}{
+		{"Delete snippets", "password", "delete", http.StatusSeeOther, "", false},
+		{"Anonymize snippets", "password", "anonymize", http.StatusSeeOther, "", false},
+		{"Wrong password", "wrong", "delete", http.StatusUnprocessableEntity, "password is incorrect", true},
+		{"Blank password", "", "delete", http.StatusUnprocessableEntity, "this field cannot be empty", true},
+		{"Unknown choice", "password", "keep", http.StatusUnprocessableEntity, "choose what happens to your snippets", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ts := newTestServer(t, app.routes())
+			defer ts.Close()
+			ts.logIn(t)
+			// Record the session so that it can be listed and destroyed.
+			_, _, body := ts.get(t, "/account/delete")
+
+			form := url.Values{}
+			form.Add("password", tt.password)
+			form.Add("snippets", tt.snippets)
+			form.Add("csrf_token", extractCSRFToken(t, body))
+			code, header, body := ts.postForm(t, "/account/delete", form)
+			assert.Equal(t, code, tt.wantCode)
+			if tt.wantError != "" {
+				assert.StringContains(t, body, tt.wantError)
+			} else {
+				assert.Equal(t, header.Get("Location"), "/")
+			}
+
+			code, _, _ = ts.get(t, "/account/view")
+			assert.Equal(t, code == http.StatusOK, tt.wantLoggedIn)
+		})
+	}
+}
+
+func TestAccountDeleteSSO(t *testing.T) {
+	idp := oidctest.NewServer(oidctest.User{Subject: "1002", Email: "foo@gmail.com", EmailVerified: true})
+	defer idp.Close()
+
+	app := newTestApplication(t)
+	config := oidc.Config{ClientID: oidctest.ClientID, ClientSecret: oidctest.ClientSecret}
+	provider, err := oidc.Discover(context.Background(), idp.URL, config, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	app.oidc = provider
+	app.oidcName = "Example SSO"
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+	ts.logIn(t)
+
+	// A password login still needs the password.
+	_, _, body := ts.get(t, "/account/delete")
+	assert.StringContains(t, body, "<a href='/account/delete/reauth'>Log in with Example SSO again</a>")
+	form := url.Values{}
+	form.Add("snippets", "delete")
+	form.Add("csrf_token", extractCSRFToken(t, body))
+	code, _, body := ts.postForm(t, "/account/delete", form)
+	assert.Equal(t, code, http.StatusUnprocessableEntity)
+	assert.StringContains(t, body, "this field cannot be empty")
+
+	code, header, _ := ts.get(t, "/account/delete/reauth")
+	assert.Equal(t, code, http.StatusSeeOther)
+	assert.Equal(t, header.Get("Location"), "/user/login/oidc")
+	_, header, _ = ts.get(t, "/user/login/oidc")
+	rs, err := ts.Client().Get(header.Get("Location"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	rs.Body.Close()
+	callback, err := url.Parse(rs.Header.Get("Location"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	code, header, _ = ts.get(t, callback.RequestURI())
+	assert.Equal(t, code, http.StatusSeeOther)
+	assert.Equal(t, header.Get("Location"), "/account/delete")
+
+	_, _, body = ts.get(t, "/account/delete")
+	assert.StringContains(t, body, "You have just logged in with single sign-on, so no password is needed.")
+	form.Set("csrf_token", extractCSRFToken(t, body))
+	code, header, _ = ts.postForm(t, "/account/delete", form)
+	assert.Equal(t, code, http.StatusSeeOther)
+	assert.Equal(t, header.Get("Location"), "/")
+}
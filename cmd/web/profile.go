@@ -0,0 +1,225 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/MohammadLashkari/snippetbox/internal/models"
+	"github.com/MohammadLashkari/snippetbox/internal/validator"
+)
+
+// userProfile shows the public page of the user with the ID in the path,
+// or sends the browser to /u/{username} if they have a username.
+func (app *application) userProfile(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil || id < 1 {
+		app.notFound(w)
+		return
+	}
+	user, err := app.users.Get(id)
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			app.notFound(w)
+		} else {
+			app.serverError(w, r, err)
+		}
+		return
+	}
+	if user.Username != "" {
+		http.Redirect(w, r, profileURL(user), http.StatusFound)
+		return
+	}
+	app.renderProfile(w, r, user)
+}
+
+// usernameProfile shows the public page of the user with the username in
+// the path. Old usernames and other spellings redirect to the current one.
+func (app *application) usernameProfile(w http.ResponseWriter, r *http.Request) {
+	user, ok := app.userFromUsername(w, r)
+	if !ok {
+		return
+	}
+	if user.Username != r.PathValue("username") {
+		http.Redirect(w, r, profileURL(user), http.StatusFound)
+		return
+	}
+	app.renderProfile(w, r, user)
+}
+
+// usernameSnippet shows a snippet at its permalink under its author's
+// username, redirecting when the username has changed.
+func (app *application) usernameSnippet(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil || id < 1 {
+		app.notFound(w)
+		return
+	}
+	user, ok := app.userFromUsername(w, r)
+	if !ok {
+		return
+	}
+	snippet, err := app.snippets.Get(id)
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			app.notFound(w)
+		} else {
+			app.serverError(w, r, err)
+		}
+		return
+	}
+	if snippet.UserID != user.ID {
+		app.notFound(w)
+		return
+	}
+	if user.Username != r.PathValue("username") {
+		http.Redirect(w, r, snippetURL(user, snippet), http.StatusFound)
+		return
+	}
+	app.renderSnippet(w, r, snippet, user)
+}
+
+func (app *application) userFromUsername(w http.ResponseWriter, r *http.Request) (*models.User, bool) {
+	user, err := app.users.GetByUsername(r.PathValue("username"))
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			app.notFound(w)
+		} else {
+			app.serverError(w, r, err)
+		}
+		return nil, false
+	}
+	// A username given up without taking another one has nowhere to go.
+	if user.Username == "" {
+		app.notFound(w)
+		return nil, false
+	}
+	return user, true
+}
+
+// renderProfile shows a user's public page: their name, bio, website, join
+// date and snippets. It must never show their email address.
+func (app *application) renderProfile(w http.ResponseWriter, r *http.Request, user *models.User) {
+	snippets, err := app.snippets.ByUser(user.ID)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	data := app.newTemplateData(r)
+	data.Profile = user
+	data.Snippets = snippets
+	data.Meta.Type = "profile"
+	data.Meta.Title = user.Name
+	if user.Bio != "" {
+		data.Meta.Description = excerpt(user.Bio, 200)
+	}
+	app.render(w, r, http.StatusOK, "user.tmpl", data)
+}
+
+type accountProfileForm struct {
+	Name                string `form:"name"`
+	Bio                 string `form:"bio"`
+	Website             string `form:"website"`
+	validator.Validator `form:"-"`
+}
+
+func (app *application) accountProfile(w http.ResponseWriter, r *http.Request) {
+	id := app.sessionManager.GetInt(r.Context(), "authenticatedUserID")
+	user, err := app.users.Get(id)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	data := app.newTemplateData(r)
+	data.Form = accountProfileForm{Name: user.Name, Bio: user.Bio, Website: user.Website}
+	app.render(w, r, http.StatusOK, "profile.tmpl", data)
+}
+
+func (app *application) accountProfilePost(w http.ResponseWriter, r *http.Request) {
+	var form accountProfileForm
+	if err := app.decodePostForm(r, &form); err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+	form.Name = strings.TrimSpace(form.Name)
+	form.Bio = strings.TrimSpace(form.Bio)
+	form.Website = strings.TrimSpace(form.Website)
+
+	form.CheckField(validator.NotBlank(form.Name), "name", "this field cannot be empty")
+	form.CheckField(validator.MaxChars(form.Name, 255), "name", "this field cannot be more than 255 characters long")
+	form.CheckField(validator.MaxChars(form.Bio, 500), "bio", "this field cannot be more than 500 characters long")
+	if form.Website != "" {
+		form.CheckField(validator.IsURL(form.Website, "http", "https"), "website", "this field must be an http or https URL")
+		form.CheckField(validator.MaxChars(form.Website, 255), "website", "this field cannot be more than 255 characters long")
+	}
+	if !form.Valid() {
+		data := app.newTemplateData(r)
+		data.Form = form
+		app.render(w, r, http.StatusUnprocessableEntity, "profile.tmpl", data)
+		return
+	}
+
+	id := app.sessionManager.GetInt(r.Context(), "authenticatedUserID")
+	if err := app.users.UpdateProfile(id, form.Name, form.Bio, form.Website); err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	app.sessionManager.Put(r.Context(), "flash", "your profile has been updated.")
+	http.Redirect(w, r, "/user/"+strconv.Itoa(id), http.StatusSeeOther)
+}
+
+type accountUsernameForm struct {
+	Username            string `form:"username"`
+	validator.Validator `form:"-"`
+}
+
+func (app *application) accountUsername(w http.ResponseWriter, r *http.Request) {
+	id := app.sessionManager.GetInt(r.Context(), "authenticatedUserID")
+	user, err := app.users.Get(id)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	data := app.newTemplateData(r)
+	data.User = user
+	data.Form = accountUsernameForm{Username: user.Username}
+	app.render(w, r, http.StatusOK, "username.tmpl", data)
+}
+
+func (app *application) accountUsernamePost(w http.ResponseWriter, r *http.Request) {
+	var form accountUsernameForm
+	if err := app.decodePostForm(r, &form); err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+	id := app.sessionManager.GetInt(r.Context(), "authenticatedUserID")
+	user, err := app.users.Get(id)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	form.Username = strings.TrimSpace(form.Username)
+	form.CheckField(validator.NotBlank(form.Username), "username", "this field cannot be empty")
+	form.CheckUsername("username", form.Username)
+	if form.Valid() {
+		err := app.users.SetUsername(id, form.Username)
+		if err != nil {
+			if !errors.Is(err, models.ErrDuplicateUsername) {
+				app.serverError(w, r, err)
+				return
+			}
+			form.AddFieldError("username", "this username is already taken")
+		}
+	}
+	if !form.Valid() {
+		data := app.newTemplateData(r)
+		data.User = user
+		data.Form = form
+		app.render(w, r, http.StatusUnprocessableEntity, "username.tmpl", data)
+		return
+	}
+	app.sessionManager.Put(r.Context(), "flash", "your username is now "+form.Username+".")
+	http.Redirect(w, r, "/u/"+form.Username, http.StatusSeeOther)
+}
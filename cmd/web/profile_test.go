@@ -0,0 +1,217 @@
+package main
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/MohammadLashkari/snippetbox/internal/assert"
+)
+
+func TestUserProfile(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	tests := []struct {
+		name         string
+		urlPath      string
+		wantCode     int
+		wantLocation string
+		wantBody     []string
+	}{
+		{
+			name:     "Username",
+			urlPath:  "/u/foo",
+			wantCode: http.StatusOK,
+			wantBody: []string{
+				"<h2>foo</h2>",
+				"<p>@foo</p>",
+				"<p>Writes Go in the evenings.</p>",
+				"<a href='https://foo.example.com' rel='nofollow ugc noopener'>https://foo.example.com</a>",
+				"<a href='/u/foo/1'>hello world</a>",
+			},
+		},
+		{
+			name:         "ID with username",
+			urlPath:      "/user/1",
+			wantCode:     http.StatusFound,
+			wantLocation: "/u/foo",
+		},
+		{
+			name:         "Other case",
+			urlPath:      "/u/FOO",
+			wantCode:     http.StatusFound,
+			wantLocation: "/u/foo",
+		},
+		{
+			name:         "Old username",
+			urlPath:      "/u/oldfoo",
+			wantCode:     http.StatusFound,
+			wantLocation: "/u/foo",
+		},
+		{
+			name:     "Unknown username",
+			urlPath:  "/u/nobody",
+			wantCode: http.StatusNotFound,
+		},
+		{
+			name:     "No username",
+			urlPath:  "/user/2",
+			wantCode: http.StatusOK,
+			wantBody: []string{"<h2>bar</h2>", "No snippets yet."},
+		},
+		{
+			name:     "Non-existent ID",
+			urlPath:  "/user/99",
+			wantCode: http.StatusNotFound,
+		},
+		{
+			name:     "Negative ID",
+			urlPath:  "/user/-1",
+			wantCode: http.StatusNotFound,
+		},
+		{
+			name:     "String ID",
+			urlPath:  "/user/foo",
+			wantCode: http.StatusNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, header, body := ts.get(t, tt.urlPath)
+			assert.Equal(t, code, tt.wantCode)
+			assert.Equal(t, header.Get("Location"), tt.wantLocation)
+			for _, want := range tt.wantBody {
+				assert.StringContains(t, body, want)
+			}
+			assert.Equal(t, strings.Contains(body, "@gmail.com"), false)
+		})
+	}
+}
+
+func TestUsernameSnippet(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	tests := []struct {
+		name         string
+		urlPath      string
+		wantCode     int
+		wantLocation string
+		wantBody     string
+	}{
+		{"Valid", "/u/foo/1", http.StatusOK, "", "<strong>hello world</strong>"},
+		{"Old username", "/u/oldfoo/1", http.StatusFound, "/u/foo/1", ""},
+		{"Other case", "/u/Foo/1", http.StatusFound, "/u/foo/1", ""},
+		{"Non-existent snippet", "/u/foo/2", http.StatusNotFound, "", ""},
+		{"Unknown username", "/u/nobody/1", http.StatusNotFound, "", ""},
+		{"String ID", "/u/foo/one", http.StatusNotFound, "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, header, body := ts.get(t, tt.urlPath)
+			assert.Equal(t, code, tt.wantCode)
+			assert.Equal(t, header.Get("Location"), tt.wantLocation)
+			assert.StringContains(t, body, tt.wantBody)
+		})
+	}
+}
+
+func TestAccountUsernamePost(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+	ts.logIn(t)
+
+	_, _, body := ts.get(t, "/account/username")
+	validCSRFToken := extractCSRFToken(t, body)
+
+	tests := []struct {
+		name         string
+		username     string
+		wantCode     int
+		wantLocation string
+		wantError    string
+	}{
+		{"Valid", "foo-2", http.StatusSeeOther, "/u/foo-2", ""},
+		{"Change of case", "Foo", http.StatusSeeOther, "/u/Foo", ""},
+		{"Blank", "", http.StatusUnprocessableEntity, "", "this field cannot be empty"},
+		{"Bad characters", "bar baz", http.StatusUnprocessableEntity, "", "this field must be 3 to 30 letters"},
+		{"Reserved", "Admin", http.StatusUnprocessableEntity, "", "this username is reserved"},
+		{"Taken", "JANE", http.StatusUnprocessableEntity, "", "this username is already taken"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			form := url.Values{}
+			form.Add("username", tt.username)
+			form.Add("csrf_token", validCSRFToken)
+			code, header, body := ts.postForm(t, "/account/username", form)
+			assert.Equal(t, code, tt.wantCode)
+			assert.Equal(t, header.Get("Location"), tt.wantLocation)
+			assert.StringContains(t, body, tt.wantError)
+		})
+	}
+}
+
+func TestSnippetViewAuthor(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	_, _, body := ts.get(t, "/snippet/view/1")
+	assert.StringContains(t, body, "<span>By <a href='/u/foo'>foo</a></span>")
+	assert.Equal(t, strings.Contains(body, "foo@gmail.com"), false)
+}
+
+func TestAccountProfilePost(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	code, header, _ := ts.get(t, "/account/profile")
+	assert.Equal(t, code, http.StatusSeeOther)
+	assert.Equal(t, header.Get("Location"), "/user/login")
+
+	ts.logIn(t)
+	code, _, body := ts.get(t, "/account/profile")
+	assert.Equal(t, code, http.StatusOK)
+	assert.StringContains(t, body, "<textarea name='bio'>Writes Go in the evenings.</textarea>")
+	validCSRFToken := extractCSRFToken(t, body)
+
+	tests := []struct {
+		name      string
+		userName  string
+		bio       string
+		website   string
+		wantCode  int
+		wantError string
+	}{
+		{"Valid", "Foo Bar", "Hello.", "https://foo.example.com/blog", http.StatusSeeOther, ""},
+		{"No website", "Foo Bar", "", "", http.StatusSeeOther, ""},
+		{"Blank name", " ", "Hello.", "", http.StatusUnprocessableEntity, "this field cannot be empty"},
+		{"Long bio", "Foo Bar", strings.Repeat("a", 501), "", http.StatusUnprocessableEntity, "this field cannot be more than 500 characters long"},
+		{"Bad website", "Foo Bar", "", "javascript:alert(1)", http.StatusUnprocessableEntity, "this field must be an http or https URL"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			form := url.Values{}
+			form.Add("name", tt.userName)
+			form.Add("bio", tt.bio)
+			form.Add("website", tt.website)
+			form.Add("csrf_token", validCSRFToken)
+			code, header, body := ts.postForm(t, "/account/profile", form)
+			assert.Equal(t, code, tt.wantCode)
+			assert.StringContains(t, body, tt.wantError)
+			if tt.wantCode == http.StatusSeeOther {
+				assert.Equal(t, header.Get("Location"), "/user/1")
+			}
+		})
+	}
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/MohammadLashkari/snippetbox/internal/models"
+	"github.com/MohammadLashkari/snippetbox/internal/validator"
+)
+
+const passwordResetTTL = time.Hour
+
+type userPasswordForgotForm struct {
+	Email               string `form:"email"`
+	validator.Validator `form:"-"`
+}
+
+type userPasswordResetForm struct {
+	Token                   string `form:"token"`
+	NewPassword             string `form:"newPassword"`
+	NewPasswordConfirmation string `form:"newPasswordConfirmation"`
+	validator.Validator     `form:"-"`
+}
+
+func (app *application) userPasswordForgot(w http.ResponseWriter, r *http.Request) {
+	data := app.newTemplateData(r)
+	data.Form = userPasswordForgotForm{}
+	app.render(w, r, http.StatusOK, "forgot.tmpl", data)
+}
+
+// userPasswordForgotPost answers the same way whether or not the address
+// belongs to an account, and the email goes out in the background, so the
+// form cannot be used to find out who is registered.
+func (app *application) userPasswordForgotPost(w http.ResponseWriter, r *http.Request) {
+	var form userPasswordForgotForm
+	if err := app.decodePostForm(r, &form); err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+	form.CheckField(validator.NotBlank(form.Email), "email", "this field cannot be empty")
+	form.CheckField(validator.Matches(form.Email, validator.EmailRX), "email", "this field must be a valid email address")
+	if !form.Valid() {
+		data := app.newTemplateData(r)
+		data.Form = form
+		app.render(w, r, http.StatusUnprocessableEntity, "forgot.tmpl", data)
+		return
+	}
+
+	user, err := app.users.GetByEmail(form.Email)
+	if err != nil && !errors.Is(err, models.ErrNoRecord) {
+		app.serverError(w, r, err)
+		return
+	}
+	if user != nil {
+		token, err := app.passwordResets.New(user.ID, passwordResetTTL)
+		if err != nil {
+			app.serverError(w, r, err)
+			return
+		}
+		app.sendEmail(r, user.Email, "reset.tmpl", map[string]any{
+			"Name":    user.Name,
+			"URL":     app.baseURL + "/user/password/reset?token=" + url.QueryEscape(token),
+			"Expires": "1 hour",
+		})
+	}
+	app.sessionManager.Put(r.Context(), "flash", "if an account exists for that address, we've emailed it a link to reset the password.")
+	http.Redirect(w, r, "/user/login", http.StatusSeeOther)
+}
+
+func (app *application) userPasswordReset(w http.ResponseWriter, r *http.Request) {
+	token := r.URL.Query().Get("token")
+	if _, err := app.passwordResets.UserID(token); err != nil {
+		app.passwordResetInvalid(w, r, err)
+		return
+	}
+	data := app.newTemplateData(r)
+	data.Form = userPasswordResetForm{Token: token}
+	app.render(w, r, http.StatusOK, "reset.tmpl", data)
+}
+
+func (app *application) userPasswordResetPost(w http.ResponseWriter, r *http.Request) {
+	var form userPasswordResetForm
+	if err := app.decodePostForm(r, &form); err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+	userID, err := app.passwordResets.UserID(form.Token)
+	if err != nil {
+		app.passwordResetInvalid(w, r, err)
+		return
+	}
+	user, err := app.users.Get(userID)
+	if err != nil {
+		app.passwordResetInvalid(w, r, err)
+		return
+	}
+	form.CheckField(validator.NotBlank(form.NewPassword), "newPassword", "this field cannot be empty")
+	form.CheckPassword("newPassword", form.NewPassword, user.Name, user.Username, user.Email)
+	form.CheckField(validator.NotBlank(form.NewPasswordConfirmation), "newPasswordConfirmation", "this field cannot be empty")
+	form.CheckField(form.NewPassword == form.NewPasswordConfirmation, "newPasswordConfirmation", "password donot match")
+	if !form.Valid() {
+		data := app.newTemplateData(r)
+		data.Form = form
+		app.render(w, r, http.StatusUnprocessableEntity, "reset.tmpl", data)
+		return
+	}
+
+	id, err := app.passwordResets.Consume(form.Token)
+	if err != nil {
+		app.passwordResetInvalid(w, r, err)
+		return
+	}
+	if err := app.users.PasswordSet(id, form.NewPassword); err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	// Someone who took over the account may have asked to move it to their
+	// own address; the link for that must not outlive the reset.
+	if err := app.emailChanges.Cancel(id); err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	// Whoever knew the old password may still be logged in somewhere. The
+	// current session is renewed first so that saving it at the end of the
+	// request doesn't bring back a session destroyed here.
+	if err := app.sessionManager.RenewToken(r.Context()); err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	app.sessionManager.Remove(r.Context(), "authenticatedUserID")
+	if err := app.destroyUserSessions(r.Context(), id); err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	app.audit(r, id, models.AuditPasswordReset, "")
+	app.sessionManager.Put(r.Context(), "flash", "your password has been reset. please login.")
+	http.Redirect(w, r, "/user/login", http.StatusSeeOther)
+}
+
+func (app *application) passwordResetInvalid(w http.ResponseWriter, r *http.Request, err error) {
+	if !errors.Is(err, models.ErrNoRecord) {
+		app.serverError(w, r, err)
+		return
+	}
+	app.sessionManager.Put(r.Context(), "flash", "that password reset link is invalid or has expired.")
+	http.Redirect(w, r, "/user/password/forgot", http.StatusSeeOther)
+}
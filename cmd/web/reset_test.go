@@ -0,0 +1,131 @@
+package main
+
+import (
+	"bytes"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/MohammadLashkari/snippetbox/internal/assert"
+	"github.com/MohammadLashkari/snippetbox/internal/mailer"
+	"github.com/MohammadLashkari/snippetbox/internal/models"
+)
+
+func TestUserPasswordForgot(t *testing.T) {
+	app := newTestApplication(t)
+	var buf bytes.Buffer
+	app.mailer = &mailer.Log{Logger: newLogger(&buf, "text")}
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	tests := []struct {
+		name      string
+		email     string
+		wantEmail bool
+	}{
+		{"Registered", "bar@example.com", true},
+		{"Unknown", "nobody@example.com", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf.Reset()
+			_, _, body := ts.get(t, "/user/password/forgot")
+			form := url.Values{}
+			form.Add("email", tt.email)
+			form.Add("csrf_token", extractCSRFToken(t, body))
+			code, header, _ := ts.postForm(t, "/user/password/forgot", form)
+
+			// Both cases must look the same to the client.
+			assert.Equal(t, code, http.StatusSeeOther)
+			assert.Equal(t, header.Get("Location"), "/user/login")
+			_, _, body = ts.get(t, "/user/login")
+			assert.StringContains(t, body, "if an account exists for that address")
+
+			app.wg.Wait()
+			if tt.wantEmail {
+				assert.StringContains(t, buf.String(), `to=bar@example.com subject="Reset your Snippetbox password"`)
+				// The link is built from the base URL, never from the Host
+				// header of the request.
+				assert.StringContains(t, buf.String(), "https://snippetbox.test/user/password/reset?token=ABCDEFGHIJKLMNOPQRSTUVWXYZ")
+			} else {
+				assert.Equal(t, buf.String(), "")
+			}
+		})
+	}
+}
+
+func TestUserPasswordReset(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	t.Run("Invalid token", func(t *testing.T) {
+		code, header, _ := ts.get(t, "/user/password/reset?token=wrong")
+		assert.Equal(t, code, http.StatusSeeOther)
+		assert.Equal(t, header.Get("Location"), "/user/password/forgot")
+	})
+
+	// Log user 1 in from another client, whose session the reset must end.
+	other := newTestServer(t, app.routes())
+	defer other.Close()
+	_, _, body := other.get(t, "/user/login")
+	form := url.Values{}
+	form.Add("email", "foo@example.com")
+	form.Add("password", "password")
+	form.Add("csrf_token", extractCSRFToken(t, body))
+	other.postForm(t, "/user/login", form)
+	code, _, _ := other.get(t, "/account/view")
+	assert.Equal(t, code, http.StatusOK)
+
+	code, _, body = ts.get(t, "/user/password/reset?token=valid-reset-token")
+	assert.Equal(t, code, http.StatusOK)
+	assert.StringContains(t, body, "<input type='hidden' name='token' value='valid-reset-token'>")
+	csrfToken := extractCSRFToken(t, body)
+
+	t.Run("Mismatched passwords", func(t *testing.T) {
+		form := url.Values{}
+		form.Add("token", "valid-reset-token")
+		form.Add("newPassword", "newPa$$word")
+		form.Add("newPasswordConfirmation", "otherPa$$word")
+		form.Add("csrf_token", csrfToken)
+		code, _, _ := ts.postForm(t, "/user/password/reset", form)
+		assert.Equal(t, code, http.StatusUnprocessableEntity)
+	})
+
+	t.Run("Weak password", func(t *testing.T) {
+		form := url.Values{}
+		form.Add("token", "valid-reset-token")
+		form.Add("newPassword", "qwerty123")
+		form.Add("newPasswordConfirmation", "qwerty123")
+		form.Add("csrf_token", csrfToken)
+		code, _, body := ts.postForm(t, "/user/password/reset", form)
+		assert.Equal(t, code, http.StatusUnprocessableEntity)
+		assert.StringContains(t, body, "this password is too common")
+	})
+
+	t.Run("Valid", func(t *testing.T) {
+		change, err := app.emailChanges.New(1, "foo@gmail.com", "new@example.com", time.Hour)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		form := url.Values{}
+		form.Add("token", "valid-reset-token")
+		form.Add("newPassword", "newPa$$word")
+		form.Add("newPasswordConfirmation", "newPa$$word")
+		form.Add("csrf_token", csrfToken)
+		code, header, _ := ts.postForm(t, "/user/password/reset", form)
+		assert.Equal(t, code, http.StatusSeeOther)
+		assert.Equal(t, header.Get("Location"), "/user/login")
+
+		code, header, _ = other.get(t, "/account/view")
+		assert.Equal(t, code, http.StatusSeeOther)
+		assert.Equal(t, header.Get("Location"), "/user/login")
+
+		// The reset cancels a pending change of email address.
+		_, err = app.emailChanges.Consume(change)
+		assert.Equal(t, err, models.ErrNoRecord)
+	})
+}
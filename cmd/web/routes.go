@@ -2,7 +2,10 @@ package main
 
 import (
 	"net/http"
+	"time"
 
+	"github.com/MohammadLashkari/snippetbox/internal/models"
+	"github.com/MohammadLashkari/snippetbox/internal/ratelimit"
 	"github.com/MohammadLashkari/snippetbox/ui"
 	"github.com/justinas/alice"
 )
@@ -13,27 +16,108 @@ func (app *application) routes() http.Handler {
 	fileServer := http.FileServer(http.FS(ui.Files))
 	mux.Handle("GET /static/", fileServer)
 
-	dynamic := alice.New(app.sessionManager.LoadAndSave, app.authenticate)
+	// Each limiter tracks at most 10,000 clients.
+	reads := app.rateLimit(ratelimit.New(ratelimit.Per(300, time.Minute), 60, 10_000))
+	writes := app.rateLimit(ratelimit.New(ratelimit.Per(30, time.Minute), 10, 10_000))
+	logins := app.rateLimit(ratelimit.New(ratelimit.Per(5, time.Minute), 5, 10_000))
+
+	dynamic := alice.New(app.sessionManager.LoadAndSave, noSurf, app.authenticate, app.trackSession, reads)
 	protected := dynamic.Append(app.requireAuthentication)
+	strict := dynamic.Append(logins)
+	verified := protected.Append(app.requireVerified)
+	moderators := protected.Append(app.requireRole(models.RoleModerator))
+	admins := protected.Append(app.requireRole(models.RoleAdmin))
 
 	// snippet
 	mux.Handle("GET /", dynamic.ThenFunc(app.homeHandler))
 	mux.Handle("GET /snippet/view/{id}", dynamic.ThenFunc(app.snippetView))
-	mux.Handle("GET /snippet/create", protected.ThenFunc(app.snippetCreate))
-	mux.Handle("POST /snippet/create", protected.ThenFunc(app.snippetCreatePost))
+	mux.Handle("GET /snippet/raw/{id}", alice.New(reads).ThenFunc(app.snippetRaw))
+	mux.Handle("GET /oembed", alice.New(reads).ThenFunc(app.oembed))
+	mux.Handle("GET /snippet/create", verified.ThenFunc(app.snippetCreate))
+	mux.Handle("POST /snippet/create", verified.Append(writes).ThenFunc(app.snippetCreatePost))
 	// user
+	mux.Handle("GET /user/{id}", dynamic.ThenFunc(app.userProfile))
+	mux.Handle("GET /u/{username}", dynamic.ThenFunc(app.usernameProfile))
+	mux.Handle("GET /u/{username}/{id}", dynamic.ThenFunc(app.usernameSnippet))
 	mux.Handle("GET /user/signup", dynamic.ThenFunc(app.userSignup))
-	mux.Handle("POST /user/signup", dynamic.ThenFunc(app.userSignupPost))
-	mux.Handle("POST /user/login", dynamic.ThenFunc(app.userLoginPost))
+	mux.Handle("POST /user/signup", strict.ThenFunc(app.userSignupPost))
+	mux.Handle("POST /user/login", strict.ThenFunc(app.userLoginPost))
 	mux.Handle("GET /user/login", dynamic.ThenFunc(app.userLogin))
+	mux.Handle("POST /user/login/passkey/begin", strict.ThenFunc(app.userLoginPasskeyBegin))
+	mux.Handle("POST /user/login/passkey/finish", strict.ThenFunc(app.userLoginPasskeyFinish))
+	mux.Handle("GET /user/login/oidc", dynamic.ThenFunc(app.userLoginOIDC))
+	mux.Handle("GET /user/login/oidc/callback", dynamic.ThenFunc(app.userLoginOIDCCallback))
+	mux.Handle("GET /user/login/2fa", dynamic.ThenFunc(app.userLoginTwoFactor))
+	mux.Handle("POST /user/login/2fa", strict.ThenFunc(app.userLoginTwoFactorPost))
+	mux.Handle("GET /user/password/forgot", dynamic.ThenFunc(app.userPasswordForgot))
+	mux.Handle("POST /user/password/forgot", strict.ThenFunc(app.userPasswordForgotPost))
+	mux.Handle("GET /user/password/reset", dynamic.ThenFunc(app.userPasswordReset))
+	mux.Handle("POST /user/password/reset", strict.ThenFunc(app.userPasswordResetPost))
+	mux.Handle("GET /user/verify", dynamic.ThenFunc(app.userVerify))
+	mux.Handle("GET /user/email/confirm", dynamic.ThenFunc(app.userEmailConfirm))
 	mux.Handle("POST /user/logout", protected.ThenFunc(app.userLogoutPost))
 
 	mux.Handle("GET /account/view", protected.ThenFunc(app.accountView))
+	mux.Handle("POST /account/verify/resend", protected.Append(writes).ThenFunc(app.accountVerifyResendPost))
+	mux.Handle("GET /account/profile", protected.ThenFunc(app.accountProfile))
+	mux.Handle("POST /account/profile", protected.ThenFunc(app.accountProfilePost))
+	mux.Handle("GET /account/username", protected.ThenFunc(app.accountUsername))
+	mux.Handle("POST /account/username", protected.ThenFunc(app.accountUsernamePost))
+	mux.Handle("GET /account/email/update", protected.ThenFunc(app.accountEmailUpdate))
+	mux.Handle("POST /account/email/update", protected.Append(writes).ThenFunc(app.accountEmailUpdatePost))
 	mux.Handle("GET /account/password/update", protected.ThenFunc(app.accountPasswordUpdate))
 	mux.Handle("POST /account/password/update", protected.ThenFunc(app.accountPasswordUpdatePost))
+	mux.Handle("GET /account/sessions", protected.ThenFunc(app.accountSessions))
+	mux.Handle("POST /account/sessions/{id}/revoke", protected.ThenFunc(app.accountSessionRevokePost))
+	mux.Handle("POST /account/sessions/revoke-others", protected.ThenFunc(app.accountSessionsRevokeOthersPost))
+	mux.Handle("GET /account/history", protected.ThenFunc(app.accountHistory))
+	mux.Handle("GET /account/passkeys", protected.ThenFunc(app.accountPasskeys))
+	mux.Handle("POST /account/passkeys/register/begin", protected.ThenFunc(app.accountPasskeyRegisterBegin))
+	mux.Handle("POST /account/passkeys/register/finish", protected.ThenFunc(app.accountPasskeyRegisterFinish))
+	mux.Handle("POST /account/passkeys/{id}/delete", protected.ThenFunc(app.accountPasskeyDeletePost))
+	mux.Handle("GET /account/2fa", protected.ThenFunc(app.accountTwoFactor))
+	mux.Handle("GET /account/2fa/qr.png", protected.ThenFunc(app.accountTwoFactorQRCode))
+	mux.Handle("POST /account/2fa/enable", protected.ThenFunc(app.accountTwoFactorEnablePost))
+	mux.Handle("POST /account/2fa/disable", protected.ThenFunc(app.accountTwoFactorDisablePost))
+	mux.Handle("POST /account/token/create", protected.ThenFunc(app.accountTokenCreatePost))
+	mux.Handle("GET /account/webhooks", protected.ThenFunc(app.accountWebhooks))
+	mux.Handle("POST /account/webhooks/create", protected.ThenFunc(app.accountWebhookCreatePost))
+	mux.Handle("POST /account/webhooks/{id}/delete", protected.ThenFunc(app.accountWebhookDeletePost))
+	mux.Handle("GET /account/export", protected.ThenFunc(app.accountExport))
+	mux.Handle("GET /account/data", protected.ThenFunc(app.accountData))
+	mux.Handle("GET /account/delete", protected.ThenFunc(app.accountDelete))
+	mux.Handle("POST /account/delete", protected.ThenFunc(app.accountDeletePost))
+	mux.Handle("GET /account/delete/reauth", protected.ThenFunc(app.accountDeleteReauth))
+	mux.Handle("GET /account/import", verified.ThenFunc(app.accountImport))
+	// Leave room for the CSRF token and multipart framing around the archive.
+	uploads := alice.New(limitBody(archiveMaxUploadSize + 64<<10)).Extend(verified)
+	mux.Handle("POST /account/import", uploads.Append(writes).ThenFunc(app.accountImportPost))
+	// admin
+	mux.Handle("GET /admin", moderators.ThenFunc(app.adminUsers))
+	mux.Handle("GET /admin/users/{id}", moderators.ThenFunc(app.adminUser))
+	mux.Handle("POST /admin/users/{id}/suspend", moderators.ThenFunc(app.adminUserSuspendPost))
+	mux.Handle("POST /admin/users/{id}/unsuspend", moderators.ThenFunc(app.adminUserUnsuspendPost))
+	mux.Handle("POST /admin/users/{id}/role", admins.ThenFunc(app.adminUserRolePost))
+	mux.Handle("POST /admin/users/{id}/delete", admins.ThenFunc(app.adminUserDeletePost))
+	mux.Handle("POST /admin/snippets/{id}/delete", moderators.ThenFunc(app.adminSnippetDeletePost))
+	mux.Handle("GET /admin/lockouts", admins.ThenFunc(app.adminLockouts))
+	mux.Handle("GET /admin/audit", admins.ThenFunc(app.adminAudit))
+	mux.Handle("POST /admin/lockouts/clear", admins.ThenFunc(app.adminLockoutClearPost))
+
 	mux.Handle("GET /about", dynamic.ThenFunc(app.about))
 	mux.HandleFunc("GET /ping", ping)
+	mux.HandleFunc("GET /ready", app.ready)
+
+	// gist-compatible api
+	api := alice.New(app.authenticateToken, reads)
+	apiProtected := api.Append(app.requireToken, writes)
+
+	mux.Handle("GET /api/gists", api.ThenFunc(app.gistList))
+	mux.Handle("GET /api/gists/{id}", api.ThenFunc(app.gistGet))
+	mux.Handle("POST /api/gists", apiProtected.ThenFunc(app.gistCreate))
+	mux.Handle("PATCH /api/gists/{id}", apiProtected.ThenFunc(app.gistUpdate))
+	mux.Handle("DELETE /api/gists/{id}", apiProtected.ThenFunc(app.gistDelete))
 
-	standard := alice.New(app.recoverPanic, app.logRequest, secureHeaders)
+	standard := alice.New(requestID, app.recoverPanic, app.logRequest, secureHeaders)
 	return standard.Then(mux)
 }
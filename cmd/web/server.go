@@ -0,0 +1,67 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"time"
+)
+
+// serve runs srv on ln until a signal arrives on quit, then shuts down in
+// order: /ready starts failing at once, the server keeps answering for
+// cfg.Shutdown.Delay so load balancers stop sending traffic, in-flight
+// requests finish, the webhook dispatcher stops and background tasks such
+// as emails complete. Everything after the delay must fit in
+// cfg.Shutdown.Timeout.
+func (app *application) serve(srv *http.Server, ln net.Listener, cfg *config, quit <-chan os.Signal) error {
+	ctx, stopDispatcher := context.WithCancel(context.Background())
+	defer stopDispatcher()
+	if app.dispatcher != nil {
+		app.background(ctx, func() {
+			app.dispatcher.Run(ctx)
+		})
+	}
+
+	shutdownErr := make(chan error, 1)
+	go func() {
+		s := <-quit
+		app.logger.Info("shutting down", "signal", s.String())
+		app.shuttingDown.Store(true)
+		srv.SetKeepAlivesEnabled(false)
+		time.Sleep(cfg.Shutdown.Delay)
+
+		ctx, cancel := context.WithTimeout(context.Background(), cfg.Shutdown.Timeout)
+		defer cancel()
+		if err := srv.Shutdown(ctx); err != nil {
+			shutdownErr <- fmt.Errorf("shutting down server: %w", err)
+			return
+		}
+
+		app.logger.Info("waiting for background tasks")
+		stopDispatcher()
+		done := make(chan struct{})
+		go func() {
+			app.wg.Wait()
+			close(done)
+		}()
+		select {
+		case <-done:
+			shutdownErr <- nil
+		case <-ctx.Done():
+			shutdownErr <- fmt.Errorf("waiting for background tasks: %w", ctx.Err())
+		}
+	}()
+
+	err := srv.ServeTLS(ln, cfg.TLS.Cert, cfg.TLS.Key)
+	if !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	if err := <-shutdownErr; err != nil {
+		return err
+	}
+	app.logger.Info("stopped server")
+	return nil
+}
@@ -0,0 +1,208 @@
+package main
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"io"
+	"log/slog"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/MohammadLashkari/snippetbox/internal/assert"
+)
+
+// writeTestCert writes a self-signed certificate for 127.0.0.1 and its key
+// to a temporary directory and returns their paths.
+func writeTestCert(t *testing.T) (certFile, keyFile string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		IPAddresses:  []net.IP{net.IPv4(127, 0, 0, 1)},
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	certFile = filepath.Join(dir, "cert.pem")
+	keyFile = filepath.Join(dir, "key.pem")
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+	if err := os.WriteFile(certFile, certPEM, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(keyFile, keyPEM, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return certFile, keyFile
+}
+
+type testServe struct {
+	url     string
+	client  *http.Client
+	quit    chan os.Signal
+	errc    chan error
+	started chan struct{}
+	release chan struct{}
+}
+
+// startServe runs app.serve on a random port with the application routes
+// plus /slow, which blocks until release is closed.
+func startServe(t *testing.T, app *application, delay, timeout time.Duration) *testServe {
+	t.Helper()
+	cfg := &config{}
+	cfg.TLS.Cert, cfg.TLS.Key = writeTestCert(t)
+	cfg.Shutdown.Delay = delay
+	cfg.Shutdown.Timeout = timeout
+
+	s := &testServe{
+		client: &http.Client{
+			Transport: &http.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: true}},
+		},
+		quit:    make(chan os.Signal, 1),
+		errc:    make(chan error, 1),
+		started: make(chan struct{}),
+		release: make(chan struct{}),
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/", app.routes())
+	mux.HandleFunc("GET /slow", func(w http.ResponseWriter, r *http.Request) {
+		close(s.started)
+		<-s.release
+		w.Write([]byte("done"))
+	})
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.url = "https://" + ln.Addr().String()
+	srv := &http.Server{Handler: mux, ErrorLog: slog.NewLogLogger(app.logger.Handler(), slog.LevelError)}
+	go func() {
+		s.errc <- app.serve(srv, ln, cfg, s.quit)
+	}()
+	return s
+}
+
+func (s *testServe) get(path string) (int, string, error) {
+	res, err := s.client.Get(s.url + path)
+	if err != nil {
+		return 0, "", err
+	}
+	defer res.Body.Close()
+	body, err := io.ReadAll(res.Body)
+	return res.StatusCode, string(body), err
+}
+
+func TestServeShutdown(t *testing.T) {
+	app := newTestApplication(t)
+	s := startServe(t, app, 200*time.Millisecond, 5*time.Second)
+
+	code, _, err := s.get("/ready")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, code, http.StatusOK)
+
+	type result struct {
+		code int
+		body string
+		err  error
+	}
+	slow := make(chan result, 1)
+	go func() {
+		code, body, err := s.get("/slow")
+		slow <- result{code, body, err}
+	}()
+	<-s.started
+
+	var backgroundDone atomic.Bool
+	app.background(context.Background(), func() {
+		time.Sleep(100 * time.Millisecond)
+		backgroundDone.Store(true)
+	})
+
+	s.quit <- syscall.SIGTERM
+
+	// During the delay the server still answers, but is no longer ready.
+	for deadline := time.Now().Add(time.Second); ; {
+		code, _, err = s.get("/ready")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if code == http.StatusServiceUnavailable || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	assert.Equal(t, code, http.StatusServiceUnavailable)
+	code, body, err := s.get("/ping")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, code, http.StatusOK)
+	assert.Equal(t, body, "OK")
+
+	// The in-flight request finishes before serve returns.
+	close(s.release)
+	res := <-slow
+	if res.err != nil {
+		t.Fatal(res.err)
+	}
+	assert.Equal(t, res.code, http.StatusOK)
+	assert.Equal(t, res.body, "done")
+
+	if err := <-s.errc; err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, backgroundDone.Load(), true)
+
+	_, _, err = s.get("/ping")
+	assert.Equal(t, err != nil, true)
+}
+
+func TestServeShutdownTimeout(t *testing.T) {
+	app := newTestApplication(t)
+	s := startServe(t, app, 0, 100*time.Millisecond)
+	defer close(s.release)
+
+	go s.get("/slow")
+	<-s.started
+
+	s.quit <- syscall.SIGINT
+	select {
+	case err := <-s.errc:
+		if err == nil {
+			t.Fatal("expected an error")
+		}
+		assert.StringContains(t, err.Error(), "shutting down server: context deadline exceeded")
+	case <-time.After(5 * time.Second):
+		t.Fatal("serve did not return after the shutdown timeout")
+	}
+}
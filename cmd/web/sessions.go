@@ -0,0 +1,124 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/MohammadLashkari/snippetbox/internal/models"
+)
+
+// sessionTouchInterval limits how often the last seen time of a session is
+// written to the database.
+const sessionTouchInterval = time.Minute
+
+// trackSession records the device and last use of logged in sessions, for
+// the list on /account/sessions. It must come after authenticate.
+func (app *application) trackSession(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if app.isAuthenticated(r) {
+			seen := app.sessionManager.GetInt64(r.Context(), "sessionSeen")
+			if time.Since(time.Unix(seen, 0)) >= sessionTouchInterval {
+				id := app.sessionManager.GetInt(r.Context(), "authenticatedUserID")
+				token := app.sessionManager.Token(r.Context())
+				if err := app.sessions.Touch(token, id, r.UserAgent(), clientIP(r)); err != nil {
+					app.serverError(w, r, err)
+					return
+				}
+				app.sessionManager.Put(r.Context(), "sessionSeen", time.Now().Unix())
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (app *application) accountSessions(w http.ResponseWriter, r *http.Request) {
+	id := app.sessionManager.GetInt(r.Context(), "authenticatedUserID")
+	sessions, err := app.sessions.ByUser(id)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	data := app.newTemplateData(r)
+	data.Sessions = sessions
+	token := app.sessionManager.Token(r.Context())
+	for _, s := range sessions {
+		if s.Token == token {
+			data.CurrentSessionID = s.ID
+		}
+	}
+	app.render(w, r, http.StatusOK, "sessions.tmpl", data)
+}
+
+func (app *application) accountSessionRevokePost(w http.ResponseWriter, r *http.Request) {
+	sessionID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil || sessionID < 1 {
+		app.notFound(w)
+		return
+	}
+	id := app.sessionManager.GetInt(r.Context(), "authenticatedUserID")
+	s, err := app.sessions.Get(sessionID, id)
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			app.notFound(w)
+		} else {
+			app.serverError(w, r, err)
+		}
+		return
+	}
+	if s.Token == app.sessionManager.Token(r.Context()) {
+		app.sessionManager.Put(r.Context(), "flash", "use log out to end the session you are using.")
+		http.Redirect(w, r, "/account/sessions", http.StatusSeeOther)
+		return
+	}
+	if err := app.revokeSession(s.Token); err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	app.audit(r, id, models.AuditSessionRevoke, fmt.Sprintf("%s from %s", s.UserAgent, s.IP))
+	app.sessionManager.Put(r.Context(), "flash", "session signed out")
+	http.Redirect(w, r, "/account/sessions", http.StatusSeeOther)
+}
+
+func (app *application) accountSessionsRevokeOthersPost(w http.ResponseWriter, r *http.Request) {
+	n, err := app.revokeOtherSessions(r)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	app.audit(r, app.sessionManager.GetInt(r.Context(), "authenticatedUserID"), models.AuditSessionRevoke,
+		fmt.Sprintf("%d other sessions", n))
+	app.sessionManager.Put(r.Context(), "flash", fmt.Sprintf("signed out of %d other sessions", n))
+	http.Redirect(w, r, "/account/sessions", http.StatusSeeOther)
+}
+
+func (app *application) revokeSession(token string) error {
+	if err := app.sessionManager.Store.Delete(token); err != nil {
+		return err
+	}
+	return app.sessions.Delete(token)
+}
+
+// revokeOtherSessions signs the current user out of every session but the
+// one making the request, and returns how many there were.
+func (app *application) revokeOtherSessions(r *http.Request) (int, error) {
+	id := app.sessionManager.GetInt(r.Context(), "authenticatedUserID")
+	sessions, err := app.sessions.ByUser(id)
+	if err != nil {
+		return 0, err
+	}
+	token := app.sessionManager.Token(r.Context())
+	n := 0
+	for _, s := range sessions {
+		if s.Token == token {
+			continue
+		}
+		if err := app.revokeSession(s.Token); err != nil {
+			return n, err
+		}
+		n++
+	}
+	return n, nil
+}
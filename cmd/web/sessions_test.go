@@ -0,0 +1,80 @@
+package main
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/MohammadLashkari/snippetbox/internal/assert"
+)
+
+// logIn logs ts's client in as user 1.
+func (ts *testServer) logIn(t *testing.T) {
+	ts.logInAs(t, "foo@example.com")
+}
+
+func (ts *testServer) logInAs(t *testing.T, email string) {
+	_, _, body := ts.get(t, "/user/login")
+	form := url.Values{}
+	form.Add("email", email)
+	form.Add("password", "password")
+	form.Add("csrf_token", extractCSRFToken(t, body))
+	code, _, _ := ts.postForm(t, "/user/login", form)
+	if code != http.StatusSeeOther {
+		t.Fatalf("login failed with status %d", code)
+	}
+}
+
+func TestAccountSessions(t *testing.T) {
+	app := newTestApplication(t)
+	laptop := newTestServer(t, app.routes())
+	defer laptop.Close()
+	phone := newTestServer(t, app.routes())
+	defer phone.Close()
+	tablet := newTestServer(t, app.routes())
+	defer tablet.Close()
+
+	for _, ts := range []*testServer{laptop, phone, tablet} {
+		ts.logIn(t)
+	}
+	// The sessions are recorded on their first request after logging in.
+	phone.get(t, "/account/view")
+	tablet.get(t, "/account/view")
+
+	code, _, body := laptop.get(t, "/account/sessions")
+	assert.Equal(t, code, http.StatusOK)
+	assert.StringContains(t, body, "This session")
+	assert.StringContains(t, body, "<form action='/account/sessions/1/revoke' method='POST'>")
+	assert.StringContains(t, body, "<form action='/account/sessions/2/revoke' method='POST'>")
+	csrfToken := extractCSRFToken(t, body)
+
+	t.Run("Revoke one", func(t *testing.T) {
+		form := url.Values{"csrf_token": {csrfToken}}
+		code, _, _ := laptop.postForm(t, "/account/sessions/1/revoke", form)
+		assert.Equal(t, code, http.StatusSeeOther)
+
+		code, header, _ := phone.get(t, "/account/view")
+		assert.Equal(t, code, http.StatusSeeOther)
+		assert.Equal(t, header.Get("Location"), "/user/login")
+		code, _, _ = tablet.get(t, "/account/view")
+		assert.Equal(t, code, http.StatusOK)
+	})
+
+	t.Run("Revoke unknown", func(t *testing.T) {
+		form := url.Values{"csrf_token": {csrfToken}}
+		code, _, _ := laptop.postForm(t, "/account/sessions/99/revoke", form)
+		assert.Equal(t, code, http.StatusNotFound)
+	})
+
+	t.Run("Revoke others", func(t *testing.T) {
+		form := url.Values{"csrf_token": {csrfToken}}
+		code, _, _ := laptop.postForm(t, "/account/sessions/revoke-others", form)
+		assert.Equal(t, code, http.StatusSeeOther)
+
+		code, _, _ = tablet.get(t, "/account/view")
+		assert.Equal(t, code, http.StatusSeeOther)
+		code, _, body := laptop.get(t, "/account/view")
+		assert.Equal(t, code, http.StatusOK)
+		assert.StringContains(t, body, "signed out of 1 other sessions")
+	})
+}
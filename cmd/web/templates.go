@@ -3,21 +3,50 @@ package main
 import (
 	"html/template"
 	"io/fs"
+	"net/url"
 	"path/filepath"
+	"strconv"
 	"time"
 
 	"github.com/MohammadLashkari/snippetbox/internal/models"
 	"github.com/MohammadLashkari/snippetbox/ui"
 )
 
+// pageMeta drives the OpenGraph and Twitter card tags in the page head.
+type pageMeta struct {
+	SiteURL     string
+	URL         string
+	Type        string
+	Title       string
+	Description string
+}
+
 type templateData struct {
-	CurrentYear     int
-	Snippet         *models.Snippet
-	Snippets        []*models.Snippet
-	User            *models.User
-	Form            any
-	Flash           string
-	IsAuthenticated bool
+	CurrentYear      int
+	Meta             pageMeta
+	Snippet          *models.Snippet
+	Snippets         []*models.Snippet
+	User             *models.User
+	Profile          *models.User
+	Author           *models.User
+	Webhooks         []*models.Webhook
+	Deliveries       []*models.WebhookDelivery
+	TOTPSecret       string
+	TwoFactorEnabled bool
+	RecoveryCodes    []string
+	Sessions         []*models.Session
+	CurrentSessionID int
+	SSOName          string
+	Passkeys         []*models.Passkey
+	Users            []*models.User
+	Lockouts         []*models.LoginLockout
+	AuditEvents      []*models.AuditEvent
+	Form             any
+	Flash            string
+	IsAuthenticated  bool
+	IsModerator      bool
+	IsAdmin          bool
+	CSRFToken        string
 }
 
 func humanDate(t time.Time) string {
@@ -27,8 +56,27 @@ func humanDate(t time.Time) string {
 	return t.UTC().Format("02 Jan 2006 at 15:04")
 }
 
+// profileURL is the address of the user's public page, under their
+// username if they have one.
+func profileURL(u *models.User) string {
+	if u.Username != "" {
+		return "/u/" + url.PathEscape(u.Username)
+	}
+	return "/user/" + strconv.Itoa(u.ID)
+}
+
+// snippetURL is the permalink of a snippet by author, which may be nil.
+func snippetURL(author *models.User, s *models.Snippet) string {
+	if author != nil && author.Username != "" {
+		return profileURL(author) + "/" + strconv.Itoa(s.ID)
+	}
+	return "/snippet/view/" + strconv.Itoa(s.ID)
+}
+
 var functions = template.FuncMap{
-	"humanDate": humanDate,
+	"humanDate":  humanDate,
+	"profileURL": profileURL,
+	"snippetURL": snippetURL,
 }
 
 func newTemplateCache() (map[string]*template.Template, error) {
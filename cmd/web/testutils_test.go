@@ -2,15 +2,22 @@ package main
 
 import (
 	"bytes"
+	"html"
 	"io"
-	"log"
+	"log/slog"
 	"net/http"
 	"net/http/cookiejar"
 	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/MohammadLashkari/snippetbox/internal/mailer"
 	"github.com/MohammadLashkari/snippetbox/internal/models/mocks"
+	"github.com/MohammadLashkari/snippetbox/internal/signing"
+	"github.com/MohammadLashkari/snippetbox/internal/webhooks"
 	"github.com/alexedwards/scs/v2"
 	"github.com/go-playground/form"
 )
@@ -25,11 +32,27 @@ func newTestApplication(t *testing.T) *application {
 	sessionManager.Lifetime = 12 * time.Hour
 	sessionManager.Cookie.Secure = true
 
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	webhookModel := &mocks.WebhookModel{}
+
 	return &application{
-		errorLog:       log.New(io.Discard, "", 0),
-		infoLog:        log.New(io.Discard, "", 0),
+		baseURL:        "https://snippetbox.test",
+		logger:         logger,
 		snippets:       &mocks.SnippetModel{},
 		users:          &mocks.UserModel{},
+		tokens:         &mocks.TokenModel{},
+		webhooks:       webhookModel,
+		twoFactor:      &mocks.TwoFactorModel{},
+		passwordResets: &mocks.PasswordResetModel{},
+		emailChanges:   &mocks.EmailChangeModel{},
+		loginAttempts:  &mocks.LoginAttemptModel{},
+		sessions:       &mocks.SessionModel{},
+		identities:     &mocks.IdentityModel{},
+		passkeys:       &mocks.PasskeyModel{},
+		auditLog:       &mocks.AuditModel{},
+		dispatcher:     webhooks.NewDispatcher(webhookModel, logger),
+		mailer:         &mailer.Log{Logger: logger},
+		signer:         &signing.Signer{Key: []byte("0123456789abcdef0123456789abcdef")},
 		templateCache:  templateCache,
 		formDecoder:    formDecoder,
 		sessionManager: sessionManager,
@@ -66,3 +89,43 @@ func (ts *testServer) get(t *testing.T, urlPath string) (int, http.Header, strin
 	bytes.TrimSpace(body)
 	return rs.StatusCode, rs.Header, string(body)
 }
+
+func (ts *testServer) request(t *testing.T, method, urlPath string, header http.Header, body io.Reader) (int, http.Header, string) {
+	req, err := http.NewRequest(method, ts.URL+urlPath, body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for key, values := range header {
+		req.Header[key] = values
+	}
+	rs, err := ts.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rs.Body.Close()
+	b, err := io.ReadAll(rs.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return rs.StatusCode, rs.Header, string(bytes.TrimSpace(b))
+}
+
+var csrfTokenRX = regexp.MustCompile(`<input type='hidden' name='csrf_token' value='(.+)'>`)
+
+func extractCSRFToken(t *testing.T, body string) string {
+	matches := csrfTokenRX.FindStringSubmatch(body)
+	if len(matches) < 2 {
+		t.Fatal("no csrf token found in body")
+	}
+	return html.UnescapeString(matches[1])
+}
+
+// postForm submits form the way a browser on the same origin would, so the
+// request passes the CSRF origin check as long as it carries a valid token.
+func (ts *testServer) postForm(t *testing.T, urlPath string, form url.Values) (int, http.Header, string) {
+	header := http.Header{
+		"Content-Type": {"application/x-www-form-urlencoded"},
+		"Origin":       {ts.URL},
+	}
+	return ts.request(t, http.MethodPost, urlPath, header, strings.NewReader(form.Encode()))
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/MohammadLashkari/snippetbox/internal/models"
+)
+
+// loginPolicy decides how long a key is blocked after a number of failed
+// logins in a row: not at all for the first few, then for a delay that
+// doubles with each failure, then for a long lockout.
+type loginPolicy struct {
+	free      int
+	lockAfter int
+	maxDelay  time.Duration
+	lockout   time.Duration
+}
+
+var (
+	accountLoginPolicy = loginPolicy{free: 3, lockAfter: 10, maxDelay: time.Minute, lockout: 15 * time.Minute}
+	ipLoginPolicy      = loginPolicy{free: 10, lockAfter: 50, maxDelay: time.Minute, lockout: 15 * time.Minute}
+)
+
+func (p loginPolicy) block(failures int) time.Duration {
+	switch {
+	case failures >= p.lockAfter:
+		return p.lockout
+	case failures <= p.free:
+		return 0
+	}
+	delay := time.Second << (failures - p.free - 1)
+	if delay > p.maxDelay {
+		delay = p.maxDelay
+	}
+	return delay
+}
+
+// Unknown email addresses are counted the same as registered ones, so
+// lockouts don't reveal which addresses have accounts.
+func loginAccountKey(email string) string {
+	return "email:" + strings.ToLower(strings.TrimSpace(email))
+}
+
+func loginIPKey(r *http.Request) string {
+	return "ip:" + clientIP(r)
+}
+
+const loginThrottledError = "too many failed login attempts. please try again later."
+
+// loginBlocked reports whether the account with email or the client is
+// blocked from logging in, setting Retry-After on w if so.
+func (app *application) loginBlocked(w http.ResponseWriter, r *http.Request, email string) (bool, error) {
+	until, err := app.loginAttempts.BlockedUntil(loginAccountKey(email), loginIPKey(r))
+	if err != nil || until.IsZero() {
+		return false, err
+	}
+	w.Header().Set("Retry-After", strconv.Itoa(int(time.Until(until).Seconds())+1))
+	return true, nil
+}
+
+// loginThrottled renders the login form with a generic error if the account
+// or the client is blocked, and reports whether it did.
+func (app *application) loginThrottled(w http.ResponseWriter, r *http.Request, form userLoginForm) bool {
+	blocked, err := app.loginBlocked(w, r, form.Email)
+	if err != nil {
+		app.serverError(w, r, err)
+		return true
+	}
+	if !blocked {
+		return false
+	}
+	form.AddNonFieldError(loginThrottledError)
+	data := app.newTemplateData(r)
+	data.Form = form
+	app.render(w, r, http.StatusTooManyRequests, "login.tmpl", data)
+	return true
+}
+
+// loginFailed counts a failed login against the account and the client,
+// blocking them as their policies say. The owner of an account that gets
+// locked out is told by email.
+func (app *application) loginFailed(r *http.Request, email string) error {
+	for _, k := range []struct {
+		key    string
+		policy loginPolicy
+	}{
+		{loginAccountKey(email), accountLoginPolicy},
+		{loginIPKey(r), ipLoginPolicy},
+	} {
+		failures, err := app.loginAttempts.Fail(k.key)
+		if err != nil {
+			return err
+		}
+		delay := k.policy.block(failures)
+		if delay == 0 {
+			continue
+		}
+		if err := app.loginAttempts.Block(k.key, time.Now().Add(delay)); err != nil {
+			return err
+		}
+		if k.policy == accountLoginPolicy && failures == k.policy.lockAfter {
+			if err := app.notifyLockout(r, email, delay); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (app *application) notifyLockout(r *http.Request, email string, lockout time.Duration) error {
+	user, err := app.users.GetByEmail(email)
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			return nil
+		}
+		return err
+	}
+	app.audit(r, user.ID, models.AuditLoginLocked, "locked for "+lockout.String())
+	app.sendEmail(r, user.Email, "lockout.tmpl", map[string]any{
+		"Name":     user.Name,
+		"Failures": accountLoginPolicy.lockAfter,
+		"IP":       clientIP(r),
+		"Lockout":  lockout.String(),
+		"ResetURL": app.baseURL + "/user/password/forgot",
+	})
+	return nil
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"bytes"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/MohammadLashkari/snippetbox/internal/assert"
+	"github.com/MohammadLashkari/snippetbox/internal/mailer"
+)
+
+func TestLoginPolicyBlock(t *testing.T) {
+	tests := []struct {
+		failures int
+		want     time.Duration
+	}{
+		{1, 0},
+		{3, 0},
+		{4, time.Second},
+		{5, 2 * time.Second},
+		{7, 8 * time.Second},
+		{9, 32 * time.Second},
+		{10, 15 * time.Minute},
+		{25, 15 * time.Minute},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, accountLoginPolicy.block(tt.failures), tt.want)
+	}
+	assert.Equal(t, ipLoginPolicy.block(17), time.Minute)
+}
+
+func TestLoginLockout(t *testing.T) {
+	app := newTestApplication(t)
+	var buf bytes.Buffer
+	app.mailer = &mailer.Log{Logger: newLogger(&buf, "text")}
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	// One short of the lockout threshold.
+	for i := 1; i < accountLoginPolicy.lockAfter; i++ {
+		app.loginAttempts.Fail(loginAccountKey("bar@example.com"))
+	}
+
+	login := func(password string) (int, http.Header, string) {
+		_, _, body := ts.get(t, "/user/login")
+		form := url.Values{}
+		form.Add("email", "bar@example.com")
+		form.Add("password", password)
+		form.Add("csrf_token", extractCSRFToken(t, body))
+		return ts.postForm(t, "/user/login", form)
+	}
+
+	code, _, body := login("wrong")
+	assert.Equal(t, code, http.StatusUnprocessableEntity)
+	assert.StringContains(t, body, "email or password is incorrect")
+
+	app.wg.Wait()
+	assert.StringContains(t, buf.String(), `to=bar@example.com subject="Your Snippetbox account has been locked"`)
+
+	code, header, body := login("password")
+	assert.Equal(t, code, http.StatusTooManyRequests)
+	assert.Equal(t, header.Get("Retry-After"), "900")
+	assert.StringContains(t, body, "too many failed login attempts. please try again later.")
+
+	lockouts, err := app.loginAttempts.Locked()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, len(lockouts), 1)
+	assert.Equal(t, lockouts[0].Key, "email:bar@example.com")
+
+	app.loginAttempts.Clear(lockouts[0].Key)
+	code, header, _ = login("password")
+	assert.Equal(t, code, http.StatusSeeOther)
+	assert.Equal(t, header.Get("Location"), "/user/login/2fa")
+}
+
+func TestTwoFactorLoginThrottle(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+	key := loginAccountKey("bar@example.com")
+
+	// Earlier failures survive a correct password while the second factor
+	// is still to be checked.
+	app.loginAttempts.Fail(key)
+	app.loginAttempts.Fail(key)
+
+	_, _, body := ts.get(t, "/user/login")
+	csrfToken := extractCSRFToken(t, body)
+	code, header, _ := ts.postForm(t, "/user/login", url.Values{
+		"email":      {"bar@example.com"},
+		"password":   {"password"},
+		"csrf_token": {csrfToken},
+	})
+	assert.Equal(t, code, http.StatusSeeOther)
+	assert.Equal(t, header.Get("Location"), "/user/login/2fa")
+
+	submit := func(code string) (int, string) {
+		status, _, body := ts.postForm(t, "/user/login/2fa", url.Values{
+			"code":       {code},
+			"csrf_token": {csrfToken},
+		})
+		return status, body
+	}
+
+	// The third failure is free, the fourth blocks the account.
+	status, body := submit("000000")
+	assert.Equal(t, status, http.StatusUnprocessableEntity)
+	assert.StringContains(t, body, "the code is invalid or has already been used")
+	status, _ = submit("111111")
+	assert.Equal(t, status, http.StatusUnprocessableEntity)
+
+	status, body = submit("aaaaa-bbbbb")
+	assert.Equal(t, status, http.StatusTooManyRequests)
+	assert.StringContains(t, body, "too many failed login attempts. please try again later.")
+
+	// Once the block is over, a good code logs in and clears the failures.
+	app.loginAttempts.Clear(key)
+	app.loginAttempts.Fail(key)
+	status, _ = submit("aaaaa-bbbbb")
+	assert.Equal(t, status, http.StatusSeeOther)
+	failures, err := app.loginAttempts.Fail(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, failures, 1)
+}
@@ -0,0 +1,290 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/MohammadLashkari/snippetbox/internal/models"
+	"github.com/MohammadLashkari/snippetbox/internal/totp"
+	"github.com/MohammadLashkari/snippetbox/internal/validator"
+	"rsc.io/qr"
+)
+
+// A user has this long after entering their password to enter a code.
+const twoFactorTimeout = 5 * time.Minute
+
+type twoFactorCodeForm struct {
+	Code                string `form:"code"`
+	validator.Validator `form:"-"`
+}
+
+type twoFactorDisableForm struct {
+	Password            string `form:"password"`
+	validator.Validator `form:"-"`
+}
+
+// pendingTwoFactorUserID returns the user who has entered their password
+// but not yet their second factor, or 0 if there is none or they took too
+// long.
+func (app *application) pendingTwoFactorUserID(r *http.Request) int {
+	started := app.sessionManager.GetInt64(r.Context(), "twoFactorStarted")
+	if time.Since(time.Unix(started, 0)) > twoFactorTimeout {
+		return 0
+	}
+	return app.sessionManager.GetInt(r.Context(), "twoFactorUserID")
+}
+
+// startTwoFactor sends a user who has passed the first factor, named by
+// method, on to enter their second one. The session token must already
+// have been renewed.
+func (app *application) startTwoFactor(w http.ResponseWriter, r *http.Request, id int, method string) {
+	app.sessionManager.Put(r.Context(), "twoFactorUserID", id)
+	app.sessionManager.Put(r.Context(), "twoFactorMethod", method)
+	app.sessionManager.Put(r.Context(), "twoFactorStarted", time.Now().Unix())
+	http.Redirect(w, r, "/user/login/2fa", http.StatusSeeOther)
+}
+
+func (app *application) userLoginTwoFactor(w http.ResponseWriter, r *http.Request) {
+	if app.pendingTwoFactorUserID(r) == 0 {
+		http.Redirect(w, r, "/user/login", http.StatusSeeOther)
+		return
+	}
+	data := app.newTemplateData(r)
+	data.Form = twoFactorCodeForm{}
+	app.render(w, r, http.StatusOK, "login2fa.tmpl", data)
+}
+
+func (app *application) userLoginTwoFactorPost(w http.ResponseWriter, r *http.Request) {
+	id := app.pendingTwoFactorUserID(r)
+	if id == 0 {
+		app.sessionManager.Put(r.Context(), "flash", "your login has expired. please login again.")
+		http.Redirect(w, r, "/user/login", http.StatusSeeOther)
+		return
+	}
+
+	var form twoFactorCodeForm
+	if err := app.decodePostForm(r, &form); err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+	form.CheckField(validator.NotBlank(form.Code), "code", "this field cannot be empty")
+
+	// Wrong codes count against the account and client just like wrong
+	// passwords, so knowing the password does not allow endless guessing.
+	user, err := app.users.Get(id)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	blocked, err := app.loginBlocked(w, r, user.Email)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	if blocked {
+		form.AddNonFieldError(loginThrottledError)
+		data := app.newTemplateData(r)
+		data.Form = form
+		app.render(w, r, http.StatusTooManyRequests, "login2fa.tmpl", data)
+		return
+	}
+
+	if form.Valid() {
+		ok, err := app.checkTwoFactorCode(id, form.Code)
+		if err != nil {
+			app.serverError(w, r, err)
+			return
+		}
+		if !ok {
+			if err := app.loginFailed(r, user.Email); err != nil {
+				app.serverError(w, r, err)
+				return
+			}
+			app.audit(r, id, models.AuditLoginFailed, "two-factor code")
+			form.AddNonFieldError("the code is invalid or has already been used")
+		}
+	}
+	if !form.Valid() {
+		data := app.newTemplateData(r)
+		data.Form = form
+		app.render(w, r, http.StatusUnprocessableEntity, "login2fa.tmpl", data)
+		return
+	}
+
+	if err := app.loginAttempts.Clear(loginAccountKey(user.Email)); err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	if err := app.sessionManager.RenewToken(r.Context()); err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	app.sessionManager.Remove(r.Context(), "twoFactorUserID")
+	app.sessionManager.Remove(r.Context(), "twoFactorStarted")
+	method := app.sessionManager.PopString(r.Context(), "twoFactorMethod")
+	app.logIn(w, r, id, method+" and two-factor code")
+}
+
+// checkTwoFactorCode accepts either a current TOTP code that has not been
+// used before or one of the user's recovery codes.
+func (app *application) checkTwoFactorCode(id int, code string) (bool, error) {
+	tf, err := app.twoFactor.Get(id)
+	if err != nil {
+		return false, err
+	}
+	if counter, ok := totp.Validate(tf.Secret, code, time.Now()); ok {
+		return app.twoFactor.UseCounter(id, counter)
+	}
+	return app.twoFactor.UseRecoveryCode(id, code)
+}
+
+func (app *application) accountTwoFactor(w http.ResponseWriter, r *http.Request) {
+	data, err := app.newTwoFactorTemplateData(r)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	if data.TwoFactorEnabled {
+		data.Form = twoFactorDisableForm{}
+	} else {
+		data.Form = twoFactorCodeForm{}
+	}
+	app.render(w, r, http.StatusOK, "twofactor.tmpl", data)
+}
+
+func (app *application) accountTwoFactorQRCode(w http.ResponseWriter, r *http.Request) {
+	secret := app.sessionManager.GetString(r.Context(), "totpEnrollSecret")
+	if secret == "" {
+		app.notFound(w)
+		return
+	}
+	id := app.sessionManager.GetInt(r.Context(), "authenticatedUserID")
+	user, err := app.users.Get(id)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	code, err := qr.Encode(totp.URI("Snippetbox", user.Email, secret), qr.M)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	code.Scale = 6
+	w.Header().Set("Content-Type", "image/png")
+	w.Write(code.PNG())
+}
+
+func (app *application) accountTwoFactorEnablePost(w http.ResponseWriter, r *http.Request) {
+	secret := app.sessionManager.GetString(r.Context(), "totpEnrollSecret")
+	if secret == "" {
+		http.Redirect(w, r, "/account/2fa", http.StatusSeeOther)
+		return
+	}
+
+	var form twoFactorCodeForm
+	if err := app.decodePostForm(r, &form); err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+	form.CheckField(validator.NotBlank(form.Code), "code", "this field cannot be empty")
+	counter, ok := totp.Validate(secret, form.Code, time.Now())
+	form.CheckField(ok, "code", "the code is not valid, check the time on your device")
+	if !form.Valid() {
+		data, err := app.newTwoFactorTemplateData(r)
+		if err != nil {
+			app.serverError(w, r, err)
+			return
+		}
+		data.Form = form
+		app.render(w, r, http.StatusUnprocessableEntity, "twofactor.tmpl", data)
+		return
+	}
+
+	id := app.sessionManager.GetInt(r.Context(), "authenticatedUserID")
+	codes, err := models.NewRecoveryCodes(10)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	if err := app.twoFactor.Enable(id, secret, codes); err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	if _, err := app.twoFactor.UseCounter(id, counter); err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	app.sessionManager.Remove(r.Context(), "totpEnrollSecret")
+	app.audit(r, id, models.AuditTwoFactorEnable, "")
+
+	// The recovery codes are only ever shown on this response.
+	data := app.newTemplateData(r)
+	data.TwoFactorEnabled = true
+	data.RecoveryCodes = codes
+	data.Form = twoFactorDisableForm{}
+	app.render(w, r, http.StatusOK, "twofactor.tmpl", data)
+}
+
+func (app *application) accountTwoFactorDisablePost(w http.ResponseWriter, r *http.Request) {
+	var form twoFactorDisableForm
+	if err := app.decodePostForm(r, &form); err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+	form.CheckField(validator.NotBlank(form.Password), "password", "this field cannot be empty")
+
+	id := app.sessionManager.GetInt(r.Context(), "authenticatedUserID")
+	if form.Valid() {
+		err := app.users.CheckPassword(id, form.Password)
+		if err != nil {
+			if !errors.Is(err, models.ErrInvalidCredentials) {
+				app.serverError(w, r, err)
+				return
+			}
+			form.AddFieldError("password", "password is incorrect")
+		}
+	}
+	if !form.Valid() {
+		data := app.newTemplateData(r)
+		data.TwoFactorEnabled = true
+		data.Form = form
+		app.render(w, r, http.StatusUnprocessableEntity, "twofactor.tmpl", data)
+		return
+	}
+
+	if err := app.twoFactor.Disable(id); err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	app.audit(r, id, models.AuditTwoFactorDisable, "")
+	app.sessionManager.Put(r.Context(), "flash", "two-factor authentication has been disabled")
+	http.Redirect(w, r, "/account/view", http.StatusSeeOther)
+}
+
+// newTwoFactorTemplateData reports whether two-factor authentication is
+// enabled and, if not, starts enrollment with a secret kept in the session
+// until the user confirms it.
+func (app *application) newTwoFactorTemplateData(r *http.Request) (*templateData, error) {
+	data := app.newTemplateData(r)
+	id := app.sessionManager.GetInt(r.Context(), "authenticatedUserID")
+	_, err := app.twoFactor.Get(id)
+	if err == nil {
+		data.TwoFactorEnabled = true
+		return data, nil
+	}
+	if !errors.Is(err, models.ErrNoRecord) {
+		return nil, err
+	}
+
+	secret := app.sessionManager.GetString(r.Context(), "totpEnrollSecret")
+	if secret == "" {
+		secret, err = totp.GenerateSecret()
+		if err != nil {
+			return nil, err
+		}
+		app.sessionManager.Put(r.Context(), "totpEnrollSecret", secret)
+	}
+	data.TOTPSecret = secret
+	return data, nil
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/MohammadLashkari/snippetbox/internal/assert"
+	"github.com/MohammadLashkari/snippetbox/internal/models/mocks"
+	"github.com/MohammadLashkari/snippetbox/internal/totp"
+)
+
+func TestUserLoginTwoFactor(t *testing.T) {
+	code, err := totp.Code(mocks.MockTOTPSecret, totp.Counter(time.Now()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name         string
+		code         string
+		wantCode     int
+		wantLocation string
+	}{
+		{"Valid code", code, http.StatusSeeOther, "/account/view"},
+		{"Recovery code", "aaaaa-bbbbb", http.StatusSeeOther, "/account/view"},
+		{"Wrong code", "000000", http.StatusUnprocessableEntity, ""},
+		{"Empty code", "", http.StatusUnprocessableEntity, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := newTestApplication(t)
+			ts := newTestServer(t, app.routes())
+			defer ts.Close()
+
+			_, _, body := ts.get(t, "/user/login")
+			csrfToken := extractCSRFToken(t, body)
+			status, header, _ := ts.postForm(t, "/user/login", url.Values{
+				"email":      {"bar@example.com"},
+				"password":   {"password"},
+				"csrf_token": {csrfToken},
+			})
+			assert.Equal(t, status, http.StatusSeeOther)
+			assert.Equal(t, header.Get("Location"), "/user/login/2fa")
+
+			// The password alone must not be enough to log in. This also
+			// makes /account/view the page to return to after logging in.
+			status, _, _ = ts.get(t, "/account/view")
+			assert.Equal(t, status, http.StatusSeeOther)
+
+			status, header, _ = ts.postForm(t, "/user/login/2fa", url.Values{
+				"code":       {tt.code},
+				"csrf_token": {csrfToken},
+			})
+			assert.Equal(t, status, tt.wantCode)
+			assert.Equal(t, header.Get("Location"), tt.wantLocation)
+
+			status, _, _ = ts.get(t, "/account/view")
+			if tt.wantLocation != "" {
+				assert.Equal(t, status, http.StatusOK)
+			} else {
+				assert.Equal(t, status, http.StatusSeeOther)
+			}
+		})
+	}
+}
+
+func TestUserLoginTwoFactorWithoutPassword(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	status, header, _ := ts.get(t, "/user/login/2fa")
+	assert.Equal(t, status, http.StatusSeeOther)
+	assert.Equal(t, header.Get("Location"), "/user/login")
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/MohammadLashkari/snippetbox/internal/models"
+)
+
+const (
+	verifyEmailPurpose = "verify-email"
+	verifyEmailTTL     = 48 * time.Hour
+)
+
+// sendVerificationEmail mails user a signed link to /user/verify in the
+// background. The link carries the address it was sent to, so it stops
+// working if the user changes their email in the meantime.
+func (app *application) sendVerificationEmail(r *http.Request, user *models.User) {
+	token := app.signer.Sign(verifyEmailPurpose, fmt.Sprintf("%d:%s", user.ID, user.Email), verifyEmailTTL)
+	data := map[string]any{
+		"Name":    user.Name,
+		"URL":     app.baseURL + "/user/verify?token=" + url.QueryEscape(token),
+		"Expires": "48 hours",
+	}
+	app.sendEmail(r, user.Email, "verify.tmpl", data)
+}
+
+func (app *application) userVerify(w http.ResponseWriter, r *http.Request) {
+	payload, err := app.signer.Verify(verifyEmailPurpose, r.URL.Query().Get("token"))
+	if err != nil {
+		app.sessionManager.Put(r.Context(), "flash", "that verification link is invalid or has expired.")
+		http.Redirect(w, r, "/account/view", http.StatusSeeOther)
+		return
+	}
+	rawID, email, _ := strings.Cut(payload, ":")
+	id, err := strconv.Atoi(rawID)
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	if err := app.users.SetVerified(id, email); err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			app.sessionManager.Put(r.Context(), "flash", "that verification link has already been used.")
+			http.Redirect(w, r, "/account/view", http.StatusSeeOther)
+		} else {
+			app.serverError(w, r, err)
+		}
+		return
+	}
+	app.sessionManager.Put(r.Context(), "flash", "your email address has been verified.")
+	http.Redirect(w, r, "/account/view", http.StatusSeeOther)
+}
+
+func (app *application) accountVerifyResendPost(w http.ResponseWriter, r *http.Request) {
+	id := app.sessionManager.GetInt(r.Context(), "authenticatedUserID")
+	user, err := app.users.Get(id)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	if !user.Verified {
+		app.sendVerificationEmail(r, user)
+		app.sessionManager.Put(r.Context(), "flash", "we've sent you a new verification email.")
+	}
+	http.Redirect(w, r, "/account/view", http.StatusSeeOther)
+}
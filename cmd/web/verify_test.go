@@ -0,0 +1,121 @@
+package main
+
+import (
+	"bytes"
+	"net/http"
+	"net/url"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/MohammadLashkari/snippetbox/internal/assert"
+	"github.com/MohammadLashkari/snippetbox/internal/mailer"
+)
+
+var verifyLinkRX = regexp.MustCompile(`https://[^\s\\"]+/user/verify\?token=[^\s\\"]+`)
+
+func TestSignupSendsVerificationEmail(t *testing.T) {
+	app := newTestApplication(t)
+	var buf bytes.Buffer
+	app.mailer = &mailer.Log{Logger: newLogger(&buf, "text")}
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	_, _, body := ts.get(t, "/user/signup")
+	form := url.Values{}
+	form.Add("name", "Bob")
+	form.Add("email", "bob@example.com")
+	form.Add("password", "validPa$$word")
+	form.Add("csrf_token", extractCSRFToken(t, body))
+	code, _, _ := ts.postForm(t, "/user/signup", form)
+	assert.Equal(t, code, http.StatusSeeOther)
+
+	app.wg.Wait()
+	assert.StringContains(t, buf.String(), `to=bob@example.com subject="Verify your Snippetbox email address"`)
+	// The log line is tagged with the ID of the signup request.
+	assert.Equal(t, regexp.MustCompile(`msg=email .*request_id=\w+`).MatchString(buf.String()), true)
+	link := verifyLinkRX.FindString(buf.String())
+	if link == "" {
+		t.Fatalf("no verification link in %q", buf.String())
+	}
+
+	u, err := url.Parse(link)
+	if err != nil {
+		t.Fatal(err)
+	}
+	payload, err := app.signer.Verify(verifyEmailPurpose, u.Query().Get("token"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, payload, "4:bob@example.com")
+}
+
+func TestUserVerify(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	tests := []struct {
+		name      string
+		token     string
+		wantFlash string
+	}{
+		{
+			name:      "Valid",
+			token:     app.signer.Sign(verifyEmailPurpose, "3:baz@example.com", time.Hour),
+			wantFlash: "your email address has been verified.",
+		},
+		{
+			name:      "Email changed",
+			token:     app.signer.Sign(verifyEmailPurpose, "3:old@example.com", time.Hour),
+			wantFlash: "that verification link has already been used.",
+		},
+		{
+			name:      "Expired",
+			token:     app.signer.Sign(verifyEmailPurpose, "3:baz@example.com", -time.Hour),
+			wantFlash: "that verification link is invalid or has expired.",
+		},
+		{
+			name:      "Wrong purpose",
+			token:     app.signer.Sign("reset-password", "3:baz@example.com", time.Hour),
+			wantFlash: "that verification link is invalid or has expired.",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, header, _ := ts.get(t, "/user/verify?token="+url.QueryEscape(tt.token))
+			assert.Equal(t, code, http.StatusSeeOther)
+			assert.Equal(t, header.Get("Location"), "/account/view")
+
+			// The flash is shown on the next page that renders.
+			_, _, body := ts.get(t, "/about")
+			assert.StringContains(t, body, tt.wantFlash)
+		})
+	}
+}
+
+func TestRequireVerified(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	_, _, body := ts.get(t, "/user/login")
+	form := url.Values{}
+	form.Add("email", "baz@example.com")
+	form.Add("password", "password")
+	form.Add("csrf_token", extractCSRFToken(t, body))
+	ts.postForm(t, "/user/login", form)
+
+	for _, path := range []string{"/snippet/create", "/account/import"} {
+		t.Run(path, func(t *testing.T) {
+			code, header, _ := ts.get(t, path)
+			assert.Equal(t, code, http.StatusSeeOther)
+			assert.Equal(t, header.Get("Location"), "/account/view")
+		})
+	}
+
+	_, _, body = ts.get(t, "/account/view")
+	assert.StringContains(t, body, "please verify your email address before creating snippets.")
+	assert.StringContains(t, body, "<form action='/account/verify/resend' method='POST'>")
+}
@@ -3,15 +3,19 @@ module github.com/MohammadLashkari/snippetbox
 go 1.22.4
 
 require (
+	github.com/BurntSushi/toml v1.4.0
 	github.com/alexedwards/scs/mysqlstore v0.0.0-20240316134038-7e11d57e8885
 	github.com/alexedwards/scs/v2 v2.8.0
 	github.com/go-playground/form v3.1.4+incompatible
 	github.com/go-sql-driver/mysql v1.8.1
 	github.com/justinas/alice v1.2.0
+	github.com/justinas/nosurf v1.2.0
 	golang.org/x/crypto v0.24.0
+	rsc.io/qr v0.2.0
 )
 
 require (
 	filippo.io/edwards25519 v1.1.0 // indirect
+	golang.org/x/sys v0.21.0 // indirect
 	gopkg.in/go-playground/assert.v1 v1.2.1 // indirect
 )
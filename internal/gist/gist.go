@@ -0,0 +1,79 @@
+// Package gist holds the request and response types of the GitHub Gist
+// compatible API. They are shared by the server and the command-line client.
+package gist
+
+import (
+	"fmt"
+	"time"
+)
+
+// DefaultFilename is the name given to a snippet's single file. Snippets
+// have no filenames of their own, so names sent by clients are not kept.
+const DefaultFilename = "snippet.txt"
+
+// DocumentationURL is returned in every error body.
+const DocumentationURL = "https://github.com/MohammadLashkari/snippetbox#gist-api"
+
+type Owner struct {
+	ID    int    `json:"id"`
+	Login string `json:"login"`
+}
+
+type File struct {
+	Filename string `json:"filename"`
+	Type     string `json:"type"`
+	Language string `json:"language"`
+	RawURL   string `json:"raw_url"`
+	Size     int    `json:"size"`
+	Content  string `json:"content,omitempty"`
+}
+
+type Gist struct {
+	ID          string           `json:"id"`
+	URL         string           `json:"url"`
+	HTMLURL     string           `json:"html_url"`
+	Files       map[string]*File `json:"files"`
+	Public      bool             `json:"public"`
+	CreatedAt   time.Time        `json:"created_at"`
+	UpdatedAt   time.Time        `json:"updated_at"`
+	Description string           `json:"description"`
+	Owner       *Owner           `json:"owner,omitempty"`
+}
+
+type FileRequest struct {
+	Filename string  `json:"filename,omitempty"`
+	Content  *string `json:"content,omitempty"`
+}
+
+// Request is the body of a create or update call. A nil entry in Files
+// asks for that file to be deleted, which a single-file snippet cannot do.
+type Request struct {
+	Description *string                 `json:"description,omitempty"`
+	Public      *bool                   `json:"public,omitempty"`
+	Files       map[string]*FileRequest `json:"files"`
+}
+
+type FieldError struct {
+	Resource string `json:"resource"`
+	Field    string `json:"field"`
+	Code     string `json:"code"`
+	Message  string `json:"message,omitempty"`
+}
+
+type Error struct {
+	Message          string       `json:"message"`
+	Errors           []FieldError `json:"errors,omitempty"`
+	DocumentationURL string       `json:"documentation_url"`
+}
+
+func (e *Error) Error() string {
+	msg := e.Message
+	for _, fe := range e.Errors {
+		if fe.Message != "" {
+			msg += fmt.Sprintf("; %s: %s", fe.Field, fe.Message)
+		} else {
+			msg += fmt.Sprintf("; %s: %s", fe.Field, fe.Code)
+		}
+	}
+	return msg
+}
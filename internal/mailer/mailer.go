@@ -0,0 +1,215 @@
+// Package mailer renders emails from templates and sends them through SMTP,
+// or writes them to files or a log during development and tests.
+package mailer
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/hex"
+	"fmt"
+	htmltemplate "html/template"
+	"io/fs"
+	"log/slog"
+	"mime/quotedprintable"
+	"net"
+	"net/smtp"
+	"os"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+)
+
+type Message struct {
+	To        string
+	Subject   string
+	PlainBody string
+	HTMLBody  string
+}
+
+// Mailer sends messages. ctx carries request-scoped values, such as the
+// request ID that log lines are tagged with.
+type Mailer interface {
+	Send(ctx context.Context, msg *Message) error
+}
+
+// Render executes the "subject", "plainBody" and "htmlBody" templates
+// defined in the file name of fsys. The HTML body is escaped with
+// html/template, the others are plain text.
+func Render(fsys fs.FS, name string, to string, data any) (*Message, error) {
+	textTmpl, err := template.New("email").ParseFS(fsys, name)
+	if err != nil {
+		return nil, err
+	}
+	htmlTmpl, err := htmltemplate.New("email").ParseFS(fsys, name)
+	if err != nil {
+		return nil, err
+	}
+
+	msg := &Message{To: to}
+	var buf bytes.Buffer
+	if err := textTmpl.ExecuteTemplate(&buf, "subject", data); err != nil {
+		return nil, err
+	}
+	msg.Subject = strings.TrimSpace(buf.String())
+
+	buf.Reset()
+	if err := textTmpl.ExecuteTemplate(&buf, "plainBody", data); err != nil {
+		return nil, err
+	}
+	msg.PlainBody = buf.String()
+
+	buf.Reset()
+	if err := htmlTmpl.ExecuteTemplate(&buf, "htmlBody", data); err != nil {
+		return nil, err
+	}
+	msg.HTMLBody = buf.String()
+	return msg, nil
+}
+
+// Bytes formats msg as a multipart/alternative MIME message.
+func (msg *Message) Bytes(from string) ([]byte, error) {
+	b := make([]byte, 12)
+	if _, err := rand.Read(b); err != nil {
+		return nil, err
+	}
+	boundary := hex.EncodeToString(b)
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "From: %s\r\n", from)
+	fmt.Fprintf(&buf, "To: %s\r\n", msg.To)
+	fmt.Fprintf(&buf, "Subject: %s\r\n", msg.Subject)
+	fmt.Fprintf(&buf, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	fmt.Fprintf(&buf, "MIME-Version: 1.0\r\n")
+	fmt.Fprintf(&buf, "Content-Type: multipart/alternative; boundary=%q\r\n\r\n", boundary)
+
+	for _, part := range []struct{ contentType, body string }{
+		{"text/plain", msg.PlainBody},
+		{"text/html", msg.HTMLBody},
+	} {
+		fmt.Fprintf(&buf, "--%s\r\n", boundary)
+		fmt.Fprintf(&buf, "Content-Type: %s; charset=utf-8\r\n", part.contentType)
+		fmt.Fprintf(&buf, "Content-Transfer-Encoding: quoted-printable\r\n\r\n")
+		qp := quotedprintable.NewWriter(&buf)
+		if _, err := qp.Write([]byte(part.body)); err != nil {
+			return nil, err
+		}
+		if err := qp.Close(); err != nil {
+			return nil, err
+		}
+		fmt.Fprintf(&buf, "\r\n")
+	}
+	fmt.Fprintf(&buf, "--%s--\r\n", boundary)
+	return buf.Bytes(), nil
+}
+
+type SMTP struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
+	Sender   string
+	// Timeout bounds each message, from dialling the server to QUIT. It
+	// defaults to 30 seconds.
+	Timeout time.Duration
+}
+
+// Send delivers msg like smtp.SendMail, upgrading to TLS when the server
+// offers it, but gives up once ctx is done or Timeout has passed.
+func (m *SMTP) Send(ctx context.Context, msg *Message) error {
+	body, err := msg.Bytes(m.Sender)
+	if err != nil {
+		return err
+	}
+	timeout := m.Timeout
+	if timeout == 0 {
+		timeout = 30 * time.Second
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", net.JoinHostPort(m.Host, strconv.Itoa(m.Port)))
+	if err != nil {
+		return err
+	}
+	// The deadline stops reads and writes that hang, and closing the
+	// connection interrupts one in progress when ctx is cancelled.
+	deadline, _ := ctx.Deadline()
+	if err := conn.SetDeadline(deadline); err != nil {
+		conn.Close()
+		return err
+	}
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+
+	c, err := smtp.NewClient(conn, m.Host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer c.Close()
+	if ok, _ := c.Extension("STARTTLS"); ok {
+		if err := c.StartTLS(&tls.Config{ServerName: m.Host}); err != nil {
+			return err
+		}
+	}
+	if m.Username != "" {
+		if err := c.Auth(smtp.PlainAuth("", m.Username, m.Password, m.Host)); err != nil {
+			return err
+		}
+	}
+	if err := c.Mail(m.Sender); err != nil {
+		return err
+	}
+	if err := c.Rcpt(msg.To); err != nil {
+		return err
+	}
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(body); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return c.Quit()
+}
+
+// File writes each message to a new .eml file in Dir.
+type File struct {
+	Dir    string
+	Sender string
+}
+
+func (m *File) Send(ctx context.Context, msg *Message) error {
+	body, err := msg.Bytes(m.Sender)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(m.Dir, 0o700); err != nil {
+		return err
+	}
+	f, err := os.CreateTemp(m.Dir, time.Now().UTC().Format("20060102T150405")+"-*.eml")
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = f.Write(body)
+	return err
+}
+
+// Log writes the recipient, subject and plain text body of each message to
+// Logger instead of sending it.
+type Log struct {
+	Logger *slog.Logger
+}
+
+func (m *Log) Send(ctx context.Context, msg *Message) error {
+	m.Logger.InfoContext(ctx, "email", "to", msg.To, "subject", msg.Subject, "body", msg.PlainBody)
+	return nil
+}
@@ -0,0 +1,144 @@
+package mailer
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"net"
+	"net/textproto"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/MohammadLashkari/snippetbox/internal/assert"
+)
+
+var testFS = fstest.MapFS{
+	"hello.tmpl": {Data: []byte(`{{define "subject"}} Hello {{.}} {{end}}
+{{define "plainBody"}}Hi {{.}}{{end}}
+{{define "htmlBody"}}<p>Hi {{.}}</p>{{end}}`)},
+}
+
+func TestRender(t *testing.T) {
+	msg, err := Render(testFS, "hello.tmpl", "bob@example.com", "<Bob>")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, msg.To, "bob@example.com")
+	assert.Equal(t, msg.Subject, "Hello <Bob>")
+	assert.Equal(t, msg.PlainBody, "Hi <Bob>")
+	assert.Equal(t, msg.HTMLBody, "<p>Hi &lt;Bob&gt;</p>")
+}
+
+func TestMessageBytes(t *testing.T) {
+	msg := &Message{To: "bob@example.com", Subject: "Hello", PlainBody: "Hi Bob", HTMLBody: "<p>Hi Bob</p>"}
+	b, err := msg.Bytes("no-reply@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := string(b)
+	assert.StringContains(t, s, "From: no-reply@example.com\r\n")
+	assert.StringContains(t, s, "To: bob@example.com\r\n")
+	assert.StringContains(t, s, "Content-Type: multipart/alternative;")
+	assert.StringContains(t, s, "Content-Type: text/plain; charset=utf-8\r\n")
+	assert.StringContains(t, s, "Content-Type: text/html; charset=utf-8\r\n")
+	if strings.Count(s, "<p>Hi Bob</p>") != 1 {
+		t.Errorf("want the HTML body once in %q", s)
+	}
+}
+
+func TestLog(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{
+		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			if a.Key == slog.TimeKey {
+				return slog.Attr{}
+			}
+			return a
+		},
+	}))
+	m := &Log{Logger: logger}
+	if err := m.Send(context.Background(), &Message{To: "bob@example.com", Subject: "Hello", PlainBody: "Hi Bob\n"}); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, buf.String(), `level=INFO msg=email to=bob@example.com subject=Hello body="Hi Bob\n"`+"\n")
+}
+
+// smtpServer accepts one connection on a local port and passes it to serve.
+// It returns the port.
+func smtpServer(t *testing.T, serve func(*textproto.Conn)) int {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		tc := textproto.NewConn(conn)
+		defer tc.Close()
+		serve(tc)
+	}()
+	return ln.Addr().(*net.TCPAddr).Port
+}
+
+func TestSMTP(t *testing.T) {
+	received := make(chan string, 1)
+	port := smtpServer(t, func(c *textproto.Conn) {
+		c.PrintfLine("220 localhost ESMTP")
+		for {
+			line, err := c.ReadLine()
+			if err != nil {
+				return
+			}
+			switch verb, _, _ := strings.Cut(line, " "); verb {
+			case "EHLO":
+				c.PrintfLine("250 localhost")
+			case "DATA":
+				c.PrintfLine("354 go ahead")
+				data, _ := c.ReadDotBytes()
+				received <- string(data)
+				c.PrintfLine("250 ok")
+			case "QUIT":
+				c.PrintfLine("221 bye")
+				return
+			default:
+				c.PrintfLine("250 ok")
+			}
+		}
+	})
+
+	m := &SMTP{Host: "127.0.0.1", Port: port, Sender: "Snippetbox <no-reply@example.com>"}
+	err := m.Send(context.Background(), &Message{To: "bob@example.com", Subject: "Hello", PlainBody: "Hi Bob"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.StringContains(t, <-received, "Subject: Hello")
+}
+
+func TestSMTPGivesUp(t *testing.T) {
+	// The server accepts the connection but never greets the client.
+	silent := func(c *textproto.Conn) { c.ReadLine() }
+	msg := &Message{To: "bob@example.com", Subject: "Hello"}
+
+	t.Run("Timeout", func(t *testing.T) {
+		m := &SMTP{Host: "127.0.0.1", Port: smtpServer(t, silent), Timeout: 50 * time.Millisecond}
+		start := time.Now()
+		err := m.Send(context.Background(), msg)
+		assert.Equal(t, err != nil, true)
+		assert.Equal(t, time.Since(start) < 5*time.Second, true)
+	})
+
+	t.Run("Cancelled", func(t *testing.T) {
+		m := &SMTP{Host: "127.0.0.1", Port: smtpServer(t, silent)}
+		ctx, cancel := context.WithCancel(context.Background())
+		time.AfterFunc(50*time.Millisecond, cancel)
+		start := time.Now()
+		err := m.Send(ctx, msg)
+		assert.Equal(t, err != nil, true)
+		assert.Equal(t, time.Since(start) < 5*time.Second, true)
+	})
+}
@@ -0,0 +1,107 @@
+package models
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base32"
+	"errors"
+	"time"
+)
+
+// EmailChange is a change of a user's email address that waits for the
+// new address to be confirmed.
+type EmailChange struct {
+	UserID   int
+	OldEmail string
+	NewEmail string
+	Expires  time.Time
+}
+
+type EmailChangeModelInterface interface {
+	New(userID int, oldEmail, newEmail string, ttl time.Duration) (string, error)
+	Consume(plaintext string) (*EmailChange, error)
+	Pending(userID int) (*EmailChange, error)
+	Cancel(userID int) error
+}
+
+type EmailChangeModel struct {
+	DB *sql.DB
+}
+
+// New records a pending change of the user's email address that expires
+// after ttl and returns the token that confirms it. Any change the user
+// asked for before is cancelled, so only the latest link works. As with
+// password resets, only the SHA-256 hash of the token is stored.
+func (m *EmailChangeModel) New(userID int, oldEmail, newEmail string, ttl time.Duration) (string, error) {
+	randomBytes := make([]byte, 16)
+	if _, err := rand.Read(randomBytes); err != nil {
+		return "", err
+	}
+	plaintext := base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(randomBytes)
+	hash := sha256.Sum256([]byte(plaintext))
+
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return "", err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`DELETE FROM email_changes WHERE user_id = ?`, userID); err != nil {
+		return "", err
+	}
+	query := `INSERT INTO email_changes (hash, user_id, old_email, new_email, expires)
+    VALUES (?, ?, ?, ?, DATE_ADD(UTC_TIMESTAMP(), INTERVAL ? SECOND))`
+	if _, err := tx.Exec(query, hash[:], userID, oldEmail, newEmail, int(ttl.Seconds())); err != nil {
+		return "", err
+	}
+	return plaintext, tx.Commit()
+}
+
+// Consume returns the unexpired change a token confirms and deletes every
+// pending change of that user, so each link works only once.
+func (m *EmailChangeModel) Consume(plaintext string) (*EmailChange, error) {
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	hash := sha256.Sum256([]byte(plaintext))
+	query := `SELECT user_id, old_email, new_email, expires FROM email_changes
+    WHERE hash = ? AND expires > UTC_TIMESTAMP() FOR UPDATE`
+	c := &EmailChange{}
+	err = tx.QueryRow(query, hash[:]).Scan(&c.UserID, &c.OldEmail, &c.NewEmail, &c.Expires)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNoRecord
+		}
+		return nil, err
+	}
+	if _, err := tx.Exec(`DELETE FROM email_changes WHERE user_id = ?`, c.UserID); err != nil {
+		return nil, err
+	}
+	return c, tx.Commit()
+}
+
+// Pending returns the user's unexpired change, or ErrNoRecord if there is
+// none.
+func (m *EmailChangeModel) Pending(userID int) (*EmailChange, error) {
+	query := `SELECT user_id, old_email, new_email, expires FROM email_changes
+    WHERE user_id = ? AND expires > UTC_TIMESTAMP()`
+	c := &EmailChange{}
+	err := m.DB.QueryRow(query, userID).Scan(&c.UserID, &c.OldEmail, &c.NewEmail, &c.Expires)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNoRecord
+		}
+		return nil, err
+	}
+	return c, nil
+}
+
+// Cancel deletes the user's pending change, if any, revoking its link.
+func (m *EmailChangeModel) Cancel(userID int) error {
+	_, err := m.DB.Exec(`DELETE FROM email_changes WHERE user_id = ?`, userID)
+	return err
+}
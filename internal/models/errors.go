@@ -1,9 +1,25 @@
 package models
 
-import "errors"
+import (
+	"database/sql"
+	"errors"
+)
 
 var (
 	ErrNoRecord           = errors.New("models: no matching record found")
 	ErrInvalidCredentials = errors.New("models: invalid credentials")
 	ErrDuplicateEmail     = errors.New("models: duplicate email")
+	ErrDuplicateUsername  = errors.New("models: duplicate username")
 )
+
+// checkAffected maps an UPDATE or DELETE that touched no rows to ErrNoRecord.
+func checkAffected(result sql.Result) error {
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrNoRecord
+	}
+	return nil
+}
@@ -0,0 +1,69 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+)
+
+type Identity struct {
+	UserID  int
+	Issuer  string
+	Subject string
+	Created time.Time
+}
+
+type IdentityModelInterface interface {
+	UserID(issuer, subject string) (int, error)
+	Insert(userID int, issuer, subject string) error
+	ByUser(userID int) ([]*Identity, error)
+}
+
+// IdentityModel links users to their accounts at OpenID Connect providers,
+// identified by the provider's issuer URL and its subject for the user.
+type IdentityModel struct {
+	DB *sql.DB
+}
+
+func (m *IdentityModel) UserID(issuer, subject string) (int, error) {
+	query := `SELECT user_id FROM user_identities WHERE issuer = ? AND subject = ?`
+	var userID int
+	err := m.DB.QueryRow(query, issuer, subject).Scan(&userID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, ErrNoRecord
+		}
+		return 0, err
+	}
+	return userID, nil
+}
+
+func (m *IdentityModel) Insert(userID int, issuer, subject string) error {
+	query := `INSERT INTO user_identities (user_id, issuer, subject, created)
+    VALUES (?, ?, ?, UTC_TIMESTAMP())`
+	_, err := m.DB.Exec(query, userID, issuer, subject)
+	return err
+}
+
+func (m *IdentityModel) ByUser(userID int) ([]*Identity, error) {
+	query := `SELECT user_id, issuer, subject, created FROM user_identities
+    WHERE user_id = ? ORDER BY id`
+	rows, err := m.DB.Query(query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	identities := []*Identity{}
+	for rows.Next() {
+		var i Identity
+		if err := rows.Scan(&i.UserID, &i.Issuer, &i.Subject, &i.Created); err != nil {
+			return nil, err
+		}
+		identities = append(identities, &i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return identities, nil
+}
@@ -0,0 +1,107 @@
+package models
+
+import (
+	"database/sql"
+	"strings"
+	"time"
+)
+
+// LoginLockout is a login attempt key that is currently blocked.
+type LoginLockout struct {
+	Key          string
+	Failures     int
+	BlockedUntil time.Time
+}
+
+type LoginAttemptModelInterface interface {
+	Fail(key string) (int, error)
+	Block(key string, until time.Time) error
+	BlockedUntil(keys ...string) (time.Time, error)
+	Clear(key string) error
+	Locked() ([]*LoginLockout, error)
+}
+
+// LoginAttemptModel counts failed logins by key, such as an account's email
+// address or a client IP. It is stored in MySQL so blocks outlive restarts.
+type LoginAttemptModel struct {
+	DB *sql.DB
+}
+
+// Fail records a failed login for key and returns the number of failures in
+// a row. The count starts again once a key has had no failures for a day.
+func (m *LoginAttemptModel) Fail(key string) (int, error) {
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	query := `INSERT INTO login_attempts (attempt_key, failures, updated)
+    VALUES (?, 1, UTC_TIMESTAMP())
+    ON DUPLICATE KEY UPDATE
+    failures = IF(updated < UTC_TIMESTAMP() - INTERVAL 1 DAY, 1, failures + 1),
+    updated = UTC_TIMESTAMP()`
+	if _, err := tx.Exec(query, key); err != nil {
+		return 0, err
+	}
+	var failures int
+	err = tx.QueryRow(`SELECT failures FROM login_attempts WHERE attempt_key = ?`, key).Scan(&failures)
+	if err != nil {
+		return 0, err
+	}
+	return failures, tx.Commit()
+}
+
+// Block blocks key until the given time. Fail has already created its row,
+// and MySQL counts a row set to the value it had as unchanged, so the number
+// of affected rows is not checked.
+func (m *LoginAttemptModel) Block(key string, until time.Time) error {
+	query := `UPDATE login_attempts SET blocked_until = ? WHERE attempt_key = ?`
+	_, err := m.DB.Exec(query, until.UTC(), key)
+	return err
+}
+
+// BlockedUntil returns the latest time any of keys is blocked until, or the
+// zero time if none of them is blocked.
+func (m *LoginAttemptModel) BlockedUntil(keys ...string) (time.Time, error) {
+	if len(keys) == 0 {
+		return time.Time{}, nil
+	}
+	args := make([]any, len(keys))
+	for i, key := range keys {
+		args[i] = key
+	}
+	query := `SELECT MAX(blocked_until) FROM login_attempts
+    WHERE attempt_key IN (?` + strings.Repeat(", ?", len(keys)-1) + `) AND blocked_until > UTC_TIMESTAMP()`
+	var until sql.NullTime
+	if err := m.DB.QueryRow(query, args...).Scan(&until); err != nil {
+		return time.Time{}, err
+	}
+	return until.Time, nil
+}
+
+// Clear forgets the failures of key, lifting any block.
+func (m *LoginAttemptModel) Clear(key string) error {
+	_, err := m.DB.Exec(`DELETE FROM login_attempts WHERE attempt_key = ?`, key)
+	return err
+}
+
+func (m *LoginAttemptModel) Locked() ([]*LoginLockout, error) {
+	query := `SELECT attempt_key, failures, blocked_until FROM login_attempts
+    WHERE blocked_until > UTC_TIMESTAMP() ORDER BY blocked_until DESC`
+	rows, err := m.DB.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	lockouts := []*LoginLockout{}
+	for rows.Next() {
+		l := &LoginLockout{}
+		if err := rows.Scan(&l.Key, &l.Failures, &l.BlockedUntil); err != nil {
+			return nil, err
+		}
+		lockouts = append(lockouts, l)
+	}
+	return lockouts, rows.Err()
+}
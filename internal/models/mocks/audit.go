@@ -0,0 +1,42 @@
+package mocks
+
+import (
+	"sync"
+	"time"
+
+	"github.com/MohammadLashkari/snippetbox/internal/models"
+)
+
+// AuditModel keeps the audit log in memory so tests can check what was
+// recorded.
+type AuditModel struct {
+	mu     sync.Mutex
+	events []*models.AuditEvent
+}
+
+func (m *AuditModel) Insert(e *models.AuditEvent) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	stored := *e
+	stored.ID = int64(len(m.events) + 1)
+	stored.Created = time.Now()
+	m.events = append(m.events, &stored)
+	return nil
+}
+
+func (m *AuditModel) Search(f models.AuditFilter) ([]*models.AuditEvent, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	events := []*models.AuditEvent{}
+	for i := len(m.events) - 1; i >= 0 && (f.Limit == 0 || len(events) < f.Limit); i-- {
+		e := m.events[i]
+		if (f.UserID != 0 && e.UserID != f.UserID) || (f.ActorID != 0 && e.ActorID != f.ActorID) {
+			continue
+		}
+		if (f.Event != "" && e.Event != f.Event) || (f.IP != "" && e.IP != f.IP) {
+			continue
+		}
+		events = append(events, e)
+	}
+	return events, nil
+}
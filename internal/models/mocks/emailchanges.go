@@ -0,0 +1,72 @@
+package mocks
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/MohammadLashkari/snippetbox/internal/models"
+)
+
+// EmailChangeModel keeps pending changes in memory so tests can follow,
+// replace and cancel them.
+type EmailChangeModel struct {
+	mu      sync.Mutex
+	next    int
+	pending map[string]models.EmailChange
+}
+
+func (m *EmailChangeModel) New(userID int, oldEmail, newEmail string, ttl time.Duration) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.pending == nil {
+		m.pending = map[string]models.EmailChange{}
+	}
+	m.cancel(userID)
+	m.next++
+	plaintext := fmt.Sprintf("email-change-%d", m.next)
+	m.pending[plaintext] = models.EmailChange{
+		UserID:   userID,
+		OldEmail: oldEmail,
+		NewEmail: newEmail,
+		Expires:  time.Now().Add(ttl),
+	}
+	return plaintext, nil
+}
+
+func (m *EmailChangeModel) Consume(plaintext string) (*models.EmailChange, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	c, ok := m.pending[plaintext]
+	if !ok || time.Now().After(c.Expires) {
+		return nil, models.ErrNoRecord
+	}
+	m.cancel(c.UserID)
+	return &c, nil
+}
+
+func (m *EmailChangeModel) Pending(userID int) (*models.EmailChange, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, c := range m.pending {
+		if c.UserID == userID && time.Now().Before(c.Expires) {
+			return &c, nil
+		}
+	}
+	return nil, models.ErrNoRecord
+}
+
+func (m *EmailChangeModel) Cancel(userID int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.cancel(userID)
+	return nil
+}
+
+func (m *EmailChangeModel) cancel(userID int) {
+	for plaintext, c := range m.pending {
+		if c.UserID == userID {
+			delete(m.pending, plaintext)
+		}
+	}
+}
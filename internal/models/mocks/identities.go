@@ -0,0 +1,49 @@
+package mocks
+
+import (
+	"sync"
+	"time"
+
+	"github.com/MohammadLashkari/snippetbox/internal/models"
+)
+
+type IdentityModel struct {
+	mu    sync.Mutex
+	links map[[2]string]*models.Identity
+}
+
+func (m *IdentityModel) UserID(issuer, subject string) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if i, ok := m.links[[2]string{issuer, subject}]; ok {
+		return i.UserID, nil
+	}
+	return 0, models.ErrNoRecord
+}
+
+func (m *IdentityModel) Insert(userID int, issuer, subject string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.links == nil {
+		m.links = map[[2]string]*models.Identity{}
+	}
+	m.links[[2]string{issuer, subject}] = &models.Identity{
+		UserID:  userID,
+		Issuer:  issuer,
+		Subject: subject,
+		Created: time.Now(),
+	}
+	return nil
+}
+
+func (m *IdentityModel) ByUser(userID int) ([]*models.Identity, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	identities := []*models.Identity{}
+	for _, i := range m.links {
+		if i.UserID == userID {
+			identities = append(identities, i)
+		}
+	}
+	return identities, nil
+}
@@ -0,0 +1,70 @@
+package mocks
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/MohammadLashkari/snippetbox/internal/models"
+)
+
+// LoginAttemptModel keeps failures in memory so tests can drive an account
+// or client into a lockout.
+type LoginAttemptModel struct {
+	mu       sync.Mutex
+	failures map[string]int
+	blocked  map[string]time.Time
+}
+
+func (m *LoginAttemptModel) Fail(key string) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.failures == nil {
+		m.failures = map[string]int{}
+	}
+	m.failures[key]++
+	return m.failures[key], nil
+}
+
+func (m *LoginAttemptModel) Block(key string, until time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.blocked == nil {
+		m.blocked = map[string]time.Time{}
+	}
+	m.blocked[key] = until
+	return nil
+}
+
+func (m *LoginAttemptModel) BlockedUntil(keys ...string) (time.Time, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var latest time.Time
+	for _, key := range keys {
+		if until := m.blocked[key]; until.After(time.Now()) && until.After(latest) {
+			latest = until
+		}
+	}
+	return latest, nil
+}
+
+func (m *LoginAttemptModel) Clear(key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.failures, key)
+	delete(m.blocked, key)
+	return nil
+}
+
+func (m *LoginAttemptModel) Locked() ([]*models.LoginLockout, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	lockouts := []*models.LoginLockout{}
+	for key, until := range m.blocked {
+		if until.After(time.Now()) {
+			lockouts = append(lockouts, &models.LoginLockout{Key: key, Failures: m.failures[key], BlockedUntil: until})
+		}
+	}
+	sort.Slice(lockouts, func(i, j int) bool { return lockouts[i].BlockedUntil.After(lockouts[j].BlockedUntil) })
+	return lockouts, nil
+}
@@ -0,0 +1,79 @@
+package mocks
+
+import (
+	"bytes"
+	"sync"
+	"time"
+
+	"github.com/MohammadLashkari/snippetbox/internal/models"
+)
+
+// PasskeyModel keeps passkeys in memory so tests can register one with a
+// software authenticator and then log in with it.
+type PasskeyModel struct {
+	mu       sync.Mutex
+	passkeys []*models.Passkey
+}
+
+func (m *PasskeyModel) Insert(userID int, name string, credentialID, publicKey []byte, signCount uint32) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	p := &models.Passkey{
+		ID:           len(m.passkeys) + 1,
+		UserID:       userID,
+		Name:         name,
+		CredentialID: credentialID,
+		PublicKey:    publicKey,
+		SignCount:    signCount,
+		Created:      time.Now(),
+	}
+	m.passkeys = append(m.passkeys, p)
+	return p.ID, nil
+}
+
+func (m *PasskeyModel) ByUser(userID int) ([]*models.Passkey, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	passkeys := []*models.Passkey{}
+	for _, p := range m.passkeys {
+		if p.UserID == userID {
+			passkeys = append(passkeys, p)
+		}
+	}
+	return passkeys, nil
+}
+
+func (m *PasskeyModel) GetByCredentialID(credentialID []byte) (*models.Passkey, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, p := range m.passkeys {
+		if bytes.Equal(p.CredentialID, credentialID) {
+			return p, nil
+		}
+	}
+	return nil, models.ErrNoRecord
+}
+
+func (m *PasskeyModel) Use(id int, signCount uint32) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, p := range m.passkeys {
+		if p.ID == id {
+			p.SignCount, p.LastUsed = signCount, time.Now()
+			return nil
+		}
+	}
+	return models.ErrNoRecord
+}
+
+func (m *PasskeyModel) Delete(id, userID int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i, p := range m.passkeys {
+		if p.ID == id && p.UserID == userID {
+			m.passkeys = append(m.passkeys[:i], m.passkeys[i+1:]...)
+			return nil
+		}
+	}
+	return models.ErrNoRecord
+}
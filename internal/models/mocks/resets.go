@@ -0,0 +1,24 @@
+package mocks
+
+import (
+	"time"
+
+	"github.com/MohammadLashkari/snippetbox/internal/models"
+)
+
+type PasswordResetModel struct{}
+
+func (m *PasswordResetModel) New(userID int, ttl time.Duration) (string, error) {
+	return "ABCDEFGHIJKLMNOPQRSTUVWXYZ", nil
+}
+
+func (m *PasswordResetModel) UserID(plaintext string) (int, error) {
+	if plaintext == "valid-reset-token" {
+		return 1, nil
+	}
+	return 0, models.ErrNoRecord
+}
+
+func (m *PasswordResetModel) Consume(plaintext string) (int, error) {
+	return m.UserID(plaintext)
+}
@@ -0,0 +1,72 @@
+package mocks
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/MohammadLashkari/snippetbox/internal/models"
+)
+
+// SessionModel keeps session metadata in memory, next to the in-memory scs
+// store used by tests.
+type SessionModel struct {
+	mu       sync.Mutex
+	sessions []*models.Session
+}
+
+func (m *SessionModel) Touch(token string, userID int, userAgent, ip string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, s := range m.sessions {
+		if s.Token == token {
+			s.UserAgent, s.IP, s.LastSeen = userAgent, ip, time.Now()
+			return nil
+		}
+	}
+	m.sessions = append(m.sessions, &models.Session{
+		ID:        len(m.sessions) + 1,
+		UserID:    userID,
+		Token:     token,
+		UserAgent: userAgent,
+		IP:        ip,
+		Created:   time.Now(),
+		LastSeen:  time.Now(),
+	})
+	return nil
+}
+
+func (m *SessionModel) ByUser(userID int) ([]*models.Session, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	sessions := []*models.Session{}
+	for _, s := range m.sessions {
+		if s.UserID == userID && s.Token != "" {
+			sessions = append(sessions, s)
+		}
+	}
+	sort.SliceStable(sessions, func(i, j int) bool { return sessions[i].LastSeen.After(sessions[j].LastSeen) })
+	return sessions, nil
+}
+
+func (m *SessionModel) Get(id, userID int) (*models.Session, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, s := range m.sessions {
+		if s.ID == id && s.UserID == userID && s.Token != "" {
+			return s, nil
+		}
+	}
+	return nil, models.ErrNoRecord
+}
+
+func (m *SessionModel) Delete(token string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, s := range m.sessions {
+		if s.Token == token {
+			s.Token = ""
+		}
+	}
+	return nil
+}
@@ -8,15 +8,27 @@ import (
 
 var mockSnippet = &models.Snippet{
 	ID:      1,
+	UserID:  1,
 	Title:   "hello world",
 	Content: "hello world",
 	Created: time.Now(),
+	Updated: time.Now(),
+	Expires: time.Now(),
+}
+
+// mockAnonymousSnippet was kept when its author deleted their account.
+var mockAnonymousSnippet = &models.Snippet{
+	ID:      4,
+	Title:   "left behind",
+	Content: "left behind",
+	Created: time.Now(),
+	Updated: time.Now(),
 	Expires: time.Now(),
 }
 
 type SnippetModel struct{}
 
-func (m *SnippetModel) Insert(title string, content string, expires int) (int, error) {
+func (m *SnippetModel) Insert(userID int, title string, content string, expires int) (int, error) {
 	return 2, nil
 }
 
@@ -24,6 +36,8 @@ func (m *SnippetModel) Get(id int) (*models.Snippet, error) {
 	switch id {
 	case 1:
 		return mockSnippet, nil
+	case 4:
+		return mockAnonymousSnippet, nil
 	default:
 		return nil, models.ErrNoRecord
 	}
@@ -32,3 +46,24 @@ func (m *SnippetModel) Get(id int) (*models.Snippet, error) {
 func (m *SnippetModel) Latest() ([]*models.Snippet, error) {
 	return []*models.Snippet{mockSnippet}, nil
 }
+
+func (m *SnippetModel) ByUser(userID int) ([]*models.Snippet, error) {
+	if userID == 1 {
+		return []*models.Snippet{mockSnippet}, nil
+	}
+	return []*models.Snippet{}, nil
+}
+
+func (m *SnippetModel) Update(id int, title, content string) error {
+	if id == 1 {
+		return nil
+	}
+	return models.ErrNoRecord
+}
+
+func (m *SnippetModel) Delete(id int) error {
+	if id == 1 || id == 4 {
+		return nil
+	}
+	return models.ErrNoRecord
+}
@@ -0,0 +1,35 @@
+package mocks
+
+import (
+	"time"
+
+	"github.com/MohammadLashkari/snippetbox/internal/models"
+)
+
+type TokenModel struct{}
+
+func (m *TokenModel) New(userID int) (string, error) {
+	return "ABCDEFGHIJKLMNOPQRSTUVWXYZ", nil
+}
+
+func (m *TokenModel) UserID(plaintext string) (int, error) {
+	switch plaintext {
+	case "valid-token":
+		return 1, nil
+	case "other-token":
+		return 2, nil
+	case "unverified-token":
+		return 3, nil
+	case "suspended-token":
+		return 7, nil
+	default:
+		return 0, models.ErrNoRecord
+	}
+}
+
+func (m *TokenModel) ByUser(userID int) ([]*models.Token, error) {
+	if userID == 1 {
+		return []*models.Token{{Created: time.Now()}}, nil
+	}
+	return []*models.Token{}, nil
+}
@@ -0,0 +1,36 @@
+package mocks
+
+import (
+	"time"
+
+	"github.com/MohammadLashkari/snippetbox/internal/models"
+)
+
+// MockTOTPSecret is the TOTP secret of user 2, the mock user with two-factor
+// authentication enabled.
+const MockTOTPSecret = "JBSWY3DPEHPK3PXP"
+
+type TwoFactorModel struct{}
+
+func (m *TwoFactorModel) Get(userID int) (*models.TwoFactor, error) {
+	if userID == 2 {
+		return &models.TwoFactor{UserID: 2, Secret: MockTOTPSecret, Created: time.Now()}, nil
+	}
+	return nil, models.ErrNoRecord
+}
+
+func (m *TwoFactorModel) Enable(userID int, secret string, recoveryCodes []string) error {
+	return nil
+}
+
+func (m *TwoFactorModel) Disable(userID int) error {
+	return nil
+}
+
+func (m *TwoFactorModel) UseCounter(userID int, counter int64) (bool, error) {
+	return userID == 2, nil
+}
+
+func (m *TwoFactorModel) UseRecoveryCode(userID int, code string) (bool, error) {
+	return userID == 2 && code == "aaaaa-bbbbb", nil
+}
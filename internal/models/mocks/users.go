@@ -1,6 +1,7 @@
 package mocks
 
 import (
+	"strings"
 	"time"
 
 	"github.com/MohammadLashkari/snippetbox/internal/models"
@@ -12,42 +13,143 @@ var mockUser = &models.User{
 	Email:          "foo@gmail.com",
 	HashedPassword: []byte("password"),
 	Created:        time.Now(),
+	Verified:       true,
+	Role:           models.RoleUser,
+	Bio:            "Writes Go in the evenings.",
+	Website:        "https://foo.example.com",
+	Username:       "foo",
+}
+
+// mockUsernameHistory maps usernames given up to the user who had them.
+var mockUsernameHistory = map[string]int{"oldfoo": 1}
+
+// mockTwoFactorUser has two-factor authentication enabled, see TwoFactorModel.
+var mockTwoFactorUser = &models.User{
+	ID:             2,
+	Name:           "bar",
+	Email:          "bar@example.com",
+	HashedPassword: []byte("password"),
+	Created:        time.Now(),
+	Verified:       true,
+	Role:           models.RoleUser,
+}
+
+// mockUnverifiedUser has not verified their email address yet.
+var mockUnverifiedUser = &models.User{
+	ID:             3,
+	Name:           "baz",
+	Email:          "baz@example.com",
+	HashedPassword: []byte("password"),
+	Created:        time.Now(),
+	Role:           models.RoleUser,
+}
+
+var mockAdminUser = &models.User{
+	ID:             5,
+	Name:           "admin",
+	Email:          "admin@example.com",
+	HashedPassword: []byte("password"),
+	Created:        time.Now(),
+	Verified:       true,
+	Role:           models.RoleAdmin,
+}
+
+var mockModeratorUser = &models.User{
+	ID:             6,
+	Name:           "moderator",
+	Email:          "mod@example.com",
+	HashedPassword: []byte("password"),
+	Created:        time.Now(),
+	Verified:       true,
+	Role:           models.RoleModerator,
+	Username:       "jane",
+}
+
+// mockSuspendedUser can log in but is turned away by the app afterwards.
+var mockSuspendedUser = &models.User{
+	ID:             7,
+	Name:           "qux",
+	Email:          "qux@example.com",
+	HashedPassword: []byte("password"),
+	Created:        time.Now(),
+	Verified:       true,
+	Role:           models.RoleUser,
+	Suspended:      true,
+}
+
+var mockUsers = []*models.User{
+	mockUser, mockTwoFactorUser, mockUnverifiedUser, mockAdminUser, mockModeratorUser, mockSuspendedUser,
+}
+
+func mockUserByID(id int) *models.User {
+	for _, user := range mockUsers {
+		if user.ID == id {
+			return user
+		}
+	}
+	return nil
 }
 
 type UserModel struct{}
 
-func (m *UserModel) Insert(name, email, password string) error {
-	switch email {
-	case "dupe@example.com":
-		return models.ErrDuplicateEmail
-	default:
-		return nil
+func (m *UserModel) Insert(name, username, email, password string) (int, error) {
+	if email == "dupe@example.com" {
+		return 0, models.ErrDuplicateEmail
 	}
+	if usernameTaken(username, 0) {
+		return 0, models.ErrDuplicateUsername
+	}
+	return 4, nil
 }
 
 func (m *UserModel) Authenticate(email, password string) (int, error) {
 	if email == "foo@example.com" && password == "password" {
 		return 1, nil
 	}
+	if email == "bar@example.com" && password == "password" {
+		return 2, nil
+	}
+	if email == "baz@example.com" && password == "password" {
+		return 3, nil
+	}
+	for _, user := range []*models.User{mockAdminUser, mockModeratorUser, mockSuspendedUser} {
+		if email == user.Email && password == "password" {
+			return user.ID, nil
+		}
+	}
 	return 0, models.ErrInvalidCredentials
 }
 
 func (m *UserModel) Exists(id int) (bool, error) {
-	switch id {
-	case 1:
-		return true, nil
-	default:
-		return false, nil
-	}
+	return mockUserByID(id) != nil, nil
 }
 
 func (m *UserModel) Get(id int) (*models.User, error) {
-	switch id {
-	case 1:
-		return mockUser, nil
-	default:
-		return nil, models.ErrNoRecord
+	if user := mockUserByID(id); user != nil {
+		return user, nil
+	}
+	return nil, models.ErrNoRecord
+}
+
+func (m *UserModel) GetByEmail(email string) (*models.User, error) {
+	for _, user := range mockUsers {
+		if user.Email == email {
+			return user, nil
+		}
+	}
+	return nil, models.ErrNoRecord
+}
+
+func (m *UserModel) GetByUsername(username string) (*models.User, error) {
+	for _, user := range mockUsers {
+		if user.Username != "" && strings.EqualFold(user.Username, username) {
+			return user, nil
+		}
 	}
+	if user := mockUserByID(mockUsernameHistory[strings.ToLower(username)]); user != nil {
+		return user, nil
+	}
+	return nil, models.ErrNoRecord
 }
 
 func (m *UserModel) PasswordUpdate(id int, currentPassword, newPassword string) error {
@@ -59,3 +161,107 @@ func (m *UserModel) PasswordUpdate(id int, currentPassword, newPassword string)
 	}
 	return models.ErrNoRecord
 }
+
+func (m *UserModel) PasswordSet(id int, newPassword string) error {
+	switch id {
+	case 1, 2, 3:
+		return nil
+	default:
+		return models.ErrNoRecord
+	}
+}
+
+func (m *UserModel) CheckPassword(id int, password string) error {
+	switch id {
+	case 1, 2, 3:
+		if password != "password" {
+			return models.ErrInvalidCredentials
+		}
+		return nil
+	default:
+		return models.ErrNoRecord
+	}
+}
+
+func (m *UserModel) SetVerified(id int, email string) error {
+	if id == 3 && email == mockUnverifiedUser.Email {
+		return nil
+	}
+	return models.ErrNoRecord
+}
+
+func (m *UserModel) SetEmail(id int, oldEmail, newEmail string) error {
+	if newEmail == "dupe@example.com" {
+		return models.ErrDuplicateEmail
+	}
+	for _, user := range mockUsers {
+		if user.Email == newEmail {
+			return models.ErrDuplicateEmail
+		}
+	}
+	if user := mockUserByID(id); user != nil && user.Email == oldEmail {
+		return nil
+	}
+	return models.ErrNoRecord
+}
+
+func (m *UserModel) UpdateProfile(id int, name, bio, website string) error {
+	return nil
+}
+
+func (m *UserModel) SetUsername(id int, username string) error {
+	if usernameTaken(username, id) {
+		return models.ErrDuplicateUsername
+	}
+	return nil
+}
+
+// usernameTaken reports whether a user other than the one with id has
+// username, whatever its case.
+func usernameTaken(username string, id int) bool {
+	for _, user := range mockUsers {
+		if user.ID != id && username != "" && strings.EqualFold(user.Username, username) {
+			return true
+		}
+	}
+	return false
+}
+
+func (m *UserModel) PreviousUsernames(id int) ([]string, error) {
+	usernames := []string{}
+	for username, userID := range mockUsernameHistory {
+		if userID == id {
+			usernames = append(usernames, username)
+		}
+	}
+	return usernames, nil
+}
+
+func (m *UserModel) Search(query string, limit int) ([]*models.User, error) {
+	users := []*models.User{}
+	for _, user := range mockUsers {
+		if strings.Contains(user.Name, query) || strings.Contains(user.Email, query) ||
+			strings.Contains(user.Username, query) {
+			users = append(users, user)
+		}
+	}
+	if len(users) > limit {
+		users = users[:limit]
+	}
+	return users, nil
+}
+
+func (m *UserModel) SetRole(id int, role string) error {
+	return nil
+}
+
+func (m *UserModel) SetSuspended(id int, suspended bool) error {
+	return nil
+}
+
+func (m *UserModel) Delete(id int, anonymizeSnippets bool) error {
+	if mockUserByID(id) == nil {
+		return models.ErrNoRecord
+	}
+	return nil
+}
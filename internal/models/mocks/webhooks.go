@@ -0,0 +1,81 @@
+package mocks
+
+import (
+	"time"
+
+	"github.com/MohammadLashkari/snippetbox/internal/models"
+)
+
+var mockWebhook = &models.Webhook{
+	ID:      1,
+	UserID:  1,
+	URL:     "https://chat.example.com/hook",
+	Secret:  "secret",
+	Events:  []string{models.EventSnippetCreated},
+	Created: time.Now(),
+}
+
+var mockDelivery = &models.WebhookDelivery{
+	ID:             1,
+	WebhookID:      1,
+	URL:            mockWebhook.URL,
+	Secret:         mockWebhook.Secret,
+	Event:          models.EventSnippetCreated,
+	Payload:        []byte(`{"event":"snippet.created"}`),
+	Status:         models.DeliveryDelivered,
+	Attempts:       1,
+	ResponseStatus: 200,
+	Created:        time.Now(),
+	NextAttempt:    time.Now(),
+}
+
+type WebhookModel struct{}
+
+func (m *WebhookModel) Insert(userID int, url, secret string, events []string) (int, error) {
+	return 2, nil
+}
+
+func (m *WebhookModel) ByUser(userID int) ([]*models.Webhook, error) {
+	if userID == 1 {
+		return []*models.Webhook{mockWebhook}, nil
+	}
+	return []*models.Webhook{}, nil
+}
+
+func (m *WebhookModel) Delete(id, userID int) error {
+	if id == 1 && userID == 1 {
+		return nil
+	}
+	return models.ErrNoRecord
+}
+
+func (m *WebhookModel) Deliveries(userID, limit int) ([]*models.WebhookDelivery, error) {
+	if userID == 1 {
+		return []*models.WebhookDelivery{mockDelivery}, nil
+	}
+	return []*models.WebhookDelivery{}, nil
+}
+
+func (m *WebhookModel) ForEvent(userID int, event string) ([]*models.Webhook, error) {
+	return []*models.Webhook{}, nil
+}
+
+func (m *WebhookModel) Enqueue(webhookID int, event string, payload []byte) error {
+	return nil
+}
+
+func (m *WebhookModel) Due(limit int) ([]*models.WebhookDelivery, error) {
+	return []*models.WebhookDelivery{}, nil
+}
+
+func (m *WebhookModel) Delivered(id, responseStatus int) error {
+	return nil
+}
+
+func (m *WebhookModel) Retry(id, responseStatus int, lastError string, delay time.Duration) error {
+	return nil
+}
+
+func (m *WebhookModel) Failed(id, responseStatus int, lastError string) error {
+	return nil
+}
@@ -0,0 +1,109 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// Passkey is a WebAuthn credential a user can log in with. PublicKey is in
+// COSE format.
+type Passkey struct {
+	ID           int
+	UserID       int
+	Name         string
+	CredentialID []byte
+	PublicKey    []byte
+	SignCount    uint32
+	Created      time.Time
+	LastUsed     time.Time
+}
+
+type PasskeyModelInterface interface {
+	Insert(userID int, name string, credentialID, publicKey []byte, signCount uint32) (int, error)
+	ByUser(userID int) ([]*Passkey, error)
+	GetByCredentialID(credentialID []byte) (*Passkey, error)
+	Use(id int, signCount uint32) error
+	Delete(id, userID int) error
+}
+
+type PasskeyModel struct {
+	DB *sql.DB
+}
+
+func (m *PasskeyModel) Insert(userID int, name string, credentialID, publicKey []byte, signCount uint32) (int, error) {
+	query := `INSERT INTO passkeys (user_id, name, credential_id, public_key, sign_count, created)
+    VALUES (?, ?, ?, ?, ?, UTC_TIMESTAMP())`
+	result, err := m.DB.Exec(query, userID, name, credentialID, publicKey, signCount)
+	if err != nil {
+		return 0, err
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+	return int(id), nil
+}
+
+const passkeyColumns = `id, user_id, name, credential_id, public_key, sign_count, created, last_used`
+
+func scanPasskey(row interface{ Scan(...any) error }) (*Passkey, error) {
+	p := &Passkey{}
+	var lastUsed sql.NullTime
+	err := row.Scan(&p.ID, &p.UserID, &p.Name, &p.CredentialID, &p.PublicKey, &p.SignCount, &p.Created, &lastUsed)
+	if err != nil {
+		return nil, err
+	}
+	p.LastUsed = lastUsed.Time
+	return p, nil
+}
+
+func (m *PasskeyModel) ByUser(userID int) ([]*Passkey, error) {
+	query := `SELECT ` + passkeyColumns + ` FROM passkeys WHERE user_id = ? ORDER BY id`
+	rows, err := m.DB.Query(query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	passkeys := []*Passkey{}
+	for rows.Next() {
+		p, err := scanPasskey(rows)
+		if err != nil {
+			return nil, err
+		}
+		passkeys = append(passkeys, p)
+	}
+	return passkeys, rows.Err()
+}
+
+func (m *PasskeyModel) GetByCredentialID(credentialID []byte) (*Passkey, error) {
+	query := `SELECT ` + passkeyColumns + ` FROM passkeys WHERE credential_id = ?`
+	p, err := scanPasskey(m.DB.QueryRow(query, credentialID))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNoRecord
+		}
+		return nil, err
+	}
+	return p, nil
+}
+
+// Use stores the sign count reported by the latest successful login with
+// the passkey.
+func (m *PasskeyModel) Use(id int, signCount uint32) error {
+	query := `UPDATE passkeys SET sign_count = ?, last_used = UTC_TIMESTAMP() WHERE id = ?`
+	result, err := m.DB.Exec(query, signCount, id)
+	if err != nil {
+		return err
+	}
+	return checkAffected(result)
+}
+
+func (m *PasskeyModel) Delete(id, userID int) error {
+	result, err := m.DB.Exec(`DELETE FROM passkeys WHERE id = ? AND user_id = ?`, id, userID)
+	if err != nil {
+		return err
+	}
+	return checkAffected(result)
+}
@@ -0,0 +1,79 @@
+package models
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base32"
+	"errors"
+	"time"
+)
+
+type PasswordResetModelInterface interface {
+	New(userID int, ttl time.Duration) (string, error)
+	UserID(plaintext string) (int, error)
+	Consume(plaintext string) (int, error)
+}
+
+type PasswordResetModel struct {
+	DB *sql.DB
+}
+
+// New generates a password reset token for the user that expires after ttl.
+// As with API tokens, only the SHA-256 hash is stored.
+func (m *PasswordResetModel) New(userID int, ttl time.Duration) (string, error) {
+	randomBytes := make([]byte, 16)
+	if _, err := rand.Read(randomBytes); err != nil {
+		return "", err
+	}
+	plaintext := base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(randomBytes)
+	hash := sha256.Sum256([]byte(plaintext))
+
+	query := `INSERT INTO password_resets (hash, user_id, expires)
+    VALUES (?, ?, DATE_ADD(UTC_TIMESTAMP(), INTERVAL ? SECOND))`
+	if _, err := m.DB.Exec(query, hash[:], userID, int(ttl.Seconds())); err != nil {
+		return "", err
+	}
+	return plaintext, nil
+}
+
+// UserID returns the user an unexpired token belongs to without using it up.
+func (m *PasswordResetModel) UserID(plaintext string) (int, error) {
+	hash := sha256.Sum256([]byte(plaintext))
+	query := `SELECT user_id FROM password_resets WHERE hash = ? AND expires > UTC_TIMESTAMP()`
+	var userID int
+	err := m.DB.QueryRow(query, hash[:]).Scan(&userID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, ErrNoRecord
+		}
+		return 0, err
+	}
+	return userID, nil
+}
+
+// Consume returns the user an unexpired token belongs to and deletes every
+// outstanding reset token of that user, so each link works only once.
+func (m *PasswordResetModel) Consume(plaintext string) (int, error) {
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	hash := sha256.Sum256([]byte(plaintext))
+	query := `SELECT user_id FROM password_resets
+    WHERE hash = ? AND expires > UTC_TIMESTAMP() FOR UPDATE`
+	var userID int
+	err = tx.QueryRow(query, hash[:]).Scan(&userID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, ErrNoRecord
+		}
+		return 0, err
+	}
+	if _, err := tx.Exec(`DELETE FROM password_resets WHERE user_id = ?`, userID); err != nil {
+		return 0, err
+	}
+	return userID, tx.Commit()
+}
@@ -0,0 +1,97 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+	"unicode/utf8"
+)
+
+// Session describes the device behind a logged in session. The session data
+// itself lives in the sessions table managed by scs, keyed by Token.
+type Session struct {
+	ID        int
+	UserID    int
+	Token     string
+	UserAgent string
+	IP        string
+	Created   time.Time
+	LastSeen  time.Time
+}
+
+type SessionModelInterface interface {
+	Touch(token string, userID int, userAgent, ip string) error
+	ByUser(userID int) ([]*Session, error)
+	Get(id, userID int) (*Session, error)
+	Delete(token string) error
+}
+
+type SessionModel struct {
+	DB *sql.DB
+}
+
+// Touch records that the session was just used by userID from the given
+// device, adding it on first use.
+func (m *SessionModel) Touch(token string, userID int, userAgent, ip string) error {
+	query := `INSERT INTO user_sessions (token, user_id, user_agent, ip, created, last_seen)
+    VALUES (?, ?, ?, ?, UTC_TIMESTAMP(), UTC_TIMESTAMP())
+    ON DUPLICATE KEY UPDATE user_agent = VALUES(user_agent), ip = VALUES(ip), last_seen = UTC_TIMESTAMP()`
+	_, err := m.DB.Exec(query, token, userID, truncate(userAgent, 255), ip)
+	return err
+}
+
+// ByUser returns the user's unexpired sessions, most recently used first.
+func (m *SessionModel) ByUser(userID int) ([]*Session, error) {
+	query := `SELECT us.id, us.user_id, us.token, us.user_agent, us.ip, us.created, us.last_seen
+    FROM user_sessions us JOIN sessions s ON s.token = us.token
+    WHERE us.user_id = ? AND s.expiry > UTC_TIMESTAMP(6)
+    ORDER BY us.last_seen DESC`
+	rows, err := m.DB.Query(query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	sessions := []*Session{}
+	for rows.Next() {
+		s := &Session{}
+		err := rows.Scan(&s.ID, &s.UserID, &s.Token, &s.UserAgent, &s.IP, &s.Created, &s.LastSeen)
+		if err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, s)
+	}
+	return sessions, rows.Err()
+}
+
+func (m *SessionModel) Get(id, userID int) (*Session, error) {
+	query := `SELECT id, user_id, token, user_agent, ip, created, last_seen
+    FROM user_sessions WHERE id = ? AND user_id = ?`
+	s := &Session{}
+	err := m.DB.QueryRow(query, id, userID).Scan(&s.ID, &s.UserID, &s.Token, &s.UserAgent, &s.IP, &s.Created, &s.LastSeen)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNoRecord
+		}
+		return nil, err
+	}
+	return s, nil
+}
+
+// Delete forgets the metadata of a session. Deleting the session from the
+// scs store removes it as well.
+func (m *SessionModel) Delete(token string) error {
+	_, err := m.DB.Exec(`DELETE FROM user_sessions WHERE token = ?`, token)
+	return err
+}
+
+// truncate shortens s to at most n bytes without splitting a UTF-8 sequence.
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
@@ -8,27 +8,32 @@ import (
 
 type Snippet struct {
 	ID      int
+	UserID  int
 	Title   string
 	Content string
 	Created time.Time
+	Updated time.Time
 	Expires time.Time
 }
 
 type SnippetModelInterface interface {
-	Insert(title, content string, expires int) (int, error)
+	Insert(userID int, title, content string, expires int) (int, error)
 	Get(id int) (*Snippet, error)
 	Latest() ([]*Snippet, error)
+	ByUser(userID int) ([]*Snippet, error)
+	Update(id int, title, content string) error
+	Delete(id int) error
 }
 
 type SnippetModel struct {
 	DB *sql.DB
 }
 
-func (m *SnippetModel) Insert(title, content string, expires int) (int, error) {
-	query := `INSERT INTO snippets (title, content, created, expires)
-    VALUES(?, ?, UTC_TIMESTAMP(), DATE_ADD(UTC_TIMESTAMP(), INTERVAL ? DAY))`
+func (m *SnippetModel) Insert(userID int, title, content string, expires int) (int, error) {
+	query := `INSERT INTO snippets (user_id, title, content, created, updated, expires)
+    VALUES(?, ?, ?, UTC_TIMESTAMP(), UTC_TIMESTAMP(), DATE_ADD(UTC_TIMESTAMP(), INTERVAL ? DAY))`
 
-	result, err := m.DB.Exec(query, title, content, expires)
+	result, err := m.DB.Exec(query, userID, title, content, expires)
 	if err != nil {
 		return 0, err
 	}
@@ -41,10 +46,10 @@ func (m *SnippetModel) Insert(title, content string, expires int) (int, error) {
 }
 
 func (m *SnippetModel) Get(id int) (*Snippet, error) {
-	query := `SELECT id, title, content, created, expires FROM snippets
+	query := `SELECT id, IFNULL(user_id, 0), title, content, created, updated, expires FROM snippets
     WHERE expires > UTC_TIMESTAMP() AND id = ?`
 	s := Snippet{}
-	err := m.DB.QueryRow(query, id).Scan(&s.ID, &s.Title, &s.Content, &s.Created, &s.Expires)
+	err := m.DB.QueryRow(query, id).Scan(&s.ID, &s.UserID, &s.Title, &s.Content, &s.Created, &s.Updated, &s.Expires)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, ErrNoRecord
@@ -56,9 +61,52 @@ func (m *SnippetModel) Get(id int) (*Snippet, error) {
 }
 
 func (m *SnippetModel) Latest() ([]*Snippet, error) {
-	query := `SELECT id, title, content, created, expires FROM snippets
+	query := `SELECT id, IFNULL(user_id, 0), title, content, created, updated, expires FROM snippets
     WHERE expires > UTC_TIMESTAMP() ORDER BY id DESC LIMIT 10`
-	rows, err := m.DB.Query(query)
+	return m.query(query)
+}
+
+func (m *SnippetModel) ByUser(userID int) ([]*Snippet, error) {
+	query := `SELECT id, IFNULL(user_id, 0), title, content, created, updated, expires FROM snippets
+    WHERE expires > UTC_TIMESTAMP() AND user_id = ? ORDER BY id DESC`
+	return m.query(query, userID)
+}
+
+// Update replaces the title and content of an unexpired snippet. The row is
+// looked up first, because MySQL does not count an update that leaves it as
+// it was, such as saving the same edit twice within a second.
+func (m *SnippetModel) Update(id int, title, content string) error {
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `SELECT id FROM snippets WHERE expires > UTC_TIMESTAMP() AND id = ? FOR UPDATE`
+	if err := tx.QueryRow(query, id).Scan(&id); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrNoRecord
+		}
+		return err
+	}
+	query = `UPDATE snippets SET title = ?, content = ?, updated = UTC_TIMESTAMP() WHERE id = ?`
+	if _, err := tx.Exec(query, title, content, id); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (m *SnippetModel) Delete(id int) error {
+	query := `DELETE FROM snippets WHERE id = ?`
+	result, err := m.DB.Exec(query, id)
+	if err != nil {
+		return err
+	}
+	return checkAffected(result)
+}
+
+func (m *SnippetModel) query(query string, args ...any) ([]*Snippet, error) {
+	rows, err := m.DB.Query(query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -67,7 +115,7 @@ func (m *SnippetModel) Latest() ([]*Snippet, error) {
 	snippets := []*Snippet{}
 	for rows.Next() {
 		s := Snippet{}
-		err := rows.Scan(&s.ID, &s.Title, &s.Content, &s.Created, &s.Expires)
+		err := rows.Scan(&s.ID, &s.UserID, &s.Title, &s.Content, &s.Created, &s.Updated, &s.Expires)
 		if err != nil {
 			return nil, err
 		}
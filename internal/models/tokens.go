@@ -0,0 +1,78 @@
+package models
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base32"
+	"errors"
+	"time"
+)
+
+// Token is what is kept about an API token besides its hash.
+type Token struct {
+	Created time.Time
+}
+
+type TokenModelInterface interface {
+	New(userID int) (string, error)
+	UserID(plaintext string) (int, error)
+	ByUser(userID int) ([]*Token, error)
+}
+
+type TokenModel struct {
+	DB *sql.DB
+}
+
+// New generates a random API token for the user. Only the SHA-256 hash is
+// stored, so the returned plaintext cannot be recovered later.
+func (m *TokenModel) New(userID int) (string, error) {
+	randomBytes := make([]byte, 16)
+	if _, err := rand.Read(randomBytes); err != nil {
+		return "", err
+	}
+	plaintext := base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(randomBytes)
+	hash := sha256.Sum256([]byte(plaintext))
+
+	query := `INSERT INTO tokens (hash, user_id, created) VALUES (?, ?, UTC_TIMESTAMP())`
+	if _, err := m.DB.Exec(query, hash[:], userID); err != nil {
+		return "", err
+	}
+	return plaintext, nil
+}
+
+func (m *TokenModel) UserID(plaintext string) (int, error) {
+	hash := sha256.Sum256([]byte(plaintext))
+	query := `SELECT user_id FROM tokens WHERE hash = ?`
+	var userID int
+	err := m.DB.QueryRow(query, hash[:]).Scan(&userID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, ErrNoRecord
+		}
+		return 0, err
+	}
+	return userID, nil
+}
+
+func (m *TokenModel) ByUser(userID int) ([]*Token, error) {
+	query := `SELECT created FROM tokens WHERE user_id = ? ORDER BY created`
+	rows, err := m.DB.Query(query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tokens := []*Token{}
+	for rows.Next() {
+		var t Token
+		if err := rows.Scan(&t.Created); err != nil {
+			return nil, err
+		}
+		tokens = append(tokens, &t)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return tokens, nil
+}
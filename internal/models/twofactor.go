@@ -0,0 +1,130 @@
+package models
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base32"
+	"errors"
+	"strings"
+	"time"
+)
+
+type TwoFactor struct {
+	UserID      int
+	Secret      string
+	LastCounter int64
+	Created     time.Time
+}
+
+type TwoFactorModelInterface interface {
+	Get(userID int) (*TwoFactor, error)
+	Enable(userID int, secret string, recoveryCodes []string) error
+	Disable(userID int) error
+	UseCounter(userID int, counter int64) (bool, error)
+	UseRecoveryCode(userID int, code string) (bool, error)
+}
+
+type TwoFactorModel struct {
+	DB *sql.DB
+}
+
+// NewRecoveryCodes returns n random one-time codes formatted as
+// "xxxxx-xxxxx" for users to write down.
+func NewRecoveryCodes(n int) ([]string, error) {
+	codes := make([]string, n)
+	for i := range codes {
+		b := make([]byte, 7)
+		if _, err := rand.Read(b); err != nil {
+			return nil, err
+		}
+		s := strings.ToLower(base32.StdEncoding.EncodeToString(b))[:10]
+		codes[i] = s[:5] + "-" + s[5:]
+	}
+	return codes, nil
+}
+
+// hashRecoveryCode ignores case, spaces and dashes so codes can be typed
+// back however they were written down. The codes carry 50 bits of
+// randomness, so a plain SHA-256 is enough to store them.
+func hashRecoveryCode(code string) []byte {
+	code = strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(code))
+	hash := sha256.Sum256([]byte(code))
+	return hash[:]
+}
+
+func (m *TwoFactorModel) Get(userID int) (*TwoFactor, error) {
+	query := `SELECT user_id, secret, last_counter, created FROM user_totp WHERE user_id = ?`
+	var tf TwoFactor
+	err := m.DB.QueryRow(query, userID).Scan(&tf.UserID, &tf.Secret, &tf.LastCounter, &tf.Created)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNoRecord
+		}
+		return nil, err
+	}
+	return &tf, nil
+}
+
+func (m *TwoFactorModel) Enable(userID int, secret string, recoveryCodes []string) error {
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `REPLACE INTO user_totp (user_id, secret, last_counter, created) VALUES (?, ?, 0, UTC_TIMESTAMP())`
+	if _, err := tx.Exec(query, userID, secret); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`DELETE FROM totp_recovery_codes WHERE user_id = ?`, userID); err != nil {
+		return err
+	}
+	query = `INSERT INTO totp_recovery_codes (user_id, hash) VALUES (?, ?)`
+	for _, code := range recoveryCodes {
+		if _, err := tx.Exec(query, userID, hashRecoveryCode(code)); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+func (m *TwoFactorModel) Disable(userID int) error {
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`DELETE FROM totp_recovery_codes WHERE user_id = ?`, userID); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`DELETE FROM user_totp WHERE user_id = ?`, userID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// UseCounter records counter as the last time step a code was accepted
+// for. It reports false if that step, or a later one, was already used, so
+// each code works only once.
+func (m *TwoFactorModel) UseCounter(userID int, counter int64) (bool, error) {
+	query := `UPDATE user_totp SET last_counter = ? WHERE user_id = ? AND last_counter < ?`
+	result, err := m.DB.Exec(query, counter, userID, counter)
+	if err != nil {
+		return false, err
+	}
+	n, err := result.RowsAffected()
+	return n > 0, err
+}
+
+// UseRecoveryCode consumes code if it is one of the user's unused codes.
+func (m *TwoFactorModel) UseRecoveryCode(userID int, code string) (bool, error) {
+	query := `DELETE FROM totp_recovery_codes WHERE user_id = ? AND hash = ?`
+	result, err := m.DB.Exec(query, userID, hashRecoveryCode(code))
+	if err != nil {
+		return false, err
+	}
+	n, err := result.RowsAffected()
+	return n > 0, err
+}
@@ -6,55 +6,128 @@ import (
 	"strings"
 	"time"
 
+	"github.com/MohammadLashkari/snippetbox/internal/passwords"
 	"github.com/go-sql-driver/mysql"
-	"golang.org/x/crypto/bcrypt"
 )
 
+// Roles, from least to most privileged. Moderators can suspend users and
+// remove snippets; admins can also delete accounts and change roles.
+const (
+	RoleUser      = "user"
+	RoleModerator = "moderator"
+	RoleAdmin     = "admin"
+)
+
+var roles = []string{RoleUser, RoleModerator, RoleAdmin}
+
+func roleRank(role string) int {
+	for i, r := range roles {
+		if r == role {
+			return i
+		}
+	}
+	return -1
+}
+
+// ValidRole reports whether role is one of the known roles.
+func ValidRole(role string) bool {
+	return roleRank(role) >= 0
+}
+
+// RoleIncludes reports whether role grants everything other does, that is
+// whether it is the same role or a more privileged one.
+func RoleIncludes(role, other string) bool {
+	rank := roleRank(other)
+	return rank >= 0 && roleRank(role) >= rank
+}
+
 type User struct {
 	ID             int
 	Name           string
 	Email          string
 	HashedPassword []byte
 	Created        time.Time
+	Verified       bool
+	Role           string
+	Suspended      bool
+	Bio            string
+	Website        string
+	Username       string
+}
+
+// HasRole reports whether the user has role or a more privileged one.
+func (u *User) HasRole(role string) bool {
+	return RoleIncludes(u.Role, role)
 }
 
 type UserModelInterface interface {
-	Insert(name, email, password string) error
+	Insert(name, username, email, password string) (int, error)
 	Authenticate(email, password string) (int, error)
 	Exists(id int) (bool, error)
 	Get(id int) (*User, error)
+	GetByEmail(email string) (*User, error)
+	GetByUsername(username string) (*User, error)
 	PasswordUpdate(id int, currentPassword, newPassword string) error
+	PasswordSet(id int, newPassword string) error
+	CheckPassword(id int, password string) error
+	SetVerified(id int, email string) error
+	SetEmail(id int, oldEmail, newEmail string) error
+	UpdateProfile(id int, name, bio, website string) error
+	SetUsername(id int, username string) error
+	PreviousUsernames(id int) ([]string, error)
+	Search(query string, limit int) ([]*User, error)
+	SetRole(id int, role string) error
+	SetSuspended(id int, suspended bool) error
+	Delete(id int, anonymizeSnippets bool) error
 }
 
+// UserModel hashes passwords with Hasher, or with passwords.Default() when
+// it is nil.
 type UserModel struct {
-	DB *sql.DB
+	DB     *sql.DB
+	Hasher *passwords.Hasher
+}
+
+func (m *UserModel) hasher() *passwords.Hasher {
+	if m.Hasher == nil {
+		return passwords.Default()
+	}
+	return m.Hasher
 }
 
-func (m *UserModel) Insert(name, email, password string) error {
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), 12)
+// Insert adds a user. The username is optional and left unset when empty.
+func (m *UserModel) Insert(name, username, email, password string) (int, error) {
+	hashedPassword, err := m.hasher().Hash(password)
 	if err != nil {
-		return err
+		return 0, err
 	}
-	query := `INSERT INTO users(name, email, hashed_password, created)
-    VALUES (?, ?, ?, UTC_TIMESTAMP())`
-	_, err = m.DB.Exec(query, name, email, string(hashedPassword))
+	query := `INSERT INTO users(name, username, email, hashed_password, created)
+    VALUES (?, NULLIF(?, ''), ?, ?, UTC_TIMESTAMP())`
+	result, err := m.DB.Exec(query, name, username, email, hashedPassword)
 	if err != nil {
-		var mysqlError *mysql.MySQLError
-		if errors.As(err, &mysqlError) {
-			if mysqlError.Number == 1062 && strings.Contains(mysqlError.Message, "users_uc_email") {
-				return ErrDuplicateEmail
-			}
+		switch {
+		case isDuplicate(err, "users_uc_email"):
+			return 0, ErrDuplicateEmail
+		case isDuplicate(err, "users_uc_username"):
+			return 0, ErrDuplicateUsername
 		}
-		return err
+		return 0, err
 	}
-	return nil
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+	return int(id), nil
 }
 
+// Authenticate returns the ID of the user with email and password. A hash
+// made with an older algorithm or weaker parameters than the hasher's is
+// replaced while the password is at hand.
 func (m *UserModel) Authenticate(email, password string) (int, error) {
 	query := `SELECT id, hashed_password FROM users WHERE email = ?`
 	var (
 		id             int
-		hashedPassword []byte
+		hashedPassword string
 	)
 	err := m.DB.QueryRow(query, email).Scan(&id, &hashedPassword)
 	if err != nil {
@@ -63,13 +136,25 @@ func (m *UserModel) Authenticate(email, password string) (int, error) {
 		}
 		return 0, err
 	}
-	err = bcrypt.CompareHashAndPassword(hashedPassword, []byte(password))
+	hasher := m.hasher()
+	err = hasher.Compare(hashedPassword, password)
 	if err != nil {
-		if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+		if errors.Is(err, passwords.ErrMismatch) {
 			return 0, ErrInvalidCredentials
 		}
 		return 0, err
 	}
+	if hasher.NeedsRehash(hashedPassword) {
+		newHashedPassword, err := hasher.Hash(password)
+		if err != nil {
+			return 0, err
+		}
+		// Leave the password alone if it was changed in the meantime.
+		query := `UPDATE users SET hashed_password = ? WHERE id = ? AND hashed_password = ?`
+		if _, err := m.DB.Exec(query, newHashedPassword, id, hashedPassword); err != nil {
+			return 0, err
+		}
+	}
 	return id, nil
 }
 
@@ -81,9 +166,45 @@ func (m *UserModel) Exists(id int) (bool, error) {
 }
 
 func (m *UserModel) Get(id int) (*User, error) {
-	query := `SELECT name, email, created FROM users WHERE id = ?`
+	query := `SELECT name, email, created, verified, role, suspended, bio, website, IFNULL(username, '')
+    FROM users WHERE id = ?`
+	user := User{ID: id}
+	err := m.DB.QueryRow(query, id).Scan(&user.Name, &user.Email, &user.Created, &user.Verified,
+		&user.Role, &user.Suspended, &user.Bio, &user.Website, &user.Username)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNoRecord
+		}
+		return nil, err
+	}
+	return &user, nil
+}
+
+func (m *UserModel) GetByEmail(email string) (*User, error) {
+	query := `SELECT id, name, email, created, verified, role, suspended, bio, website, IFNULL(username, '')
+    FROM users WHERE email = ?`
+	return m.getOne(query, email)
+}
+
+// GetByUsername returns the user with username, or the user who last gave
+// it up. In the second case User.Username differs from username, and
+// callers can redirect to the new one. Usernames match whatever their case.
+func (m *UserModel) GetByUsername(username string) (*User, error) {
+	query := `SELECT id, name, email, created, verified, role, suspended, bio, website, IFNULL(username, '')
+    FROM users WHERE username = ?`
+	user, err := m.getOne(query, username)
+	if !errors.Is(err, ErrNoRecord) {
+		return user, err
+	}
+	query = `SELECT u.id, u.name, u.email, u.created, u.verified, u.role, u.suspended, u.bio, u.website,
+    IFNULL(u.username, '') FROM username_history h JOIN users u ON u.id = h.user_id WHERE h.username = ?`
+	return m.getOne(query, username)
+}
+
+func (m *UserModel) getOne(query string, args ...any) (*User, error) {
 	var user User
-	err := m.DB.QueryRow(query, id).Scan(&user.Name, &user.Email, &user.Created)
+	err := m.DB.QueryRow(query, args...).Scan(&user.ID, &user.Name, &user.Email, &user.Created, &user.Verified,
+		&user.Role, &user.Suspended, &user.Bio, &user.Website, &user.Username)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, ErrNoRecord
@@ -94,24 +215,217 @@ func (m *UserModel) Get(id int) (*User, error) {
 }
 
 func (m *UserModel) PasswordUpdate(id int, currentPassword, newPassword string) error {
+	if err := m.CheckPassword(id, currentPassword); err != nil {
+		return err
+	}
+	return m.PasswordSet(id, newPassword)
+}
+
+// PasswordSet replaces the user's password without checking the current one,
+// for when they have proven who they are some other way.
+func (m *UserModel) PasswordSet(id int, newPassword string) error {
+	newHashedPassword, err := m.hasher().Hash(newPassword)
+	if err != nil {
+		return err
+	}
+	query := `UPDATE users SET hashed_password = ? WHERE id = ?`
+	result, err := m.DB.Exec(query, newHashedPassword, id)
+	if err != nil {
+		return err
+	}
+	return checkAffected(result)
+}
+
+// CheckPassword returns ErrInvalidCredentials unless password is the
+// user's current password. It is used to confirm sensitive changes.
+func (m *UserModel) CheckPassword(id int, password string) error {
 	query := `SELECT hashed_password FROM users WHERE id = ?`
-	var currentHashedPassword []byte
-	err := m.DB.QueryRow(query, id).Scan(&currentHashedPassword)
+	var hashedPassword string
+	err := m.DB.QueryRow(query, id).Scan(&hashedPassword)
 	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrNoRecord
+		}
 		return err
 	}
-	err = bcrypt.CompareHashAndPassword(currentHashedPassword, []byte(currentPassword))
+	err = m.hasher().Compare(hashedPassword, password)
 	if err != nil {
-		if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+		if errors.Is(err, passwords.ErrMismatch) {
 			return ErrInvalidCredentials
 		}
 		return err
 	}
-	newHashedPassword, err := bcrypt.GenerateFromPassword([]byte(newPassword), 12)
+	return nil
+}
+
+// SetVerified marks the user's email address as verified. The address must
+// still be the one the verification link was sent to; ErrNoRecord is
+// returned if it has changed or was already verified.
+func (m *UserModel) SetVerified(id int, email string) error {
+	query := `UPDATE users SET verified = TRUE WHERE id = ? AND email = ? AND NOT verified`
+	result, err := m.DB.Exec(query, id, email)
 	if err != nil {
 		return err
 	}
-	query = `UPDATE users SET hashed_password = ? WHERE id = ?`
-	_, err = m.DB.Exec(query, string(newHashedPassword), id)
+	return checkAffected(result)
+}
+
+// SetEmail changes the user's email address from oldEmail to newEmail and
+// marks it as verified, since the change is only made once the user has
+// followed a link sent to the new address. ErrNoRecord is returned if the
+// address is no longer oldEmail, and ErrDuplicateEmail if another account
+// has taken newEmail in the meantime.
+func (m *UserModel) SetEmail(id int, oldEmail, newEmail string) error {
+	query := `UPDATE users SET email = ?, verified = TRUE WHERE id = ? AND email = ?`
+	result, err := m.DB.Exec(query, newEmail, id, oldEmail)
+	if err != nil {
+		if isDuplicate(err, "users_uc_email") {
+			return ErrDuplicateEmail
+		}
+		return err
+	}
+	return checkAffected(result)
+}
+
+func (m *UserModel) UpdateProfile(id int, name, bio, website string) error {
+	query := `UPDATE users SET name = ?, bio = ?, website = ? WHERE id = ?`
+	_, err := m.DB.Exec(query, name, bio, website, id)
 	return err
 }
+
+// SetUsername gives the user a new username, or none when it is empty. The
+// old one is kept in username_history so links to it can be redirected.
+// ErrDuplicateUsername is returned if another account has the username.
+func (m *UserModel) SetUsername(id int, username string) error {
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var old string
+	query := `SELECT IFNULL(username, '') FROM users WHERE id = ? FOR UPDATE`
+	if err := tx.QueryRow(query, id).Scan(&old); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrNoRecord
+		}
+		return err
+	}
+	if old == username {
+		return nil
+	}
+
+	query = `UPDATE users SET username = NULLIF(?, '') WHERE id = ?`
+	if _, err := tx.Exec(query, username, id); err != nil {
+		if isDuplicate(err, "users_uc_username") {
+			return ErrDuplicateUsername
+		}
+		return err
+	}
+	// A change of case only is not a new name.
+	if old != "" && !strings.EqualFold(old, username) {
+		query = `INSERT INTO username_history (username, user_id, created) VALUES (?, ?, UTC_TIMESTAMP())
+        ON DUPLICATE KEY UPDATE user_id = VALUES(user_id), created = VALUES(created)`
+		if _, err := tx.Exec(query, old, id); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// PreviousUsernames returns the usernames the user has given up, the most
+// recent first.
+func (m *UserModel) PreviousUsernames(id int) ([]string, error) {
+	query := `SELECT username FROM username_history WHERE user_id = ? ORDER BY created DESC`
+	rows, err := m.DB.Query(query, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	usernames := []string{}
+	for rows.Next() {
+		var username string
+		if err := rows.Scan(&username); err != nil {
+			return nil, err
+		}
+		usernames = append(usernames, username)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return usernames, nil
+}
+
+// isDuplicate reports whether err is a MySQL duplicate entry error for the
+// unique constraint named key.
+func isDuplicate(err error, key string) bool {
+	var mysqlError *mysql.MySQLError
+	return errors.As(err, &mysqlError) && mysqlError.Number == 1062 &&
+		strings.Contains(mysqlError.Message, key)
+}
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// Search returns up to limit users whose name, username or email address
+// contains query, newest first. An empty query lists every user.
+func (m *UserModel) Search(query string, limit int) ([]*User, error) {
+	pattern := "%" + likeEscaper.Replace(query) + "%"
+	stmt := `SELECT id, name, email, created, verified, role, suspended, IFNULL(username, '') FROM users
+    WHERE name LIKE ? OR email LIKE ? OR username LIKE ? ORDER BY id DESC LIMIT ?`
+	rows, err := m.DB.Query(stmt, pattern, pattern, pattern, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	users := []*User{}
+	for rows.Next() {
+		u := &User{}
+		err := rows.Scan(&u.ID, &u.Name, &u.Email, &u.Created, &u.Verified, &u.Role, &u.Suspended, &u.Username)
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, u)
+	}
+	return users, rows.Err()
+}
+
+func (m *UserModel) SetRole(id int, role string) error {
+	query := `UPDATE users SET role = ? WHERE id = ?`
+	_, err := m.DB.Exec(query, role, id)
+	return err
+}
+
+func (m *UserModel) SetSuspended(id int, suspended bool) error {
+	query := `UPDATE users SET suspended = ? WHERE id = ?`
+	_, err := m.DB.Exec(query, suspended, id)
+	return err
+}
+
+// Delete removes the user. Their tokens, passkeys and other records go with
+// them through the foreign keys, and so do their snippets unless
+// anonymizeSnippets is set, in which case the snippets are detached from
+// the user in the same transaction so that they outlive the account without
+// saying who wrote them.
+func (m *UserModel) Delete(id int, anonymizeSnippets bool) error {
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if anonymizeSnippets {
+		if _, err := tx.Exec(`UPDATE snippets SET user_id = NULL WHERE user_id = ?`, id); err != nil {
+			return err
+		}
+	}
+	result, err := tx.Exec(`DELETE FROM users WHERE id = ?`, id)
+	if err != nil {
+		return err
+	}
+	if err := checkAffected(result); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,197 @@
+package models
+
+import (
+	"database/sql"
+	"strings"
+	"time"
+)
+
+const (
+	EventSnippetCreated = "snippet.created"
+	EventSnippetUpdated = "snippet.updated"
+	EventSnippetDeleted = "snippet.deleted"
+)
+
+var WebhookEvents = []string{EventSnippetCreated, EventSnippetUpdated, EventSnippetDeleted}
+
+const (
+	DeliveryPending   = "pending"
+	DeliveryDelivered = "delivered"
+	DeliveryFailed    = "failed"
+)
+
+type Webhook struct {
+	ID      int
+	UserID  int
+	URL     string
+	Secret  string
+	Events  []string
+	Created time.Time
+}
+
+type WebhookDelivery struct {
+	ID             int
+	WebhookID      int
+	URL            string
+	Secret         string
+	Event          string
+	Payload        []byte
+	Status         string
+	Attempts       int
+	ResponseStatus int
+	LastError      string
+	Created        time.Time
+	NextAttempt    time.Time
+}
+
+type WebhookModelInterface interface {
+	Insert(userID int, url, secret string, events []string) (int, error)
+	ByUser(userID int) ([]*Webhook, error)
+	Delete(id, userID int) error
+	Deliveries(userID, limit int) ([]*WebhookDelivery, error)
+
+	ForEvent(userID int, event string) ([]*Webhook, error)
+	Enqueue(webhookID int, event string, payload []byte) error
+	Due(limit int) ([]*WebhookDelivery, error)
+	Delivered(id, responseStatus int) error
+	Retry(id, responseStatus int, lastError string, delay time.Duration) error
+	Failed(id, responseStatus int, lastError string) error
+}
+
+type WebhookModel struct {
+	DB *sql.DB
+}
+
+func (m *WebhookModel) Insert(userID int, url, secret string, events []string) (int, error) {
+	query := `INSERT INTO webhooks (user_id, url, secret, events, created)
+    VALUES (?, ?, ?, ?, UTC_TIMESTAMP())`
+	result, err := m.DB.Exec(query, userID, url, secret, strings.Join(events, ","))
+	if err != nil {
+		return 0, err
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+	return int(id), nil
+}
+
+func (m *WebhookModel) ByUser(userID int) ([]*Webhook, error) {
+	query := `SELECT id, user_id, url, secret, events, created FROM webhooks
+    WHERE user_id = ? ORDER BY id`
+	return m.query(query, userID)
+}
+
+func (m *WebhookModel) Delete(id, userID int) error {
+	query := `DELETE FROM webhooks WHERE id = ? AND user_id = ?`
+	result, err := m.DB.Exec(query, id, userID)
+	if err != nil {
+		return err
+	}
+	return checkAffected(result)
+}
+
+func (m *WebhookModel) ForEvent(userID int, event string) ([]*Webhook, error) {
+	query := `SELECT id, user_id, url, secret, events, created FROM webhooks
+    WHERE user_id = ? AND FIND_IN_SET(?, events) > 0`
+	return m.query(query, userID, event)
+}
+
+func (m *WebhookModel) Enqueue(webhookID int, event string, payload []byte) error {
+	query := `INSERT INTO webhook_deliveries (webhook_id, event, payload, status, attempts, created, next_attempt)
+    VALUES (?, ?, ?, ?, 0, UTC_TIMESTAMP(), UTC_TIMESTAMP())`
+	_, err := m.DB.Exec(query, webhookID, event, payload, DeliveryPending)
+	return err
+}
+
+func (m *WebhookModel) Due(limit int) ([]*WebhookDelivery, error) {
+	query := `SELECT d.id, d.webhook_id, w.url, w.secret, d.event, d.payload, d.status, d.attempts,
+    d.response_status, d.last_error, d.created, d.next_attempt
+    FROM webhook_deliveries d INNER JOIN webhooks w ON w.id = d.webhook_id
+    WHERE d.status = ? AND d.next_attempt <= UTC_TIMESTAMP()
+    ORDER BY d.next_attempt LIMIT ?`
+	return m.queryDeliveries(query, DeliveryPending, limit)
+}
+
+// Deliveries returns the user's latest deliveries first, or all of them
+// when limit is 0.
+func (m *WebhookModel) Deliveries(userID, limit int) ([]*WebhookDelivery, error) {
+	query := `SELECT d.id, d.webhook_id, w.url, w.secret, d.event, d.payload, d.status, d.attempts,
+    d.response_status, d.last_error, d.created, d.next_attempt
+    FROM webhook_deliveries d INNER JOIN webhooks w ON w.id = d.webhook_id
+    WHERE w.user_id = ? ORDER BY d.id DESC`
+	if limit <= 0 {
+		return m.queryDeliveries(query, userID)
+	}
+	return m.queryDeliveries(query+" LIMIT ?", userID, limit)
+}
+
+func (m *WebhookModel) Delivered(id, responseStatus int) error {
+	query := `UPDATE webhook_deliveries SET status = ?, attempts = attempts + 1,
+    response_status = ?, last_error = '' WHERE id = ?`
+	_, err := m.DB.Exec(query, DeliveryDelivered, responseStatus, id)
+	return err
+}
+
+func (m *WebhookModel) Retry(id, responseStatus int, lastError string, delay time.Duration) error {
+	query := `UPDATE webhook_deliveries SET attempts = attempts + 1, response_status = ?, last_error = ?,
+    next_attempt = DATE_ADD(UTC_TIMESTAMP(), INTERVAL ? SECOND) WHERE id = ?`
+	_, err := m.DB.Exec(query, responseStatus, lastError, int(delay.Seconds()), id)
+	return err
+}
+
+func (m *WebhookModel) Failed(id, responseStatus int, lastError string) error {
+	query := `UPDATE webhook_deliveries SET status = ?, attempts = attempts + 1,
+    response_status = ?, last_error = ? WHERE id = ?`
+	_, err := m.DB.Exec(query, DeliveryFailed, responseStatus, lastError, id)
+	return err
+}
+
+func (m *WebhookModel) query(query string, args ...any) ([]*Webhook, error) {
+	rows, err := m.DB.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	webhooks := []*Webhook{}
+	for rows.Next() {
+		var (
+			w      Webhook
+			events string
+		)
+		err := rows.Scan(&w.ID, &w.UserID, &w.URL, &w.Secret, &events, &w.Created)
+		if err != nil {
+			return nil, err
+		}
+		w.Events = strings.Split(events, ",")
+		webhooks = append(webhooks, &w)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return webhooks, nil
+}
+
+func (m *WebhookModel) queryDeliveries(query string, args ...any) ([]*WebhookDelivery, error) {
+	rows, err := m.DB.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	deliveries := []*WebhookDelivery{}
+	for rows.Next() {
+		var d WebhookDelivery
+		err := rows.Scan(&d.ID, &d.WebhookID, &d.URL, &d.Secret, &d.Event, &d.Payload, &d.Status,
+			&d.Attempts, &d.ResponseStatus, &d.LastError, &d.Created, &d.NextAttempt)
+		if err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, &d)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return deliveries, nil
+}
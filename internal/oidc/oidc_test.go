@@ -0,0 +1,128 @@
+package oidc_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/MohammadLashkari/snippetbox/internal/assert"
+	"github.com/MohammadLashkari/snippetbox/internal/oidc"
+	"github.com/MohammadLashkari/snippetbox/internal/oidc/oidctest"
+)
+
+const redirectURL = "https://snippetbox.example.com/callback"
+
+func newProvider(t *testing.T) (*oidctest.Server, *oidc.Provider) {
+	idp := oidctest.NewServer(oidctest.User{Subject: "42", Email: "bob@example.com", EmailVerified: true, Name: "Bob"})
+	t.Cleanup(idp.Close)
+	config := oidc.Config{ClientID: oidctest.ClientID, ClientSecret: oidctest.ClientSecret, Scopes: []string{"email", "profile"}}
+	p, err := oidc.Discover(context.Background(), idp.URL, config, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return idp, p
+}
+
+// authorize follows the provider's redirect back to us and returns the
+// code and state it carries.
+func authorize(t *testing.T, authURL string) (string, string) {
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+	resp, err := client.Get(authURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusFound {
+		t.Fatalf("authorize: %s", resp.Status)
+	}
+	loc, err := url.Parse(resp.Header.Get("Location"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return loc.Query().Get("code"), loc.Query().Get("state")
+}
+
+func TestAuthorizationCodeFlow(t *testing.T) {
+	_, p := newProvider(t)
+	verifier, _ := oidc.RandomString()
+	authURL := p.AuthCodeURL(redirectURL, "state", "nonce", oidc.S256Challenge(verifier))
+	code, state := authorize(t, authURL)
+	assert.Equal(t, state, "state")
+
+	t.Run("Wrong verifier", func(t *testing.T) {
+		code, _ := authorize(t, authURL)
+		_, err := p.Exchange(context.Background(), code, redirectURL, "wrong", "nonce")
+		if err == nil {
+			t.Fatal("want an error")
+		}
+	})
+
+	t.Run("Wrong nonce", func(t *testing.T) {
+		code, _ := authorize(t, authURL)
+		_, err := p.Exchange(context.Background(), code, redirectURL, verifier, "other")
+		assert.Equal(t, errors.Is(err, oidc.ErrInvalidToken), true)
+	})
+
+	claims, err := p.Exchange(context.Background(), code, redirectURL, verifier, "nonce")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, claims.Subject, "42")
+	assert.Equal(t, claims.Email, "bob@example.com")
+	assert.Equal(t, bool(claims.EmailVerified), true)
+	assert.Equal(t, claims.Name, "Bob")
+
+	_, err = p.Exchange(context.Background(), code, redirectURL, verifier, "nonce")
+	if err == nil {
+		t.Error("want an error reusing a code")
+	}
+}
+
+func TestVerify(t *testing.T) {
+	idp, p := newProvider(t)
+	now := time.Now()
+	claims := func(change func(map[string]any)) map[string]any {
+		c := map[string]any{
+			"iss":   idp.URL,
+			"sub":   "42",
+			"aud":   []string{"other", oidctest.ClientID},
+			"azp":   oidctest.ClientID,
+			"iat":   now.Unix(),
+			"exp":   now.Add(time.Minute).Unix(),
+			"nonce": "nonce",
+		}
+		if change != nil {
+			change(c)
+		}
+		return c
+	}
+	valid := idp.Sign(claims(nil))
+
+	tests := []struct {
+		name    string
+		token   string
+		wantErr bool
+	}{
+		{"Valid", valid, false},
+		{"Tampered", valid[:len(valid)-4] + "AAAA", true},
+		{"Malformed", "not.a-token", true},
+		{"Other issuer", idp.Sign(claims(func(c map[string]any) { c["iss"] = "https://evil.example.com" })), true},
+		{"Other audience", idp.Sign(claims(func(c map[string]any) { c["aud"] = "other" })), true},
+		{"Only audience", idp.Sign(claims(func(c map[string]any) { c["aud"] = oidctest.ClientID; delete(c, "azp") })), false},
+		{"Several audiences without azp", idp.Sign(claims(func(c map[string]any) { delete(c, "azp") })), true},
+		{"Authorized for another party", idp.Sign(claims(func(c map[string]any) { c["azp"] = "other" })), true},
+		{"Expired", idp.Sign(claims(func(c map[string]any) { c["exp"] = now.Add(-time.Hour).Unix() })), true},
+		{"Issued in the future", idp.Sign(claims(func(c map[string]any) { c["iat"] = now.Add(time.Hour).Unix() })), true},
+		{"No subject", idp.Sign(claims(func(c map[string]any) { delete(c, "sub") })), true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := p.Verify(context.Background(), tt.token, "nonce")
+			assert.Equal(t, err != nil, tt.wantErr)
+		})
+	}
+}
@@ -0,0 +1,177 @@
+// Package passwords hashes and checks passwords with argon2id or bcrypt,
+// and tells when a stored hash should be replaced because it was made with
+// another algorithm or weaker parameters.
+package passwords
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	Argon2id = "argon2id"
+	Bcrypt   = "bcrypt"
+)
+
+var (
+	ErrMismatch    = errors.New("passwords: hash and password do not match")
+	ErrInvalidHash = errors.New("passwords: invalid hash")
+)
+
+var encoding = base64.RawStdEncoding
+
+// Argon2idParams are the cost parameters of argon2id. Memory is in KiB.
+type Argon2idParams struct {
+	Memory      uint32
+	Iterations  uint32
+	Parallelism uint8
+	SaltLength  uint32
+	KeyLength   uint32
+}
+
+// DefaultArgon2idParams follow the second recommended option of RFC 9106
+// with less parallelism, which costs about 64 MiB and tens of milliseconds.
+var DefaultArgon2idParams = Argon2idParams{
+	Memory:      64 * 1024,
+	Iterations:  3,
+	Parallelism: 2,
+	SaltLength:  16,
+	KeyLength:   32,
+}
+
+// DefaultBcryptCost is the cost passwords were hashed with before argon2id
+// was introduced.
+const DefaultBcryptCost = 12
+
+// Hasher hashes new passwords with Algorithm, using Argon2id or BcryptCost
+// as its parameters. It checks hashes made by either algorithm.
+type Hasher struct {
+	Algorithm  string
+	Argon2id   Argon2idParams
+	BcryptCost int
+}
+
+// Default returns a Hasher using argon2id with the default parameters.
+func Default() *Hasher {
+	return &Hasher{
+		Algorithm:  Argon2id,
+		Argon2id:   DefaultArgon2idParams,
+		BcryptCost: DefaultBcryptCost,
+	}
+}
+
+// Validate reports whether the algorithm and its parameters are usable.
+func (h *Hasher) Validate() error {
+	switch h.Algorithm {
+	case Argon2id:
+		p := h.Argon2id
+		if p.Memory < 8*uint32(p.Parallelism) || p.Iterations < 1 || p.Parallelism < 1 ||
+			p.SaltLength < 8 || p.KeyLength < 16 {
+			return fmt.Errorf("passwords: invalid argon2id parameters m=%d,t=%d,p=%d",
+				p.Memory, p.Iterations, p.Parallelism)
+		}
+	case Bcrypt:
+		if h.BcryptCost < bcrypt.MinCost || h.BcryptCost > bcrypt.MaxCost {
+			return fmt.Errorf("passwords: bcrypt cost must be between %d and %d", bcrypt.MinCost, bcrypt.MaxCost)
+		}
+	default:
+		return fmt.Errorf("passwords: unknown algorithm %q", h.Algorithm)
+	}
+	return nil
+}
+
+// Hash returns a hash of password that carries its algorithm and
+// parameters, in the PHC string format for argon2id and the usual "$2a$"
+// format for bcrypt.
+func (h *Hasher) Hash(password string) (string, error) {
+	if err := h.Validate(); err != nil {
+		return "", err
+	}
+	if h.Algorithm == Bcrypt {
+		hash, err := bcrypt.GenerateFromPassword([]byte(password), h.BcryptCost)
+		return string(hash), err
+	}
+
+	p := h.Argon2id
+	salt := make([]byte, p.SaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+	key := argon2.IDKey([]byte(password), salt, p.Iterations, p.Memory, p.Parallelism, p.KeyLength)
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s", argon2.Version,
+		p.Memory, p.Iterations, p.Parallelism, encoding.EncodeToString(salt), encoding.EncodeToString(key)), nil
+}
+
+// Compare returns nil if hash is a hash of password, made by either
+// algorithm, and ErrMismatch if it is not.
+func (h *Hasher) Compare(hash, password string) error {
+	if !strings.HasPrefix(hash, "$argon2id$") {
+		err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
+		switch {
+		case errors.Is(err, bcrypt.ErrMismatchedHashAndPassword):
+			return ErrMismatch
+		case err != nil:
+			return ErrInvalidHash
+		}
+		return nil
+	}
+
+	p, salt, key, err := decodeArgon2id(hash)
+	if err != nil {
+		return err
+	}
+	got := argon2.IDKey([]byte(password), salt, p.Iterations, p.Memory, p.Parallelism, uint32(len(key)))
+	if subtle.ConstantTimeCompare(got, key) != 1 {
+		return ErrMismatch
+	}
+	return nil
+}
+
+// NeedsRehash reports whether hash was made with another algorithm than
+// the hasher's, or with weaker parameters, and should be replaced the next
+// time the password is known.
+func (h *Hasher) NeedsRehash(hash string) bool {
+	if h.Algorithm == Bcrypt {
+		cost, err := bcrypt.Cost([]byte(hash))
+		return err != nil || cost < h.BcryptCost
+	}
+
+	p, salt, key, err := decodeArgon2id(hash)
+	if err != nil {
+		return true
+	}
+	want := h.Argon2id
+	return p.Memory < want.Memory || p.Iterations < want.Iterations || p.Parallelism < want.Parallelism ||
+		uint32(len(salt)) < want.SaltLength || uint32(len(key)) < want.KeyLength
+}
+
+func decodeArgon2id(hash string) (p Argon2idParams, salt, key []byte, err error) {
+	parts := strings.Split(hash, "$")
+	if len(parts) != 6 || parts[1] != Argon2id {
+		return p, nil, nil, ErrInvalidHash
+	}
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return p, nil, nil, ErrInvalidHash
+	}
+	_, err = fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &p.Memory, &p.Iterations, &p.Parallelism)
+	if err != nil || p.Iterations < 1 || p.Parallelism < 1 {
+		return p, nil, nil, ErrInvalidHash
+	}
+	if salt, err = encoding.DecodeString(parts[4]); err != nil {
+		return p, nil, nil, ErrInvalidHash
+	}
+	if key, err = encoding.DecodeString(parts[5]); err != nil || len(key) == 0 {
+		return p, nil, nil, ErrInvalidHash
+	}
+	p.SaltLength = uint32(len(salt))
+	p.KeyLength = uint32(len(key))
+	return p, salt, key, nil
+}
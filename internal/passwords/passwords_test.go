@@ -0,0 +1,132 @@
+package passwords
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/MohammadLashkari/snippetbox/internal/assert"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// testHasher uses cheap parameters to keep the tests fast.
+func testHasher(algorithm string) *Hasher {
+	return &Hasher{
+		Algorithm:  algorithm,
+		Argon2id:   Argon2idParams{Memory: 64, Iterations: 1, Parallelism: 1, SaltLength: 16, KeyLength: 32},
+		BcryptCost: bcrypt.MinCost,
+	}
+}
+
+func TestHashAndCompare(t *testing.T) {
+	for _, algorithm := range []string{Argon2id, Bcrypt} {
+		t.Run(algorithm, func(t *testing.T) {
+			h := testHasher(algorithm)
+			hash, err := h.Hash("pa$$word")
+			if err != nil {
+				t.Fatal(err)
+			}
+			assert.Equal(t, h.Compare(hash, "pa$$word"), nil)
+			assert.Equal(t, errors.Is(h.Compare(hash, "password"), ErrMismatch), true)
+			assert.Equal(t, h.NeedsRehash(hash), false)
+
+			other, err := h.Hash("pa$$word")
+			if err != nil {
+				t.Fatal(err)
+			}
+			assert.Equal(t, hash == other, false)
+		})
+	}
+}
+
+func TestArgon2idFormat(t *testing.T) {
+	hash, err := testHasher(Argon2id).Hash("pa$$word")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, strings.HasPrefix(hash, "$argon2id$v=19$m=64,t=1,p=1$"), true)
+}
+
+func TestCompareAcrossAlgorithms(t *testing.T) {
+	old, err := testHasher(Bcrypt).Hash("pa$$word")
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := testHasher(Argon2id)
+	assert.Equal(t, h.Compare(old, "pa$$word"), nil)
+	assert.Equal(t, h.NeedsRehash(old), true)
+}
+
+func TestNeedsRehash(t *testing.T) {
+	weak, err := testHasher(Argon2id).Hash("pa$$word")
+	if err != nil {
+		t.Fatal(err)
+	}
+	bcryptHash, err := testHasher(Bcrypt).Hash("pa$$word")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		hasher func(h *Hasher)
+		hash   string
+		want   bool
+	}{
+		{"Same parameters", func(h *Hasher) {}, weak, false},
+		{"More memory", func(h *Hasher) { h.Argon2id.Memory = 128 }, weak, true},
+		{"More iterations", func(h *Hasher) { h.Argon2id.Iterations = 2 }, weak, true},
+		{"More parallelism", func(h *Hasher) { h.Argon2id.Parallelism = 2; h.Argon2id.Memory = 64 }, weak, true},
+		{"Longer key", func(h *Hasher) { h.Argon2id.KeyLength = 64 }, weak, true},
+		{"Less memory", func(h *Hasher) { h.Argon2id.Memory = 32 }, weak, false},
+		{"Bcrypt hash", func(h *Hasher) {}, bcryptHash, true},
+		{"Higher bcrypt cost", func(h *Hasher) { h.Algorithm = Bcrypt; h.BcryptCost = bcrypt.MinCost + 1 }, bcryptHash, true},
+		{"Same bcrypt cost", func(h *Hasher) { h.Algorithm = Bcrypt }, bcryptHash, false},
+		{"Argon2id hash with bcrypt", func(h *Hasher) { h.Algorithm = Bcrypt }, weak, true},
+		{"Malformed", func(h *Hasher) {}, "$argon2id$v=19$m=64", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := testHasher(Argon2id)
+			tt.hasher(h)
+			assert.Equal(t, h.NeedsRehash(tt.hash), tt.want)
+		})
+	}
+}
+
+func TestCompareInvalidHash(t *testing.T) {
+	h := testHasher(Argon2id)
+	tests := []string{
+		"",
+		"plaintext",
+		"$argon2id$v=18$m=64,t=1,p=1$c2FsdHNhbHRzYWx0c2FsdA$a2V5",
+		"$argon2id$v=19$m=64,t=0,p=1$c2FsdHNhbHRzYWx0c2FsdA$a2V5",
+		"$argon2id$v=19$m=64,t=1,p=1$!!!$a2V5",
+		"$argon2id$v=19$m=64,t=1,p=1$c2FsdHNhbHRzYWx0c2FsdA$",
+	}
+	for _, hash := range tests {
+		assert.Equal(t, errors.Is(h.Compare(hash, "pa$$word"), ErrInvalidHash), true)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		hasher func(h *Hasher)
+		valid  bool
+	}{
+		{"Default", func(h *Hasher) { *h = *Default() }, true},
+		{"Bcrypt", func(h *Hasher) { h.Algorithm = Bcrypt }, true},
+		{"Unknown algorithm", func(h *Hasher) { h.Algorithm = "md5" }, false},
+		{"Zero iterations", func(h *Hasher) { h.Argon2id.Iterations = 0 }, false},
+		{"Too little memory", func(h *Hasher) { h.Argon2id.Memory = 4 }, false},
+		{"Bcrypt cost too high", func(h *Hasher) { h.Algorithm = Bcrypt; h.BcryptCost = 40 }, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := testHasher(Argon2id)
+			tt.hasher(h)
+			assert.Equal(t, h.Validate() == nil, tt.valid)
+		})
+	}
+}
@@ -0,0 +1,104 @@
+// Package ratelimit implements a keyed token-bucket rate limiter whose
+// memory use is bounded by a maximum number of tracked clients.
+package ratelimit
+
+import (
+	"container/list"
+	"math"
+	"sync"
+	"time"
+)
+
+type bucket struct {
+	key    string
+	tokens float64
+	last   time.Time
+}
+
+// Limiter allows Rate events per second for each key, with bursts of up to
+// Burst events. Buckets are kept in least-recently-used order: once a
+// bucket has been idle long enough to refill it is dropped, and when
+// MaxClients buckets exist the least recently used one is evicted. Both
+// only ever forget a client that had spare tokens or was the quietest.
+type Limiter struct {
+	rate       float64
+	burst      float64
+	maxClients int
+
+	mu      sync.Mutex
+	buckets map[string]*list.Element
+	lru     *list.List
+
+	// now is replaced in tests.
+	now func() time.Time
+}
+
+func New(rate float64, burst, maxClients int) *Limiter {
+	return &Limiter{
+		rate:       rate,
+		burst:      float64(burst),
+		maxClients: maxClients,
+		buckets:    make(map[string]*list.Element),
+		lru:        list.New(),
+		now:        time.Now,
+	}
+}
+
+// Per returns the rate of n events every d, for use with New.
+func Per(n int, d time.Duration) float64 {
+	return float64(n) / d.Seconds()
+}
+
+// Allow takes a token from the bucket of key. When none is left it reports
+// false and how long until the next token becomes available.
+func (l *Limiter) Allow(key string) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	l.evictIdle(now)
+
+	var b *bucket
+	if e, ok := l.buckets[key]; ok {
+		l.lru.MoveToFront(e)
+		b = e.Value.(*bucket)
+		b.tokens = math.Min(l.burst, b.tokens+now.Sub(b.last).Seconds()*l.rate)
+		b.last = now
+	} else {
+		if l.lru.Len() >= l.maxClients {
+			l.remove(l.lru.Back())
+		}
+		b = &bucket{key: key, tokens: l.burst, last: now}
+		l.buckets[key] = l.lru.PushFront(b)
+	}
+
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0
+	}
+	wait := time.Duration((1 - b.tokens) / l.rate * float64(time.Second))
+	return false, wait
+}
+
+// Len returns the number of clients currently tracked.
+func (l *Limiter) Len() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.lru.Len()
+}
+
+// evictIdle drops buckets, oldest first, that would be full by now anyway.
+func (l *Limiter) evictIdle(now time.Time) {
+	refill := time.Duration(l.burst / l.rate * float64(time.Second))
+	for e := l.lru.Back(); e != nil; e = l.lru.Back() {
+		if now.Sub(e.Value.(*bucket).last) < refill {
+			return
+		}
+		l.remove(e)
+	}
+}
+
+func (l *Limiter) remove(e *list.Element) {
+	delete(l.buckets, e.Value.(*bucket).key)
+	l.lru.Remove(e)
+}
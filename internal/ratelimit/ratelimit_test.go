@@ -0,0 +1,65 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/MohammadLashkari/snippetbox/internal/assert"
+)
+
+type clock struct {
+	t time.Time
+}
+
+func (c *clock) now() time.Time {
+	return c.t
+}
+
+func newTestLimiter(rate float64, burst, maxClients int) (*Limiter, *clock) {
+	c := &clock{t: time.Date(2024, 06, 29, 21, 05, 0, 0, time.UTC)}
+	l := New(rate, burst, maxClients)
+	l.now = c.now
+	return l, c
+}
+
+func TestAllow(t *testing.T) {
+	l, c := newTestLimiter(Per(1, time.Second), 3, 10)
+
+	for range 3 {
+		ok, _ := l.Allow("a")
+		assert.Equal(t, ok, true)
+	}
+	ok, wait := l.Allow("a")
+	assert.Equal(t, ok, false)
+	assert.Equal(t, wait, time.Second)
+
+	ok, _ = l.Allow("b")
+	assert.Equal(t, ok, true)
+
+	c.t = c.t.Add(500 * time.Millisecond)
+	ok, wait = l.Allow("a")
+	assert.Equal(t, ok, false)
+	assert.Equal(t, wait, 500*time.Millisecond)
+
+	c.t = c.t.Add(500 * time.Millisecond)
+	ok, _ = l.Allow("a")
+	assert.Equal(t, ok, true)
+}
+
+func TestEviction(t *testing.T) {
+	l, c := newTestLimiter(Per(1, time.Second), 2, 2)
+
+	l.Allow("a")
+	l.Allow("a")
+	l.Allow("b")
+	l.Allow("c")
+	assert.Equal(t, l.Len(), 2)
+
+	// "a" was the least recently used client, so it starts over.
+	ok, _ := l.Allow("a")
+	assert.Equal(t, ok, true)
+
+	c.t = c.t.Add(2 * time.Second)
+	l.Allow("d")
+	assert.Equal(t, l.Len(), 1)
+}
@@ -0,0 +1,37 @@
+package signing
+
+import (
+	"testing"
+	"time"
+
+	"github.com/MohammadLashkari/snippetbox/internal/assert"
+)
+
+func TestSignVerify(t *testing.T) {
+	s := &Signer{Key: []byte("0123456789abcdef0123456789abcdef")}
+	token := s.Sign("verify-email", "1:foo@example.com", time.Hour)
+
+	tests := []struct {
+		name        string
+		signer      *Signer
+		purpose     string
+		token       string
+		wantPayload string
+		wantErr     error
+	}{
+		{"Valid", s, "verify-email", token, "1:foo@example.com", nil},
+		{"Other purpose", s, "reset-password", token, "", ErrInvalid},
+		{"Other key", &Signer{Key: []byte("other")}, "verify-email", token, "", ErrInvalid},
+		{"Tampered", s, "verify-email", "MjpiYXJAZXhhbXBsZS5jb20" + token[len("MTpmb29AZXhhbXBsZS5jb20"):], "", ErrInvalid},
+		{"Expired", s, "verify-email", s.Sign("verify-email", "1", -time.Second), "", ErrExpired},
+		{"Garbage", s, "verify-email", "garbage", "", ErrInvalid},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			payload, err := tt.signer.Verify(tt.purpose, tt.token)
+			assert.Equal(t, err, tt.wantErr)
+			assert.Equal(t, payload, tt.wantPayload)
+		})
+	}
+}
@@ -0,0 +1,56 @@
+package totp
+
+import (
+	"encoding/base32"
+	"testing"
+	"time"
+
+	"github.com/MohammadLashkari/snippetbox/internal/assert"
+)
+
+// The SHA-1 test vectors of RFC 6238, appendix B, truncated to six digits.
+func TestCode(t *testing.T) {
+	secret := base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString([]byte("12345678901234567890"))
+
+	tests := []struct {
+		unix int64
+		want string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+		{20000000000, "353130"},
+	}
+
+	for _, tt := range tests {
+		got, err := Code(secret, Counter(time.Unix(tt.unix, 0)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.Equal(t, got, tt.want)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	secret, err := GenerateSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Unix(1234567890, 0)
+	code, _ := Code(secret, Counter(now))
+
+	counter, ok := Validate(secret, code, now)
+	assert.Equal(t, ok, true)
+	assert.Equal(t, counter, Counter(now))
+
+	_, ok = Validate(secret, code, now.Add(Period))
+	assert.Equal(t, ok, true)
+
+	_, ok = Validate(secret, code, now.Add(2*Period))
+	assert.Equal(t, ok, false)
+
+	_, ok = Validate(secret, "12345", now)
+	assert.Equal(t, ok, false)
+}
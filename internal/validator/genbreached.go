@@ -0,0 +1,76 @@
+//go:build ignore
+
+// Genbreached builds breached.gz from passwords.txt, a list of passwords
+// one per line. go generate runs it as
+//
+//	go run genbreached.go passwords.txt breached.gz
+//
+// The output holds the sorted SHA-1 digests of the passwords, so a bigger
+// list such as a download of Pwned Passwords can be used in the same way.
+//
+// passwords.txt is the list of common passwords that ships with zxcvbn-go
+// (MIT licensed, see passwords-license.txt), plus the passwords of an
+// earlier, hand-made list that it misses.
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha1"
+	"log"
+	"os"
+	"sort"
+)
+
+func main() {
+	if len(os.Args) != 3 {
+		log.Fatal("usage: go run genbreached.go passwords.txt breached.gz")
+	}
+	in, err := os.Open(os.Args[1])
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer in.Close()
+
+	seen := map[[sha1.Size]byte]bool{}
+	var digests [][sha1.Size]byte
+	sc := bufio.NewScanner(in)
+	for sc.Scan() {
+		line := sc.Text()
+		if line == "" {
+			continue
+		}
+		d := sha1.Sum([]byte(line))
+		if !seen[d] {
+			seen[d] = true
+			digests = append(digests, d)
+		}
+	}
+	if err := sc.Err(); err != nil {
+		log.Fatal(err)
+	}
+	sort.Slice(digests, func(i, j int) bool {
+		return bytes.Compare(digests[i][:], digests[j][:]) < 0
+	})
+
+	out, err := os.Create(os.Args[2])
+	if err != nil {
+		log.Fatal(err)
+	}
+	zw, err := gzip.NewWriterLevel(out, gzip.BestCompression)
+	if err != nil {
+		log.Fatal(err)
+	}
+	for _, d := range digests {
+		if _, err := zw.Write(d[:]); err != nil {
+			log.Fatal(err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		log.Fatal(err)
+	}
+	if err := out.Close(); err != nil {
+		log.Fatal(err)
+	}
+}
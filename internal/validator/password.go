@@ -0,0 +1,210 @@
+package validator
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/sha1"
+	_ "embed"
+	"encoding/hex"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+)
+
+// MinPasswordLength is the shortest password CheckPassword accepts.
+const MinPasswordLength = 8
+
+// minPasswordEntropy is the least estimated strength, in bits, of an
+// acceptable password. Eight random lowercase letters score about 37.
+const minPasswordEntropy = 34
+
+// breachedGz holds the sorted SHA-1 digests of the common and breached
+// passwords in passwords.txt. Run go generate after changing the list.
+//
+//go:generate go run genbreached.go passwords.txt breached.gz
+//go:embed breached.gz
+var breachedGz []byte
+
+var (
+	breachedOnce    sync.Once
+	breachedDigests []byte
+)
+
+var keyboardRows = []string{"1234567890", "qwertyuiop", "asdfghjkl", "zxcvbnm"}
+
+var leetReplacer = strings.NewReplacer("0", "o", "1", "i", "3", "e", "4", "a", "5", "s", "7", "t", "@", "a", "$", "s", "!", "i")
+
+// CheckPassword adds a field error for key explaining what is wrong with
+// password, if anything. It must be long enough, must not be or be close to
+// a known common or breached password, must not contain userInputs such as
+// the user's name or email address, and must not be too easy to guess.
+func (v *Validator) CheckPassword(key, password string, userInputs ...string) {
+	if problem := PasswordProblem(password, userInputs...); problem != "" {
+		v.AddFieldError(key, problem)
+	}
+}
+
+// PasswordProblem returns a message describing why password is weak, or ""
+// if it is acceptable. See CheckPassword.
+func PasswordProblem(password string, userInputs ...string) string {
+	if !MinChars(password, MinPasswordLength) {
+		return "this field must be at least " + strconv.Itoa(MinPasswordLength) + " characters long"
+	}
+	for _, candidate := range passwordVariants(password) {
+		if Breached(candidate) {
+			return "this password is too common or has appeared in a data breach, please choose another"
+		}
+	}
+	lower := strings.ToLower(password)
+	for _, input := range userInputs {
+		// Only the local part of an email address is personal.
+		input, _, _ = strings.Cut(input, "@")
+		for _, part := range strings.FieldsFunc(strings.ToLower(input), isSeparator) {
+			if utf8.RuneCountInString(part) >= 4 && strings.Contains(lower, part) {
+				return "this field must not contain your name or email address"
+			}
+		}
+	}
+	if PasswordEntropy(password) < minPasswordEntropy {
+		return "this password is too easy to guess, make it longer and avoid repeated characters, sequences and keyboard patterns"
+	}
+	return ""
+}
+
+func isSeparator(r rune) bool {
+	return r == '.' || r == '_' || r == '-' || r == '+' || unicode.IsSpace(r)
+}
+
+// passwordVariants returns password along with the forms people commonly
+// dress up a weak password in: changed case, digits for letters, and a
+// short run of digits or symbols on the end.
+func passwordVariants(password string) []string {
+	lower := strings.ToLower(password)
+	variants := []string{password, lower, leetReplacer.Replace(lower)}
+	base := strings.TrimRightFunc(lower, func(r rune) bool {
+		return unicode.IsDigit(r) || unicode.IsPunct(r) || unicode.IsSymbol(r)
+	})
+	if n := len(lower) - len(base); n > 0 && n <= 6 && utf8.RuneCountInString(base) >= 4 {
+		variants = append(variants, base, leetReplacer.Replace(base))
+	}
+	return variants
+}
+
+// PasswordEntropy estimates the strength of password in bits, from the
+// kinds of characters it uses and its length. Characters that repeat the
+// one before or follow it in the alphabet or along a keyboard row count
+// for a quarter of a character.
+func PasswordEntropy(password string) float64 {
+	var lower, upper, digit, symbol, other bool
+	length := 0.0
+	prev := rune(-1)
+	for _, r := range password {
+		switch {
+		case r >= 'a' && r <= 'z':
+			lower = true
+		case r >= 'A' && r <= 'Z':
+			upper = true
+		case r >= '0' && r <= '9':
+			digit = true
+		case r < utf8.RuneSelf:
+			symbol = true
+		default:
+			other = true
+		}
+		if prev >= 0 && followsPattern(unicode.ToLower(prev), unicode.ToLower(r)) {
+			length += 0.25
+		} else {
+			length++
+		}
+		prev = r
+	}
+
+	pool := 0
+	for _, c := range []struct {
+		used bool
+		size int
+	}{{lower, 26}, {upper, 26}, {digit, 10}, {symbol, 33}, {other, 100}} {
+		if c.used {
+			pool += c.size
+		}
+	}
+	if pool == 0 {
+		return 0
+	}
+	return length * math.Log2(float64(pool))
+}
+
+func followsPattern(prev, r rune) bool {
+	if r == prev || r == prev+1 || r == prev-1 {
+		return true
+	}
+	for _, row := range keyboardRows {
+		i := strings.IndexRune(row, prev)
+		if i < 0 {
+			continue
+		}
+		if (i > 0 && rune(row[i-1]) == r) || (i < len(row)-1 && rune(row[i+1]) == r) {
+			return true
+		}
+	}
+	return false
+}
+
+// Breached reports whether password is on the embedded list of common and
+// breached passwords. The lookup works like the range API of Have I Been
+// Pwned, by the first five hex digits of the password's SHA-1 hash.
+func Breached(password string) bool {
+	sum := sha1.Sum([]byte(password))
+	digest := strings.ToUpper(hex.EncodeToString(sum[:]))
+	for _, suffix := range BreachedRange(digest[:5]) {
+		if suffix == digest[5:] {
+			return true
+		}
+	}
+	return false
+}
+
+// BreachedRange returns the remaining 35 hex digits, in upper case, of the
+// SHA-1 hashes on the embedded list that start with the five hex digits of
+// prefix.
+func BreachedRange(prefix string) []string {
+	if len(prefix) != 5 {
+		return nil
+	}
+	want, err := strconv.ParseUint(prefix, 16, 32)
+	if err != nil {
+		return nil
+	}
+
+	digests := loadBreached()
+	n := len(digests) / sha1.Size
+	top := func(i int) uint64 {
+		d := digests[i*sha1.Size:]
+		return uint64(d[0])<<12 | uint64(d[1])<<4 | uint64(d[2])>>4
+	}
+	suffixes := []string{}
+	for i := sort.Search(n, func(i int) bool { return top(i) >= want }); i < n && top(i) == want; i++ {
+		digest := strings.ToUpper(hex.EncodeToString(digests[i*sha1.Size : (i+1)*sha1.Size]))
+		suffixes = append(suffixes, digest[5:])
+	}
+	return suffixes
+}
+
+func loadBreached() []byte {
+	breachedOnce.Do(func() {
+		zr, err := gzip.NewReader(bytes.NewReader(breachedGz))
+		if err != nil {
+			panic(err)
+		}
+		breachedDigests, err = io.ReadAll(zr)
+		if err != nil {
+			panic(err)
+		}
+	})
+	return breachedDigests
+}
@@ -0,0 +1,66 @@
+package validator
+
+import (
+	"testing"
+
+	"github.com/MohammadLashkari/snippetbox/internal/assert"
+)
+
+func TestPasswordProblem(t *testing.T) {
+	tests := []struct {
+		name       string
+		password   string
+		userInputs []string
+		want       string
+	}{
+		{"Strong", "correct horse battery staple", nil, ""},
+		{"Mixed", "Tr0ub4dor&3", nil, ""},
+		{"Random letters", "kdjqmzxp", nil, ""},
+		{"Too short", "x7#Qa", nil, "this field must be at least 8 characters long"},
+		{"Breached", "password", nil, "this password is too common or has appeared in a data breach, please choose another"},
+		{"Breached with suffix", "password1", nil, "this password is too common or has appeared in a data breach, please choose another"},
+		{"Breached capitalized", "Sunshine2024!", nil, "this password is too common or has appeared in a data breach, please choose another"},
+		{"Breached leet", "P@ssw0rd", nil, "this password is too common or has appeared in a data breach, please choose another"},
+		{"Breached with digits", "Password123", nil, "this password is too common or has appeared in a data breach, please choose another"},
+		{"Breached with symbol", "letmein!", nil, "this password is too common or has appeared in a data breach, please choose another"},
+		{"Breached less common", "butterfly", nil, "this password is too common or has appeared in a data breach, please choose another"},
+		{"Contains name", "alice-loves-rust", []string{"Alice", "alice@example.com"}, "this field must not contain your name or email address"},
+		{"Contains email", "xy.bob1987.zz", []string{"Robert", "bob1987@example.com"}, "this field must not contain your name or email address"},
+		{"Email domain", "example-Q7#mz", []string{"Robert", "bob@example.com"}, ""},
+		{"Repeated", "zzzzzzzzzzzz", nil, "this password is too easy to guess, make it longer and avoid repeated characters, sequences and keyboard patterns"},
+		{"Sequence", "lmnopqrstu", nil, "this password is too easy to guess, make it longer and avoid repeated characters, sequences and keyboard patterns"},
+		{"Keyboard row", "poiuytrewq", nil, "this password is too easy to guess, make it longer and avoid repeated characters, sequences and keyboard patterns"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, PasswordProblem(tt.password, tt.userInputs...), tt.want)
+		})
+	}
+}
+
+func TestCheckPassword(t *testing.T) {
+	var v Validator
+	v.CheckPassword("password", "letmein123")
+	assert.Equal(t, v.Valid(), false)
+	assert.StringContains(t, v.FieldErrors["password"], "too common")
+
+	v = Validator{}
+	v.CheckPassword("password", "validPa$$word")
+	assert.Equal(t, v.Valid(), true)
+}
+
+func TestBreachedRange(t *testing.T) {
+	// SHA-1("password") = 5BAA61E4C9B93F3F0682250B6CF8331B7EE68FD8
+	suffixes := BreachedRange("5BAA6")
+	found := false
+	for _, s := range suffixes {
+		if s == "1E4C9B93F3F0682250B6CF8331B7EE68FD8" {
+			found = true
+		}
+	}
+	assert.Equal(t, found, true)
+	assert.Equal(t, len(BreachedRange("5baa")), 0)
+	assert.Equal(t, len(BreachedRange("ZZZZZ")), 0)
+	assert.Equal(t, Breached("password"), true)
+	assert.Equal(t, Breached("kdjqmzxp"), false)
+}
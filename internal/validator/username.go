@@ -0,0 +1,40 @@
+package validator
+
+import (
+	"regexp"
+	"strings"
+)
+
+// UsernameRX matches 3 to 30 ASCII letters, digits, hyphens and
+// underscores, starting and ending with a letter or digit.
+var UsernameRX = regexp.MustCompile(`^[a-zA-Z0-9](?:[a-zA-Z0-9_-]{1,28})[a-zA-Z0-9]$`)
+
+// reservedUsernames are names that could be mistaken for the site itself
+// or its staff, or that clash with a path under the site root. They are
+// compared in lower case.
+var reservedUsernames = map[string]bool{
+	"about": true, "account": true, "admin": true, "administrator": true,
+	"api": true, "help": true, "login": true, "logout": true, "mod": true,
+	"moderator": true, "null": true, "oembed": true, "ping": true, "root": true,
+	"security": true, "settings": true, "signup": true, "snippet": true,
+	"snippetbox": true, "snippets": true, "staff": true, "static": true,
+	"support": true, "system": true, "u": true, "user": true, "users": true,
+	"www": true,
+}
+
+// CheckUsername adds a field error for key unless username is well formed
+// and not reserved.
+func (v *Validator) CheckUsername(key, username string) {
+	switch {
+	case !Matches(username, UsernameRX):
+		v.AddFieldError(key, "this field must be 3 to 30 letters, digits, hyphens or underscores, starting and ending with a letter or digit")
+	case ReservedUsername(username):
+		v.AddFieldError(key, "this username is reserved, please choose another")
+	}
+}
+
+// ReservedUsername reports whether username may not be chosen, whatever
+// its case.
+func ReservedUsername(username string) bool {
+	return reservedUsernames[strings.ToLower(username)]
+}
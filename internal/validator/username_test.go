@@ -0,0 +1,36 @@
+package validator
+
+import (
+	"testing"
+
+	"github.com/MohammadLashkari/snippetbox/internal/assert"
+)
+
+func TestCheckUsername(t *testing.T) {
+	tests := []struct {
+		name      string
+		username  string
+		wantError string
+	}{
+		{"Valid", "alice", ""},
+		{"Mixed case", "Alice_Smith-2", ""},
+		{"Shortest", "abc", ""},
+		{"Longest", "a234567890123456789012345678z0", ""},
+		{"Too short", "ab", "this field must be 3 to 30 letters, digits, hyphens or underscores, starting and ending with a letter or digit"},
+		{"Too long", "a2345678901234567890123456789z1", "this field must be 3 to 30 letters, digits, hyphens or underscores, starting and ending with a letter or digit"},
+		{"Leading hyphen", "-alice", "this field must be 3 to 30 letters, digits, hyphens or underscores, starting and ending with a letter or digit"},
+		{"Trailing underscore", "alice_", "this field must be 3 to 30 letters, digits, hyphens or underscores, starting and ending with a letter or digit"},
+		{"Dot", "alice.smith", "this field must be 3 to 30 letters, digits, hyphens or underscores, starting and ending with a letter or digit"},
+		{"Non-ASCII", "àlice", "this field must be 3 to 30 letters, digits, hyphens or underscores, starting and ending with a letter or digit"},
+		{"Reserved", "admin", "this username is reserved, please choose another"},
+		{"Reserved upper case", "API", "this username is reserved, please choose another"},
+		{"Reserved path", "static", "this username is reserved, please choose another"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var v Validator
+			v.CheckUsername("username", tt.username)
+			assert.Equal(t, v.FieldErrors["username"], tt.wantError)
+		})
+	}
+}
@@ -0,0 +1,54 @@
+package webauthn
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/MohammadLashkari/snippetbox/internal/assert"
+)
+
+func TestDecodeCBOR(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    []byte
+		want     any
+		wantRest int
+		wantErr  bool
+	}{
+		{"Small int", []byte{0x0a}, int64(10), 0, false},
+		{"Uint16", []byte{0x19, 0x03, 0xe8}, int64(1000), 0, false},
+		{"Negative", []byte{0x38, 0x63}, int64(-100), 0, false},
+		{"Bytes", []byte{0x42, 0x01, 0x02, 0xff}, []byte{1, 2}, 1, false},
+		{"Text", []byte{0x63, 'f', 'o', 'o'}, "foo", 0, false},
+		{"Array", []byte{0x82, 0x01, 0xf5}, []any{int64(1), true}, 0, false},
+		{"Map", []byte{0xa1, 0x20, 0xf6}, map[any]any{int64(-1): nil}, 0, false},
+		{"Truncated bytes", []byte{0x45, 0x01}, nil, 0, true},
+		{"Huge array", []byte{0x9b, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}, nil, 0, true},
+		{"Indefinite", []byte{0x5f}, nil, 0, true},
+		{"Tag", []byte{0xc1, 0x00}, nil, 0, true},
+		{"Float", []byte{0xf9, 0x3c, 0x00}, nil, 0, true},
+		{"Array map key", []byte{0xa1, 0x80, 0x00}, nil, 0, true},
+		{"Empty", []byte{}, nil, 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, rest, err := decodeCBOR(tt.input)
+			assert.Equal(t, err != nil, tt.wantErr)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %#v; want %#v", got, tt.want)
+			}
+			assert.Equal(t, len(rest), tt.wantRest)
+		})
+	}
+
+	t.Run("Deep nesting", func(t *testing.T) {
+		input := make([]byte, 100)
+		for i := range input {
+			input[i] = 0x81
+		}
+		if _, _, err := decodeCBOR(input); err == nil {
+			t.Error("want an error")
+		}
+	})
+}
@@ -0,0 +1,375 @@
+// Package webauthn implements the server side of WebAuthn registration and
+// authentication for passkeys: "none" attestation, ES256 and RS256 public
+// keys, assertion signatures and sign counters.
+package webauthn
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+)
+
+const (
+	flagUserPresent  = 0x01
+	flagAttestedData = 0x40
+
+	algES256 = -7
+	algRS256 = -257
+
+	timeout = 5 * 60 * 1000
+)
+
+var (
+	ErrInvalid = errors.New("webauthn: invalid response")
+	// ErrCloned means the sign counter went backwards, a sign that the
+	// credential's private key has been copied to another authenticator.
+	ErrCloned = errors.New("webauthn: sign counter did not increase")
+)
+
+var encoding = base64.RawURLEncoding
+
+// Config identifies the relying party: the site credentials are scoped to.
+type Config struct {
+	RPID   string
+	RPName string
+	Origin string
+}
+
+type CredentialDescriptor struct {
+	Type string `json:"type"`
+	ID   string `json:"id"`
+}
+
+type RelyingParty struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+type UserEntity struct {
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	DisplayName string `json:"displayName"`
+}
+
+type CredentialParameter struct {
+	Type string `json:"type"`
+	Alg  int    `json:"alg"`
+}
+
+type AuthenticatorSelection struct {
+	ResidentKey      string `json:"residentKey"`
+	UserVerification string `json:"userVerification"`
+}
+
+// CreationOptions are passed to navigator.credentials.create. Binary values
+// are base64url encoded and must be decoded by the page's script.
+type CreationOptions struct {
+	Challenge              string                 `json:"challenge"`
+	RP                     RelyingParty           `json:"rp"`
+	User                   UserEntity             `json:"user"`
+	PubKeyCredParams       []CredentialParameter  `json:"pubKeyCredParams"`
+	Timeout                int                    `json:"timeout"`
+	Attestation            string                 `json:"attestation"`
+	AuthenticatorSelection AuthenticatorSelection `json:"authenticatorSelection"`
+	ExcludeCredentials     []CredentialDescriptor `json:"excludeCredentials"`
+}
+
+// RequestOptions are passed to navigator.credentials.get. They allow any
+// discoverable credential for the site, so the user doesn't need to say
+// who they are first.
+type RequestOptions struct {
+	Challenge        string `json:"challenge"`
+	RPID             string `json:"rpId"`
+	Timeout          int    `json:"timeout"`
+	UserVerification string `json:"userVerification"`
+}
+
+// NewChallenge returns a random challenge, base64url encoded.
+func NewChallenge() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return encoding.EncodeToString(b), nil
+}
+
+func (c Config) CreationOptions(challenge string, userHandle []byte, name, displayName string, exclude [][]byte) *CreationOptions {
+	opts := &CreationOptions{
+		Challenge: challenge,
+		RP:        RelyingParty{ID: c.RPID, Name: c.RPName},
+		User:      UserEntity{ID: encoding.EncodeToString(userHandle), Name: name, DisplayName: displayName},
+		PubKeyCredParams: []CredentialParameter{
+			{Type: "public-key", Alg: algES256},
+			{Type: "public-key", Alg: algRS256},
+		},
+		Timeout:                timeout,
+		Attestation:            "none",
+		AuthenticatorSelection: AuthenticatorSelection{ResidentKey: "required", UserVerification: "preferred"},
+		ExcludeCredentials:     []CredentialDescriptor{},
+	}
+	for _, id := range exclude {
+		opts.ExcludeCredentials = append(opts.ExcludeCredentials, CredentialDescriptor{Type: "public-key", ID: encoding.EncodeToString(id)})
+	}
+	return opts
+}
+
+func (c Config) RequestOptions(challenge string) *RequestOptions {
+	return &RequestOptions{Challenge: challenge, RPID: c.RPID, Timeout: timeout, UserVerification: "preferred"}
+}
+
+// RegistrationResponse is the credential returned by
+// navigator.credentials.create, with binary values base64url encoded.
+type RegistrationResponse struct {
+	ID       string `json:"id"`
+	Type     string `json:"type"`
+	Response struct {
+		ClientDataJSON    string `json:"clientDataJSON"`
+		AttestationObject string `json:"attestationObject"`
+	} `json:"response"`
+}
+
+// AssertionResponse is the credential returned by navigator.credentials.get,
+// with binary values base64url encoded.
+type AssertionResponse struct {
+	ID       string `json:"id"`
+	Type     string `json:"type"`
+	Response struct {
+		ClientDataJSON    string `json:"clientDataJSON"`
+		AuthenticatorData string `json:"authenticatorData"`
+		Signature         string `json:"signature"`
+		UserHandle        string `json:"userHandle"`
+	} `json:"response"`
+}
+
+// CredentialID returns the decoded ID of the credential used.
+func (r *AssertionResponse) CredentialID() ([]byte, error) {
+	return encoding.DecodeString(r.ID)
+}
+
+// UserHandle returns the decoded user handle, which is empty if the
+// authenticator didn't return one.
+func (r *AssertionResponse) UserHandle() ([]byte, error) {
+	return encoding.DecodeString(r.Response.UserHandle)
+}
+
+// Credential is a newly registered public key credential. PublicKey is in
+// COSE format.
+type Credential struct {
+	ID        []byte
+	PublicKey []byte
+	SignCount uint32
+}
+
+// VerifyRegistration checks a registration response against the challenge
+// it was created for and returns the new credential.
+func (c Config) VerifyRegistration(resp *RegistrationResponse, challenge string) (*Credential, error) {
+	if resp.Type != "public-key" {
+		return nil, ErrInvalid
+	}
+	clientDataJSON, err := encoding.DecodeString(resp.Response.ClientDataJSON)
+	if err != nil {
+		return nil, ErrInvalid
+	}
+	if err := c.checkClientData(clientDataJSON, "webauthn.create", challenge); err != nil {
+		return nil, err
+	}
+
+	rawAttestation, err := encoding.DecodeString(resp.Response.AttestationObject)
+	if err != nil {
+		return nil, ErrInvalid
+	}
+	v, _, err := decodeCBOR(rawAttestation)
+	if err != nil {
+		return nil, err
+	}
+	attestation, ok := v.(map[any]any)
+	if !ok {
+		return nil, ErrInvalid
+	}
+	if format, _ := attestation["fmt"].(string); format != "none" {
+		return nil, fmt.Errorf("%w: unsupported attestation format %q", ErrInvalid, format)
+	}
+	rawAuthData, ok := attestation["authData"].([]byte)
+	if !ok {
+		return nil, ErrInvalid
+	}
+
+	authData, err := c.parseAuthData(rawAuthData, true)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := parsePublicKey(authData.publicKey); err != nil {
+		return nil, err
+	}
+	return &Credential{ID: authData.credentialID, PublicKey: authData.publicKey, SignCount: authData.signCount}, nil
+}
+
+// VerifyAssertion checks an authentication response against the challenge
+// and the stored public key and sign count of the credential it used, and
+// returns the new sign count to store.
+func (c Config) VerifyAssertion(resp *AssertionResponse, challenge string, publicKey []byte, signCount uint32) (uint32, error) {
+	if resp.Type != "public-key" {
+		return 0, ErrInvalid
+	}
+	clientDataJSON, err := encoding.DecodeString(resp.Response.ClientDataJSON)
+	if err != nil {
+		return 0, ErrInvalid
+	}
+	if err := c.checkClientData(clientDataJSON, "webauthn.get", challenge); err != nil {
+		return 0, err
+	}
+	rawAuthData, err := encoding.DecodeString(resp.Response.AuthenticatorData)
+	if err != nil {
+		return 0, ErrInvalid
+	}
+	authData, err := c.parseAuthData(rawAuthData, false)
+	if err != nil {
+		return 0, err
+	}
+	sig, err := encoding.DecodeString(resp.Response.Signature)
+	if err != nil {
+		return 0, ErrInvalid
+	}
+
+	key, err := parsePublicKey(publicKey)
+	if err != nil {
+		return 0, err
+	}
+	clientDataHash := sha256.Sum256(clientDataJSON)
+	digest := sha256.Sum256(append(rawAuthData[:len(rawAuthData):len(rawAuthData)], clientDataHash[:]...))
+	if !verifySignature(key, digest[:], sig) {
+		return 0, fmt.Errorf("%w: bad signature", ErrInvalid)
+	}
+
+	// Authenticators that don't keep counters always report zero.
+	if (authData.signCount != 0 || signCount != 0) && authData.signCount <= signCount {
+		return 0, ErrCloned
+	}
+	return authData.signCount, nil
+}
+
+func (c Config) checkClientData(raw []byte, typ, challenge string) error {
+	var clientData struct {
+		Type        string `json:"type"`
+		Challenge   string `json:"challenge"`
+		Origin      string `json:"origin"`
+		CrossOrigin bool   `json:"crossOrigin"`
+	}
+	if err := json.Unmarshal(raw, &clientData); err != nil {
+		return ErrInvalid
+	}
+	switch {
+	case clientData.Type != typ:
+		return fmt.Errorf("%w: type %q", ErrInvalid, clientData.Type)
+	case challenge == "" || subtle.ConstantTimeCompare([]byte(clientData.Challenge), []byte(challenge)) != 1:
+		return fmt.Errorf("%w: challenge mismatch", ErrInvalid)
+	case clientData.Origin != c.Origin || clientData.CrossOrigin:
+		return fmt.Errorf("%w: origin %q", ErrInvalid, clientData.Origin)
+	}
+	return nil
+}
+
+type authenticatorData struct {
+	flags        byte
+	signCount    uint32
+	credentialID []byte
+	publicKey    []byte
+}
+
+func (c Config) parseAuthData(b []byte, attested bool) (*authenticatorData, error) {
+	if len(b) < 37 {
+		return nil, ErrInvalid
+	}
+	rpIDHash := sha256.Sum256([]byte(c.RPID))
+	if !bytes.Equal(b[:32], rpIDHash[:]) {
+		return nil, fmt.Errorf("%w: relying party ID mismatch", ErrInvalid)
+	}
+	d := &authenticatorData{flags: b[32], signCount: binary.BigEndian.Uint32(b[33:37])}
+	if d.flags&flagUserPresent == 0 {
+		return nil, fmt.Errorf("%w: user not present", ErrInvalid)
+	}
+	if !attested {
+		return d, nil
+	}
+
+	if d.flags&flagAttestedData == 0 {
+		return nil, fmt.Errorf("%w: no attested credential data", ErrInvalid)
+	}
+	rest := b[37:]
+	if len(rest) < 18 {
+		return nil, ErrInvalid
+	}
+	n := int(binary.BigEndian.Uint16(rest[16:18]))
+	rest = rest[18:]
+	if n == 0 || n > 1023 || len(rest) < n {
+		return nil, ErrInvalid
+	}
+	d.credentialID = rest[:n:n]
+	// The public key is a CBOR item, possibly followed by extensions.
+	_, after, err := decodeCBOR(rest[n:])
+	if err != nil {
+		return nil, err
+	}
+	d.publicKey = rest[n : len(rest)-len(after)]
+	return d, nil
+}
+
+// parsePublicKey decodes an ES256 or RS256 public key in COSE format.
+func parsePublicKey(cose []byte) (any, error) {
+	v, _, err := decodeCBOR(cose)
+	if err != nil {
+		return nil, err
+	}
+	m, ok := v.(map[any]any)
+	if !ok {
+		return nil, ErrInvalid
+	}
+	kty, _ := m[int64(1)].(int64)
+	alg, _ := m[int64(3)].(int64)
+	switch {
+	case kty == 2 && alg == algES256:
+		crv, _ := m[int64(-1)].(int64)
+		x, _ := m[int64(-2)].([]byte)
+		y, _ := m[int64(-3)].([]byte)
+		if crv != 1 || len(x) != 32 || len(y) != 32 {
+			return nil, fmt.Errorf("%w: bad EC2 key", ErrInvalid)
+		}
+		// crypto/ecdh rejects points that are not on the curve.
+		if _, err := ecdh.P256().NewPublicKey(append(append([]byte{4}, x...), y...)); err != nil {
+			return nil, fmt.Errorf("%w: bad EC2 key", ErrInvalid)
+		}
+		return &ecdsa.PublicKey{Curve: elliptic.P256(), X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}, nil
+	case kty == 3 && alg == algRS256:
+		n, _ := m[int64(-1)].([]byte)
+		e, _ := m[int64(-2)].([]byte)
+		if len(n) < 256 || len(e) == 0 || len(e) > 4 {
+			return nil, fmt.Errorf("%w: bad RSA key", ErrInvalid)
+		}
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}, nil
+	default:
+		return nil, fmt.Errorf("%w: unsupported key type %d with algorithm %d", ErrInvalid, kty, alg)
+	}
+}
+
+func verifySignature(key any, digest, sig []byte) bool {
+	switch key := key.(type) {
+	case *ecdsa.PublicKey:
+		return ecdsa.VerifyASN1(key, digest, sig)
+	case *rsa.PublicKey:
+		return rsa.VerifyPKCS1v15(key, crypto.SHA256, digest, sig) == nil
+	default:
+		return false
+	}
+}
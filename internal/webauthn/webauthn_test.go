@@ -0,0 +1,103 @@
+package webauthn_test
+
+import (
+	"encoding/base64"
+	"errors"
+	"testing"
+
+	"github.com/MohammadLashkari/snippetbox/internal/assert"
+	"github.com/MohammadLashkari/snippetbox/internal/webauthn"
+	"github.com/MohammadLashkari/snippetbox/internal/webauthn/webauthntest"
+)
+
+var config = webauthn.Config{RPID: "snippetbox.example.com", RPName: "Snippetbox", Origin: "https://snippetbox.example.com"}
+
+func register(t *testing.T, a *webauthntest.Authenticator) *webauthn.Credential {
+	challenge, err := webauthn.NewChallenge()
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts := config.CreationOptions(challenge, []byte("1"), "bob@example.com", "Bob", nil)
+	cred, err := config.VerifyRegistration(a.Create(opts), challenge)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cred
+}
+
+func TestVerifyRegistration(t *testing.T) {
+	a := webauthntest.New(config.RPID, config.Origin)
+	cred := register(t, a)
+	assert.Equal(t, string(cred.ID), string(a.ID))
+	assert.Equal(t, cred.SignCount, uint32(0))
+
+	opts := config.CreationOptions("challenge", []byte("1"), "bob@example.com", "Bob", nil)
+	tests := []struct {
+		name      string
+		config    webauthn.Config
+		challenge string
+	}{
+		{"Other challenge", config, "other"},
+		{"Other origin", webauthn.Config{RPID: config.RPID, Origin: "https://evil.example.com"}, "challenge"},
+		{"Other relying party", webauthn.Config{RPID: "evil.example.com", Origin: config.Origin}, "challenge"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := tt.config.VerifyRegistration(a.Create(opts), tt.challenge)
+			assert.Equal(t, errors.Is(err, webauthn.ErrInvalid), true)
+		})
+	}
+
+	t.Run("Garbage attestation", func(t *testing.T) {
+		resp := a.Create(opts)
+		resp.Response.AttestationObject = "_w"
+		_, err := config.VerifyRegistration(resp, "challenge")
+		if err == nil {
+			t.Fatal("want an error")
+		}
+	})
+}
+
+func TestVerifyAssertion(t *testing.T) {
+	a := webauthntest.New(config.RPID, config.Origin)
+	cred := register(t, a)
+	opts := config.RequestOptions("challenge")
+
+	resp := a.Get(opts)
+	count, err := config.VerifyAssertion(resp, "challenge", cred.PublicKey, cred.SignCount)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, count, uint32(1))
+
+	handle, err := resp.UserHandle()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, string(handle), "1")
+
+	t.Run("Replayed counter", func(t *testing.T) {
+		_, err := config.VerifyAssertion(resp, "challenge", cred.PublicKey, count)
+		assert.Equal(t, err, webauthn.ErrCloned)
+	})
+
+	t.Run("Other challenge", func(t *testing.T) {
+		_, err := config.VerifyAssertion(a.Get(opts), "other", cred.PublicKey, count)
+		assert.Equal(t, errors.Is(err, webauthn.ErrInvalid), true)
+	})
+
+	t.Run("Other key", func(t *testing.T) {
+		other := register(t, webauthntest.New(config.RPID, config.Origin))
+		_, err := config.VerifyAssertion(a.Get(opts), "challenge", other.PublicKey, count)
+		assert.Equal(t, errors.Is(err, webauthn.ErrInvalid), true)
+	})
+
+	t.Run("Tampered authenticator data", func(t *testing.T) {
+		resp := a.Get(opts)
+		authData, _ := base64.RawURLEncoding.DecodeString(resp.Response.AuthenticatorData)
+		authData[len(authData)-1]++
+		resp.Response.AuthenticatorData = base64.RawURLEncoding.EncodeToString(authData)
+		_, err := config.VerifyAssertion(resp, "challenge", cred.PublicKey, count)
+		assert.Equal(t, errors.Is(err, webauthn.ErrInvalid), true)
+	})
+}
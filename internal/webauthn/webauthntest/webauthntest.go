@@ -0,0 +1,140 @@
+// Package webauthntest provides a software authenticator for testing
+// WebAuthn registration and login without a browser.
+package webauthntest
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+
+	"github.com/MohammadLashkari/snippetbox/internal/webauthn"
+)
+
+var encoding = base64.RawURLEncoding
+
+// Authenticator holds a single ES256 passkey for RPID, used from Origin.
+type Authenticator struct {
+	RPID       string
+	Origin     string
+	Key        *ecdsa.PrivateKey
+	ID         []byte
+	UserHandle []byte
+	SignCount  uint32
+}
+
+func New(rpID, origin string) *Authenticator {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		panic(err)
+	}
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		panic(err)
+	}
+	return &Authenticator{RPID: rpID, Origin: origin, Key: key, ID: id}
+}
+
+// Create answers navigator.credentials.create options with "none"
+// attestation.
+func (a *Authenticator) Create(opts *webauthn.CreationOptions) *webauthn.RegistrationResponse {
+	a.UserHandle, _ = encoding.DecodeString(opts.User.ID)
+	cose := encodeMap([][2][]byte{
+		{encodeInt(1), encodeInt(2)},
+		{encodeInt(3), encodeInt(-7)},
+		{encodeInt(-1), encodeInt(1)},
+		{encodeInt(-2), encodeBytes(a.Key.X.FillBytes(make([]byte, 32)))},
+		{encodeInt(-3), encodeBytes(a.Key.Y.FillBytes(make([]byte, 32)))},
+	})
+	authData := a.authData(0x41)
+	authData = append(authData, make([]byte, 16)...) // AAGUID
+	authData = binary.BigEndian.AppendUint16(authData, uint16(len(a.ID)))
+	authData = append(authData, a.ID...)
+	authData = append(authData, cose...)
+
+	attestation := encodeMap([][2][]byte{
+		{encodeText("fmt"), encodeText("none")},
+		{encodeText("attStmt"), encodeMap(nil)},
+		{encodeText("authData"), encodeBytes(authData)},
+	})
+
+	resp := &webauthn.RegistrationResponse{ID: encoding.EncodeToString(a.ID), Type: "public-key"}
+	resp.Response.ClientDataJSON = encoding.EncodeToString(a.clientData("webauthn.create", opts.Challenge))
+	resp.Response.AttestationObject = encoding.EncodeToString(attestation)
+	return resp
+}
+
+// Get answers navigator.credentials.get options, incrementing the sign
+// counter.
+func (a *Authenticator) Get(opts *webauthn.RequestOptions) *webauthn.AssertionResponse {
+	a.SignCount++
+	authData := a.authData(0x05)
+	clientData := a.clientData("webauthn.get", opts.Challenge)
+	clientDataHash := sha256.Sum256(clientData)
+	digest := sha256.Sum256(append(authData, clientDataHash[:]...))
+	sig, err := ecdsa.SignASN1(rand.Reader, a.Key, digest[:])
+	if err != nil {
+		panic(err)
+	}
+
+	resp := &webauthn.AssertionResponse{ID: encoding.EncodeToString(a.ID), Type: "public-key"}
+	resp.Response.ClientDataJSON = encoding.EncodeToString(clientData)
+	resp.Response.AuthenticatorData = encoding.EncodeToString(authData)
+	resp.Response.Signature = encoding.EncodeToString(sig)
+	resp.Response.UserHandle = encoding.EncodeToString(a.UserHandle)
+	return resp
+}
+
+func (a *Authenticator) authData(flags byte) []byte {
+	rpIDHash := sha256.Sum256([]byte(a.RPID))
+	b := append(rpIDHash[:], flags)
+	return binary.BigEndian.AppendUint32(b, a.SignCount)
+}
+
+func (a *Authenticator) clientData(typ, challenge string) []byte {
+	b, err := json.Marshal(map[string]any{"type": typ, "challenge": challenge, "origin": a.Origin, "crossOrigin": false})
+	if err != nil {
+		panic(err)
+	}
+	return b
+}
+
+func encodeHead(major byte, n uint64) []byte {
+	switch {
+	case n < 24:
+		return []byte{major<<5 | byte(n)}
+	case n < 1<<8:
+		return []byte{major<<5 | 24, byte(n)}
+	case n < 1<<16:
+		return binary.BigEndian.AppendUint16([]byte{major<<5 | 25}, uint16(n))
+	default:
+		return binary.BigEndian.AppendUint32([]byte{major<<5 | 26}, uint32(n))
+	}
+}
+
+func encodeInt(n int64) []byte {
+	if n < 0 {
+		return encodeHead(1, uint64(-1-n))
+	}
+	return encodeHead(0, uint64(n))
+}
+
+func encodeBytes(b []byte) []byte {
+	return append(encodeHead(2, uint64(len(b))), b...)
+}
+
+func encodeText(s string) []byte {
+	return append(encodeHead(3, uint64(len(s))), s...)
+}
+
+func encodeMap(pairs [][2][]byte) []byte {
+	b := encodeHead(5, uint64(len(pairs)))
+	for _, p := range pairs {
+		b = append(b, p[0]...)
+		b = append(b, p[1]...)
+	}
+	return b
+}
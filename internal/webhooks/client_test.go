@@ -0,0 +1,104 @@
+package webhooks
+
+import (
+	"context"
+	"net/http"
+	"net/netip"
+	"testing"
+
+	"github.com/MohammadLashkari/snippetbox/internal/assert"
+	"github.com/MohammadLashkari/snippetbox/internal/models"
+)
+
+func TestPublicAddr(t *testing.T) {
+	tests := []struct {
+		addr string
+		want bool
+	}{
+		{"93.184.216.34", true},
+		{"2606:2800:220:1:248:1893:25c8:1946", true},
+		{"127.0.0.1", false},
+		{"::1", false},
+		{"10.1.2.3", false},
+		{"172.16.0.1", false},
+		{"192.168.1.1", false},
+		{"169.254.169.254", false},
+		{"100.100.100.200", false},
+		{"0.0.0.0", false},
+		{"::", false},
+		{"fd00::1", false},
+		{"fe80::1", false},
+		{"224.0.0.1", false},
+		{"::ffff:127.0.0.1", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.addr, func(t *testing.T) {
+			assert.Equal(t, PublicAddr(netip.MustParseAddr(tt.addr)), tt.want)
+		})
+	}
+}
+
+func TestCheckURL(t *testing.T) {
+	tests := []struct {
+		url  string
+		want error
+	}{
+		{"https://example.com/hook", nil},
+		{"https://93.184.216.34/hook", nil},
+		{"http://localhost:8080/hook", ErrForbiddenAddress},
+		{"http://api.localhost./hook", ErrForbiddenAddress},
+		{"http://127.0.0.1/hook", ErrForbiddenAddress},
+		{"http://[::1]:8080/hook", ErrForbiddenAddress},
+		{"http://169.254.169.254/latest/meta-data/", ErrForbiddenAddress},
+	}
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			assert.Equal(t, CheckURL(tt.url), tt.want)
+		})
+	}
+}
+
+func TestDeliverRefusesPrivateAddress(t *testing.T) {
+	called := false
+	d, store := newTestDispatcher(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	}))
+	// The test receiver listens on loopback, which the real client refuses.
+	d.Client = NewClient()
+
+	if err := d.Notify(1, models.EventSnippetCreated, nil); err != nil {
+		t.Fatal(err)
+	}
+	d.DeliverDue(context.Background())
+
+	assert.Equal(t, called, false)
+	delivery := store.deliveries[0]
+	assert.Equal(t, delivery.ResponseStatus, 0)
+	assert.StringContains(t, delivery.LastError, "receiver address is not public")
+}
+
+func TestDeliverDoesNotFollowRedirects(t *testing.T) {
+	followed := false
+	d, store := newTestDispatcher(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/internal" {
+			followed = true
+			return
+		}
+		http.Redirect(w, r, "/internal", http.StatusFound)
+	}))
+	// Keep the test server's transport, which may dial loopback, but use
+	// the redirect policy of the real client.
+	client := NewClient()
+	client.Transport = d.Client.Transport
+	d.Client = client
+
+	if err := d.Notify(1, models.EventSnippetCreated, nil); err != nil {
+		t.Fatal(err)
+	}
+	d.DeliverDue(context.Background())
+
+	assert.Equal(t, followed, false)
+	delivery := store.deliveries[0]
+	assert.Equal(t, delivery.ResponseStatus, http.StatusFound)
+	assert.StringContains(t, delivery.LastError, "receiver responded with 302 Found")
+}
@@ -0,0 +1,256 @@
+// Package webhooks queues snippet events for the webhooks registered by
+// their owner and delivers them as signed JSON payloads in the background.
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/MohammadLashkari/snippetbox/internal/models"
+)
+
+const (
+	EventHeader     = "X-Snippetbox-Event"
+	DeliveryHeader  = "X-Snippetbox-Delivery"
+	SignatureHeader = "X-Snippetbox-Signature-256"
+)
+
+// Store is the part of models.WebhookModelInterface the dispatcher uses. It
+// must be safe for concurrent use.
+type Store interface {
+	ForEvent(userID int, event string) ([]*models.Webhook, error)
+	Enqueue(webhookID int, event string, payload []byte) error
+	Due(limit int) ([]*models.WebhookDelivery, error)
+	Delivered(id, responseStatus int) error
+	Retry(id, responseStatus int, lastError string, delay time.Duration) error
+	Failed(id, responseStatus int, lastError string) error
+}
+
+type Payload struct {
+	Event     string    `json:"event"`
+	CreatedAt time.Time `json:"created_at"`
+	Data      any       `json:"data"`
+}
+
+type Dispatcher struct {
+	Store  Store
+	Client *http.Client
+	Logger *slog.Logger
+
+	// PollInterval is how often the queue is checked for due deliveries
+	// when nothing has been enqueued in the meantime.
+	PollInterval time.Duration
+	// A delivery is retried after BaseDelay, doubling on each attempt up
+	// to MaxDelay, and marked as failed after MaxAttempts.
+	BaseDelay   time.Duration
+	MaxDelay    time.Duration
+	MaxAttempts int
+	// Workers is how many receiver URLs are posted to at once, and
+	// MaxPerReceiver how many deliveries to one URL are attempted each
+	// time the queue is checked, so that a slow receiver cannot hold up
+	// the others.
+	Workers        int
+	MaxPerReceiver int
+
+	wake chan struct{}
+}
+
+func NewDispatcher(store Store, logger *slog.Logger) *Dispatcher {
+	return &Dispatcher{
+		Store:          store,
+		Client:         NewClient(),
+		Logger:         logger,
+		PollInterval:   5 * time.Second,
+		BaseDelay:      30 * time.Second,
+		MaxDelay:       6 * time.Hour,
+		MaxAttempts:    8,
+		Workers:        4,
+		MaxPerReceiver: 5,
+		wake:           make(chan struct{}, 1),
+	}
+}
+
+// NewSecret returns a random hex secret for signing a webhook's deliveries.
+func NewSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// Sign returns the value of the signature header for body, in the same
+// "sha256=<hex>" form GitHub uses.
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify reports whether signature is the signature of body under secret.
+func Verify(secret string, body []byte, signature string) bool {
+	return hmac.Equal([]byte(Sign(secret, body)), []byte(signature))
+}
+
+// Notify queues event for every webhook of the user subscribed to it.
+func (d *Dispatcher) Notify(userID int, event string, data any) error {
+	hooks, err := d.Store.ForEvent(userID, event)
+	if err != nil {
+		return err
+	}
+	if len(hooks) == 0 {
+		return nil
+	}
+	payload, err := json.Marshal(Payload{
+		Event:     event,
+		CreatedAt: time.Now().UTC().Truncate(time.Second),
+		Data:      data,
+	})
+	if err != nil {
+		return err
+	}
+	for _, hook := range hooks {
+		if err := d.Store.Enqueue(hook.ID, event, payload); err != nil {
+			return err
+		}
+	}
+	select {
+	case d.wake <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// Run delivers queued payloads until ctx is cancelled.
+func (d *Dispatcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(d.PollInterval)
+	defer ticker.Stop()
+	for {
+		d.DeliverDue(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-d.wake:
+		}
+	}
+}
+
+// DeliverDue makes one attempt at deliveries whose time has come. Up to
+// Workers receivers are posted to in parallel, each receiver's deliveries
+// one after another and no more than MaxPerReceiver of them; the rest stay
+// queued for the next call.
+func (d *Dispatcher) DeliverDue(ctx context.Context) {
+	deliveries, err := d.Store.Due(100)
+	if err != nil {
+		d.Logger.ErrorContext(ctx, err.Error())
+		return
+	}
+	var urls []string
+	batches := map[string][]*models.WebhookDelivery{}
+	for _, delivery := range deliveries {
+		batch, ok := batches[delivery.URL]
+		if !ok {
+			urls = append(urls, delivery.URL)
+		}
+		if len(batch) < d.MaxPerReceiver {
+			batches[delivery.URL] = append(batch, delivery)
+		}
+	}
+
+	jobs := make(chan []*models.WebhookDelivery)
+	var wg sync.WaitGroup
+	for range min(max(d.Workers, 1), len(urls)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for batch := range jobs {
+				for _, delivery := range batch {
+					if ctx.Err() != nil {
+						break
+					}
+					if err := d.deliver(ctx, delivery); err != nil {
+						d.Logger.ErrorContext(ctx, err.Error(), "delivery_id", delivery.ID)
+					}
+				}
+			}
+		}()
+	}
+	for _, url := range urls {
+		jobs <- batches[url]
+	}
+	close(jobs)
+	wg.Wait()
+}
+
+func (d *Dispatcher) deliver(ctx context.Context, delivery *models.WebhookDelivery) error {
+	status, err := d.post(ctx, delivery)
+	if err == nil {
+		return d.Store.Delivered(delivery.ID, status)
+	}
+	lastError := truncate(err.Error(), 255)
+	attempts := delivery.Attempts + 1
+	if attempts >= d.MaxAttempts {
+		return d.Store.Failed(delivery.ID, status, lastError)
+	}
+	return d.Store.Retry(delivery.ID, status, lastError, d.backoff(attempts))
+}
+
+func (d *Dispatcher) post(ctx context.Context, delivery *models.WebhookDelivery) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, delivery.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "Snippetbox-Hookshot")
+	req.Header.Set(EventHeader, delivery.Event)
+	req.Header.Set(DeliveryHeader, strconv.Itoa(delivery.ID))
+	req.Header.Set(SignatureHeader, Sign(delivery.Secret, delivery.Payload))
+
+	rs, err := d.Client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer rs.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(rs.Body, 64<<10))
+
+	if rs.StatusCode < 200 || rs.StatusCode > 299 {
+		return rs.StatusCode, fmt.Errorf("webhooks: receiver responded with %s", rs.Status)
+	}
+	return rs.StatusCode, nil
+}
+
+// truncate shortens s to at most n runes, so that it fits a VARCHAR(n)
+// column without splitting a UTF-8 sequence.
+func truncate(s string, n int) string {
+	runes := []rune(s)
+	if len(runes) <= n {
+		return s
+	}
+	return string(runes[:n])
+}
+
+// backoff returns the delay before the next attempt, given how many
+// attempts have been made so far.
+func (d *Dispatcher) backoff(attempts int) time.Duration {
+	delay := d.BaseDelay
+	for i := 1; i < attempts; i++ {
+		delay *= 2
+		if delay >= d.MaxDelay {
+			return d.MaxDelay
+		}
+	}
+	return delay
+}
@@ -0,0 +1,239 @@
+package webhooks
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/MohammadLashkari/snippetbox/internal/assert"
+	"github.com/MohammadLashkari/snippetbox/internal/models"
+)
+
+// memoryStore keeps deliveries in memory and records what happened to them.
+type memoryStore struct {
+	mu         sync.Mutex
+	hooks      []*models.Webhook
+	deliveries []*models.WebhookDelivery
+	delays     []time.Duration
+}
+
+func (s *memoryStore) ForEvent(userID int, event string) ([]*models.Webhook, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var hooks []*models.Webhook
+	for _, h := range s.hooks {
+		for _, e := range h.Events {
+			if h.UserID == userID && e == event {
+				hooks = append(hooks, h)
+			}
+		}
+	}
+	return hooks, nil
+}
+
+func (s *memoryStore) Enqueue(webhookID int, event string, payload []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, h := range s.hooks {
+		if h.ID == webhookID {
+			s.deliveries = append(s.deliveries, &models.WebhookDelivery{
+				ID:        len(s.deliveries) + 1,
+				WebhookID: webhookID,
+				URL:       h.URL,
+				Secret:    h.Secret,
+				Event:     event,
+				Payload:   payload,
+				Status:    models.DeliveryPending,
+			})
+		}
+	}
+	return nil
+}
+
+func (s *memoryStore) Due(limit int) ([]*models.WebhookDelivery, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var due []*models.WebhookDelivery
+	for _, d := range s.deliveries {
+		if d.Status == models.DeliveryPending {
+			due = append(due, d)
+		}
+	}
+	return due, nil
+}
+
+func (s *memoryStore) Delivered(id, responseStatus int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	d := s.deliveries[id-1]
+	d.Attempts++
+	d.Status, d.ResponseStatus = models.DeliveryDelivered, responseStatus
+	return nil
+}
+
+func (s *memoryStore) Retry(id, responseStatus int, lastError string, delay time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	d := s.deliveries[id-1]
+	d.Attempts++
+	d.ResponseStatus, d.LastError = responseStatus, lastError
+	s.delays = append(s.delays, delay)
+	return nil
+}
+
+func (s *memoryStore) Failed(id, responseStatus int, lastError string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	d := s.deliveries[id-1]
+	d.Attempts++
+	d.Status, d.ResponseStatus, d.LastError = models.DeliveryFailed, responseStatus, lastError
+	return nil
+}
+
+func newTestDispatcher(t *testing.T, receiver http.Handler) (*Dispatcher, *memoryStore) {
+	ts := httptest.NewServer(receiver)
+	t.Cleanup(ts.Close)
+
+	store := &memoryStore{hooks: []*models.Webhook{{
+		ID:     1,
+		UserID: 1,
+		URL:    ts.URL,
+		Secret: "secret",
+		Events: []string{models.EventSnippetCreated},
+	}}}
+	d := NewDispatcher(store, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	d.Client = ts.Client()
+	return d, store
+}
+
+func TestDeliverSigned(t *testing.T) {
+	var (
+		gotEvent string
+		gotData  map[string]any
+		verified bool
+	)
+	d, store := newTestDispatcher(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		verified = Verify("secret", body, r.Header.Get(SignatureHeader))
+		gotEvent = r.Header.Get(EventHeader)
+		var p Payload
+		json.Unmarshal(body, &p)
+		gotData, _ = p.Data.(map[string]any)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	if err := d.Notify(1, models.EventSnippetCreated, map[string]any{"title": "hello world"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Notify(1, models.EventSnippetDeleted, map[string]any{"title": "unsubscribed"}); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, len(store.deliveries), 1)
+
+	d.DeliverDue(context.Background())
+
+	assert.Equal(t, verified, true)
+	assert.Equal(t, gotEvent, models.EventSnippetCreated)
+	assert.Equal(t, gotData["title"], any("hello world"))
+	assert.Equal(t, store.deliveries[0].Status, models.DeliveryDelivered)
+	assert.Equal(t, store.deliveries[0].ResponseStatus, http.StatusNoContent)
+}
+
+func TestDeliverRetriesWithBackoff(t *testing.T) {
+	d, store := newTestDispatcher(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	d.BaseDelay = time.Second
+	d.MaxDelay = 5 * time.Second
+	d.MaxAttempts = 5
+
+	if err := d.Notify(1, models.EventSnippetCreated, nil); err != nil {
+		t.Fatal(err)
+	}
+	for range 5 {
+		d.DeliverDue(context.Background())
+	}
+
+	want := []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 5 * time.Second}
+	assert.Equal(t, len(store.delays), len(want))
+	for i := range want {
+		assert.Equal(t, store.delays[i], want[i])
+	}
+	delivery := store.deliveries[0]
+	assert.Equal(t, delivery.Status, models.DeliveryFailed)
+	assert.Equal(t, delivery.Attempts, 5)
+	assert.Equal(t, delivery.ResponseStatus, http.StatusInternalServerError)
+}
+
+func TestTruncate(t *testing.T) {
+	long := strings.Repeat("é", 300)
+	got := truncate(long, 255)
+	assert.Equal(t, utf8.ValidString(got), true)
+	assert.Equal(t, utf8.RuneCountInString(got), 255)
+	assert.Equal(t, truncate("short", 255), "short")
+}
+
+func TestVerify(t *testing.T) {
+	body := []byte(`{"event":"snippet.created"}`)
+	assert.Equal(t, Verify("secret", body, Sign("secret", body)), true)
+	assert.Equal(t, Verify("other", body, Sign("secret", body)), false)
+	assert.Equal(t, Verify("secret", body, "sha256=00"), false)
+}
+
+func TestDeliverDueSharesOutReceivers(t *testing.T) {
+	// Each receiver waits for the other's first request, so the test only
+	// passes if they are posted to at the same time.
+	var first, second sync.Once
+	firstArrived, secondArrived := make(chan struct{}), make(chan struct{})
+	receiver := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		other := firstArrived
+		if strings.HasSuffix(r.URL.Path, "/first") {
+			first.Do(func() { close(firstArrived) })
+			other = secondArrived
+		} else {
+			second.Do(func() { close(secondArrived) })
+		}
+		select {
+		case <-other:
+			w.WriteHeader(http.StatusNoContent)
+		case <-time.After(5 * time.Second):
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	})
+	d, store := newTestDispatcher(t, receiver)
+	d.MaxPerReceiver = 3
+	firstHook := store.hooks[0]
+	secondHook := *firstHook
+	secondHook.ID = 2
+	secondHook.URL = firstHook.URL + "/second"
+	firstHook.URL += "/first"
+	store.hooks = append(store.hooks, &secondHook)
+
+	for range 5 {
+		if err := store.Enqueue(1, models.EventSnippetCreated, []byte(`{}`)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := store.Enqueue(2, models.EventSnippetCreated, []byte(`{}`)); err != nil {
+		t.Fatal(err)
+	}
+	d.DeliverDue(context.Background())
+
+	statuses := map[string]int{}
+	for _, delivery := range store.deliveries {
+		assert.Equal(t, delivery.ResponseStatus == http.StatusServiceUnavailable, false)
+		statuses[delivery.URL+" "+delivery.Status]++
+	}
+	// Only MaxPerReceiver deliveries to the first receiver are attempted.
+	assert.Equal(t, statuses[firstHook.URL+" "+models.DeliveryDelivered], 3)
+	assert.Equal(t, statuses[firstHook.URL+" "+models.DeliveryPending], 2)
+	assert.Equal(t, statuses[secondHook.URL+" "+models.DeliveryDelivered], 1)
+}
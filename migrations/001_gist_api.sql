@@ -0,0 +1,15 @@
+-- Snippet ownership and API tokens for the gist-compatible API.
+-- Snippets created before this migration keep a NULL owner.
+ALTER TABLE snippets ADD COLUMN user_id INTEGER NULL AFTER id;
+ALTER TABLE snippets ADD COLUMN updated DATETIME NULL AFTER created;
+UPDATE snippets SET updated = created;
+ALTER TABLE snippets MODIFY updated DATETIME NOT NULL;
+ALTER TABLE snippets ADD CONSTRAINT snippets_fk_user FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE;
+CREATE INDEX idx_snippets_user_id ON snippets(user_id);
+
+CREATE TABLE tokens (
+    hash BINARY(32) PRIMARY KEY,
+    user_id INTEGER NOT NULL,
+    created DATETIME NOT NULL,
+    CONSTRAINT tokens_fk_user FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
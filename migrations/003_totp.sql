@@ -0,0 +1,16 @@
+CREATE TABLE user_totp (
+    user_id INTEGER NOT NULL PRIMARY KEY,
+    secret VARCHAR(64) NOT NULL,
+    last_counter BIGINT NOT NULL DEFAULT 0,
+    created DATETIME NOT NULL,
+    CONSTRAINT user_totp_fk_user FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE TABLE totp_recovery_codes (
+    id INTEGER NOT NULL PRIMARY KEY AUTO_INCREMENT,
+    user_id INTEGER NOT NULL,
+    hash BINARY(32) NOT NULL,
+    CONSTRAINT totp_recovery_codes_fk_user FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_totp_recovery_codes_user ON totp_recovery_codes(user_id, hash);
@@ -0,0 +1,4 @@
+ALTER TABLE users ADD COLUMN verified BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- Accounts created before verification was introduced are trusted as-is.
+UPDATE users SET verified = TRUE;
@@ -0,0 +1,8 @@
+CREATE TABLE password_resets (
+    hash BINARY(32) PRIMARY KEY,
+    user_id INTEGER NOT NULL,
+    expires DATETIME NOT NULL,
+    CONSTRAINT password_resets_fk_user FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_password_resets_user ON password_resets(user_id);
@@ -0,0 +1,8 @@
+-- Failed logins counted per account email ("email:...") and per client IP
+-- ("ip:..."). Deleting a row lifts its lockout.
+CREATE TABLE login_attempts (
+    attempt_key VARCHAR(255) NOT NULL PRIMARY KEY,
+    failures INTEGER NOT NULL,
+    blocked_until DATETIME NULL,
+    updated DATETIME NOT NULL
+);
@@ -0,0 +1,16 @@
+-- Device metadata for logged in sessions. Rows go away with the scs session
+-- they describe, whether it expires, is destroyed or has its token renewed.
+CREATE TABLE user_sessions (
+    id INTEGER NOT NULL PRIMARY KEY AUTO_INCREMENT,
+    token CHAR(43) NOT NULL,
+    user_id INTEGER NOT NULL,
+    user_agent VARCHAR(255) NOT NULL,
+    ip VARCHAR(45) NOT NULL,
+    created DATETIME NOT NULL,
+    last_seen DATETIME NOT NULL,
+    CONSTRAINT user_sessions_uc_token UNIQUE (token),
+    CONSTRAINT user_sessions_fk_session FOREIGN KEY (token) REFERENCES sessions(token) ON DELETE CASCADE,
+    CONSTRAINT user_sessions_fk_user FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_user_sessions_user ON user_sessions(user_id, last_seen);
@@ -0,0 +1,12 @@
+CREATE TABLE passkeys (
+    id INTEGER NOT NULL PRIMARY KEY AUTO_INCREMENT,
+    user_id INTEGER NOT NULL,
+    name VARCHAR(100) NOT NULL,
+    credential_id VARBINARY(1023) NOT NULL,
+    public_key BLOB NOT NULL,
+    sign_count INTEGER UNSIGNED NOT NULL,
+    created DATETIME NOT NULL,
+    last_used DATETIME NULL,
+    CONSTRAINT passkeys_uc_credential_id UNIQUE (credential_id),
+    CONSTRAINT passkeys_fk_user FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
@@ -0,0 +1,5 @@
+-- Roles rank user < moderator < admin. Promote the first admin by hand, e.g.
+-- UPDATE users SET role = 'admin' WHERE email = 'alice@example.com';
+ALTER TABLE users
+    ADD COLUMN role ENUM('user', 'moderator', 'admin') NOT NULL DEFAULT 'user',
+    ADD COLUMN suspended BOOLEAN NOT NULL DEFAULT FALSE;
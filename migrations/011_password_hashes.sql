@@ -0,0 +1,3 @@
+-- Argon2id hashes in the PHC string format are longer than bcrypt's 60
+-- characters. Existing bcrypt hashes are upgraded as users log in.
+ALTER TABLE users MODIFY hashed_password VARCHAR(255) NOT NULL;
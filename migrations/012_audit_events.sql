@@ -0,0 +1,23 @@
+-- Security-relevant events, kept for good: user_id and actor_id have no
+-- foreign keys so the history outlives deleted accounts. Triggers refuse
+-- changes to rows once written.
+CREATE TABLE audit_events (
+    id BIGINT NOT NULL PRIMARY KEY AUTO_INCREMENT,
+    user_id INTEGER NULL,
+    actor_id INTEGER NULL,
+    event VARCHAR(50) NOT NULL,
+    detail VARCHAR(255) NOT NULL,
+    ip VARCHAR(45) NOT NULL,
+    user_agent VARCHAR(255) NOT NULL,
+    created DATETIME NOT NULL
+);
+
+CREATE INDEX idx_audit_events_user ON audit_events(user_id, id);
+CREATE INDEX idx_audit_events_event ON audit_events(event, id);
+CREATE INDEX idx_audit_events_ip ON audit_events(ip, id);
+
+CREATE TRIGGER audit_events_no_update BEFORE UPDATE ON audit_events
+FOR EACH ROW SIGNAL SQLSTATE '45000' SET MESSAGE_TEXT = 'audit_events is append-only';
+
+CREATE TRIGGER audit_events_no_delete BEFORE DELETE ON audit_events
+FOR EACH ROW SIGNAL SQLSTATE '45000' SET MESSAGE_TEXT = 'audit_events is append-only';
@@ -0,0 +1,4 @@
+-- Public profile fields, shown on /user/{id}. The email address never is.
+ALTER TABLE users
+    ADD COLUMN bio VARCHAR(500) NOT NULL DEFAULT '',
+    ADD COLUMN website VARCHAR(255) NOT NULL DEFAULT '';
@@ -0,0 +1,14 @@
+-- Usernames are optional and unique whatever their case; the collation
+-- makes both the constraint and lookups case-insensitive.
+ALTER TABLE users
+    ADD COLUMN username VARCHAR(30) COLLATE utf8mb4_general_ci NULL,
+    ADD CONSTRAINT users_uc_username UNIQUE (username);
+
+-- Usernames a user has given up, so that links to them keep working. A name
+-- in use by an account takes precedence over an old one here.
+CREATE TABLE username_history (
+    username VARCHAR(30) COLLATE utf8mb4_general_ci NOT NULL PRIMARY KEY,
+    user_id INTEGER NOT NULL,
+    created DATETIME NOT NULL,
+    CONSTRAINT username_history_fk_user FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
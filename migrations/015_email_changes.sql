@@ -0,0 +1,12 @@
+-- Pending email address changes. A user has at most one; asking for another
+-- or resetting the password deletes it, which revokes its link.
+CREATE TABLE email_changes (
+    hash BINARY(32) PRIMARY KEY,
+    user_id INTEGER NOT NULL,
+    old_email VARCHAR(255) NOT NULL,
+    new_email VARCHAR(255) NOT NULL,
+    expires DATETIME NOT NULL,
+    CONSTRAINT email_changes_fk_user FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_email_changes_user ON email_changes(user_id);
@@ -0,0 +1,100 @@
+// Passkey registration and login. The server sends and expects binary
+// values as base64url strings.
+(function () {
+	function decode(s) {
+		s = s.replace(/-/g, "+").replace(/_/g, "/");
+		var bin = atob(s + "===".slice((s.length + 3) % 4));
+		var bytes = new Uint8Array(bin.length);
+		for (var i = 0; i < bin.length; i++) {
+			bytes[i] = bin.charCodeAt(i);
+		}
+		return bytes.buffer;
+	}
+
+	function encode(buf) {
+		var bytes = new Uint8Array(buf);
+		var bin = "";
+		for (var i = 0; i < bytes.length; i++) {
+			bin += String.fromCharCode(bytes[i]);
+		}
+		return btoa(bin).replace(/\+/g, "-").replace(/\//g, "_").replace(/=+$/, "");
+	}
+
+	function post(url, csrfToken, body) {
+		return fetch(url, {
+			method: "POST",
+			headers: {"Content-Type": "application/json", "X-CSRF-Token": csrfToken},
+			body: JSON.stringify(body || {}),
+		}).then(function (res) {
+			return res.json().then(function (data) {
+				if (!res.ok) {
+					throw new Error(data.message || res.statusText);
+				}
+				return data;
+			});
+		});
+	}
+
+	function showError(err) {
+		var el = document.getElementById("passkey-error");
+		el.textContent = err.message;
+		el.hidden = false;
+	}
+
+	if (!window.PublicKeyCredential) {
+		return;
+	}
+
+	var register = document.getElementById("passkey-register");
+	if (register) {
+		register.addEventListener("submit", function (e) {
+			e.preventDefault();
+			var csrfToken = register.dataset.csrfToken;
+			post("/account/passkeys/register/begin", csrfToken).then(function (opts) {
+				opts.challenge = decode(opts.challenge);
+				opts.user.id = decode(opts.user.id);
+				opts.excludeCredentials.forEach(function (c) { c.id = decode(c.id); });
+				return navigator.credentials.create({publicKey: opts});
+			}).then(function (cred) {
+				return post("/account/passkeys/register/finish", csrfToken, {
+					name: register.elements.name.value,
+					credential: {
+						id: cred.id,
+						type: cred.type,
+						response: {
+							clientDataJSON: encode(cred.response.clientDataJSON),
+							attestationObject: encode(cred.response.attestationObject),
+						},
+					},
+				});
+			}).then(function (data) {
+				window.location = data.redirect;
+			}).catch(showError);
+		});
+	}
+
+	var login = document.getElementById("passkey-login");
+	if (login) {
+		login.hidden = false;
+		login.addEventListener("click", function () {
+			var csrfToken = login.dataset.csrfToken;
+			post("/user/login/passkey/begin", csrfToken).then(function (opts) {
+				opts.challenge = decode(opts.challenge);
+				return navigator.credentials.get({publicKey: opts});
+			}).then(function (cred) {
+				return post("/user/login/passkey/finish", csrfToken, {
+					id: cred.id,
+					type: cred.type,
+					response: {
+						clientDataJSON: encode(cred.response.clientDataJSON),
+						authenticatorData: encode(cred.response.authenticatorData),
+						signature: encode(cred.response.signature),
+						userHandle: cred.response.userHandle ? encode(cred.response.userHandle) : "",
+					},
+				});
+			}).then(function (data) {
+				window.location = data.redirect;
+			}).catch(showError);
+		});
+	}
+})();